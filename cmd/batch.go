@@ -0,0 +1,376 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/hooks"
+	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/notify"
+	"github.com/pulse-downloader/pulse/internal/utils"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// batchProgressInterval is how often the aggregate progress of a batch is redrawn
+const batchProgressInterval = 500 * time.Millisecond
+
+// batchLogInterval is how often the aggregate progress is printed when stderr is not a terminal
+const batchLogInterval = 10 * time.Second
+
+// batchResult is the outcome of one download of a batch
+type batchResult struct {
+	Entry    download.InputEntry // The line of the batch file, written back if the download fails
+	URL      string
+	Filename string
+	DestPath string
+	Category string
+	Size     int64
+	Started  time.Time
+	Elapsed  time.Duration
+	Err      error
+	Kind     types.ErrorKind
+
+	id        string
+	state     *types.ProgressState
+	done      bool
+	lastEvent time.Time // When the last progress event was written
+	lastBytes int64     // Bytes downloaded at the last progress event
+}
+
+// batchProgress draws the aggregate progress of a batch on one line of stderr
+type batchProgress struct {
+	out         io.Writer
+	interactive bool // Redraw in place instead of printing a line now and then
+	lastBytes   int64
+	lastTime    time.Time
+	lastLog     time.Time
+	speed       float64
+	drawn       bool
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// clear removes the progress line so other output can be printed
+func (bp *batchProgress) clear() {
+	if bp.interactive && bp.drawn {
+		fmt.Fprint(bp.out, "\r\033[K")
+		bp.drawn = false
+	}
+}
+
+// printf prints a line above the progress line
+func (bp *batchProgress) printf(format string, args ...any) {
+	bp.clear()
+	fmt.Fprintf(bp.out, format, args...)
+}
+
+// draw updates the progress line from the current results
+func (bp *batchProgress) draw(results []batchResult) {
+	var done, failed, active int
+	var downloaded, total int64
+	for _, r := range results {
+		switch {
+		case r.done && r.Err != nil:
+			failed++
+		case r.done:
+			done++
+		case r.state != nil && !r.Started.IsZero():
+			active++
+		}
+		if r.state != nil {
+			d, t, _, _, _ := r.state.GetProgress()
+			downloaded += d
+			total += max(t, d)
+		}
+	}
+
+	now := time.Now()
+	if !bp.lastTime.IsZero() {
+		if secs := now.Sub(bp.lastTime).Seconds(); secs > 0 {
+			current := float64(downloaded-bp.lastBytes) / secs
+			bp.speed = 0.7*bp.speed + 0.3*max(current, 0) // Smooth out bursts
+		}
+	}
+	bp.lastBytes, bp.lastTime = downloaded, now
+
+	line := fmt.Sprintf("[%d/%d done, %d failed, %d active] %s / %s - %s/s",
+		done+failed, len(results), failed, active,
+		utils.ConvertBytesToHumanReadable(downloaded), utils.ConvertBytesToHumanReadable(total),
+		utils.ConvertBytesToHumanReadable(int64(bp.speed)))
+	if bp.interactive {
+		fmt.Fprint(bp.out, "\r\033[K"+line)
+		bp.drawn = true
+	} else if now.Sub(bp.lastLog) >= batchLogInterval {
+		fmt.Fprintln(bp.out, line)
+		bp.lastLog = now
+	}
+}
+
+// runBatch downloads the entries of a batch file through a worker pool, jobs at a time, and
+// returns the outcome of each in the same order. Downloads still running when ctx is cancelled are paused.
+// With events set, the events of the downloads are written instead of the progress line.
+func runBatch(ctx context.Context, entries []download.InputEntry, outPath string, opts download.Options, jobs int, verbose bool, settings *config.Settings, events *eventWriter) []batchResult {
+	if jobs < 1 {
+		jobs = settings.General.MaxConcurrentDownloads
+	}
+	eventCh := make(chan tea.Msg, progressChannelBuffer)
+	pool := download.NewWorkerPool(eventCh, jobs)
+	hookList := settings.Hooks
+	notifier := notify.New(settings.Notifications, nil)
+	rc := &types.RuntimeConfig{
+		MaxConnectionsPerHost: settings.Connections.MaxConnectionsPerHost,
+		MaxGlobalConnections:  settings.Connections.MaxGlobalConnections,
+		UserAgent:             settings.Connections.UserAgent,
+		DownloadRetries:       settings.Performance.DownloadRetries,
+		DownloadRetryDelay:    settings.Performance.DownloadRetryDelay,
+		Categories:            convertCategoryRules(settings.Categories),
+	}
+
+	results := make([]batchResult, len(entries))
+	index := make(map[string]int, len(entries))
+	for i, e := range entries {
+		id := uuid.New().String()
+		index[id] = i
+		results[i] = batchResult{Entry: e, URL: e.URL, id: id, state: types.NewProgressState(id, 0)}
+		o := e.Options(opts)
+		pool.Add(types.DownloadConfig{
+			URL:        e.URL,
+			OutputPath: e.OutputPath(outPath),
+			ID:         id,
+			Filename:   o.Filename,
+			Quality:    o.Quality,
+			Category:   o.Category,
+			Headers:    o.Headers,
+			Checksum:   o.Checksum,
+			Mirrors:    o.Mirrors,
+			Continue:   o.Continue,
+			Verbose:    verbose,
+			ProgressCh: eventCh,
+			State:      results[i].state,
+			Runtime:    rc,
+		})
+	}
+
+	bp := &batchProgress{out: os.Stderr, interactive: isTerminal(os.Stderr), lastLog: time.Now()}
+	interval := batchProgressInterval
+	if events != nil {
+		bp = &batchProgress{out: io.Discard}
+		interval = min(interval, events.interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Hooks, notifications and extraction run alongside the remaining downloads
+	var after sync.WaitGroup
+	defer after.Wait()
+
+	cancelled := ctx.Done()
+	remaining := len(entries)
+	for remaining > 0 {
+		select {
+		case <-cancelled:
+			bp.printf("Interrupted, pausing %d running download(s)...\n", remaining)
+			cancelled = nil
+			// Downloads that never started report nothing; stop once the running ones paused
+			go func() {
+				pool.GracefulShutdown()
+				eventCh <- batchStoppedMsg{}
+			}()
+		case msg := <-eventCh:
+			switch m := msg.(type) {
+			case batchStoppedMsg:
+				for i := range results {
+					if r := &results[i]; !r.done {
+						r.done = true
+						r.Err = types.NewError(types.KindCancelled, context.Canceled)
+						r.Kind = types.KindCancelled
+						if events != nil {
+							events.emit(errorEvent{eventHeader: newEventHeader("error", r.id, r.URL), Path: r.DestPath, Kind: string(r.Kind), Error: r.Err.Error(), ExitCode: exitCancelled})
+						}
+					}
+				}
+				remaining = 0
+			case messages.DownloadStartedMsg:
+				r := &results[index[m.DownloadID]]
+				r.Started = time.Now()
+				r.lastEvent = r.Started
+				r.Filename, r.DestPath, r.Category = m.Filename, m.DestPath, m.Category
+				if events != nil {
+					events.emit(startedEvent{eventHeader: newEventHeader("started", r.id, r.URL), Filename: m.Filename, Path: m.DestPath, Size: m.Total, Category: m.Category})
+				}
+				if verbose || !bp.interactive {
+					bp.printf("Started: %s (%s)\n", m.Filename, utils.ConvertBytesToHumanReadable(m.Total))
+				}
+			case messages.DownloadCompleteMsg:
+				r := &results[index[m.DownloadID]]
+				if r.done {
+					break
+				}
+				r.done = true
+				remaining--
+				r.Size, r.Elapsed = m.Total, m.Elapsed
+				if verbose || !bp.interactive {
+					bp.printf("Complete: %s (%s in %s)\n", r.Filename, utils.ConvertBytesToHumanReadable(r.Size), r.Elapsed.Round(time.Millisecond))
+				}
+				done := *r
+				after.Add(1)
+				go func() {
+					defer after.Done()
+					if events != nil {
+						events.completed(done.id, done.URL, done.DestPath, done.Size, done.Elapsed)
+					}
+					sendNotifications(notifier, notify.Notification{Event: notify.EventComplete, ID: m.DownloadID, URL: done.URL, Filename: done.Filename, Path: done.DestPath, Size: done.Size, Category: done.Category, Elapsed: done.Elapsed})
+					extractDir := ""
+					if settings.General.ExtractArchives {
+						extractDir = extractArchive(ctx, done.DestPath, settings.General.DeleteAfterExtract)
+					}
+					runEventHooks(hookList, hooks.Info{Event: hooks.EventComplete, ID: m.DownloadID, URL: done.URL, File: done.DestPath, Size: done.Size, Category: done.Category, ExtractDir: extractDir})
+				}()
+			case messages.DownloadErrorMsg:
+				r := &results[index[m.DownloadID]]
+				if r.done {
+					break
+				}
+				r.done = true
+				remaining--
+				r.Err, r.Kind = m.Err, m.Kind
+				if !r.Started.IsZero() {
+					r.Elapsed = time.Since(r.Started)
+				}
+				name := r.Filename
+				if name == "" {
+					name = r.URL
+				}
+				bp.printf("Error (%s): %s: %v\n", m.Kind, name, m.Err)
+				if events != nil {
+					events.emit(errorEvent{eventHeader: newEventHeader("error", r.id, r.URL), Path: r.DestPath, Kind: string(m.Kind), Error: m.Err.Error(), ExitCode: exitCodeFor([]types.ErrorKind{m.Kind})})
+				}
+				failed := *r
+				after.Add(1)
+				go func() {
+					defer after.Done()
+					runEventHooks(hookList, hooks.Info{Event: hooks.EventError, ID: m.DownloadID, URL: failed.URL, File: incompletePath(failed.DestPath), Size: failed.Size, Category: failed.Category, Error: failed.Err.Error()})
+					sendNotifications(notifier, notify.Notification{Event: notify.EventError, ID: m.DownloadID, URL: failed.URL, Filename: failed.Filename, Path: failed.DestPath, Size: failed.Size, Category: failed.Category, Error: failed.Err.Error()})
+				}()
+			case messages.DownloadRetryingMsg:
+				r := &results[index[m.DownloadID]]
+				bp.printf("Retrying %s in %s (%d/%d): %v\n", r.URL, m.Delay, m.Attempt, m.MaxAttempts, m.Err)
+				if events != nil {
+					events.emit(retryEvent{eventHeader: newEventHeader("retry", r.id, r.URL), Attempt: m.Attempt, MaxAttempts: m.MaxAttempts, DelayMs: m.Delay.Milliseconds(), Kind: string(m.Kind), Error: m.Err.Error()})
+				}
+			case messages.HealthEventMsg:
+				if verbose {
+					bp.printf("  Warning: %s\n", m.Detail)
+				}
+			}
+		case <-ticker.C:
+			bp.draw(results)
+			if events == nil {
+				break
+			}
+			now := time.Now()
+			for i := range results {
+				r := &results[i]
+				if r.done || r.Started.IsZero() || now.Sub(r.lastEvent) < events.interval {
+					continue
+				}
+				downloaded, total, _, _, _ := r.state.GetProgress()
+				events.progress(r.id, r.URL, downloaded, total, r.lastBytes, now.Sub(r.lastEvent))
+				r.lastEvent, r.lastBytes = now, downloaded
+			}
+		}
+	}
+	bp.draw(results)
+	bp.clear()
+	return results
+}
+
+// batchStoppedMsg tells runBatch that an interrupted batch has paused its running downloads
+type batchStoppedMsg struct{}
+
+// printBatchSummary writes one line per download of a batch and the totals
+func printBatchSummary(w io.Writer, results []batchResult, elapsed time.Duration) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tSIZE\tTIME\tFILE")
+	var ok int
+	var total int64
+	for _, r := range results {
+		status, size := "ok", "-"
+		if r.Err != nil {
+			status = "failed (" + string(r.Kind) + ")"
+		} else {
+			ok++
+			total += r.Size
+			size = utils.ConvertBytesToHumanReadable(r.Size)
+		}
+		name := r.Filename
+		if name == "" {
+			name = r.URL
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", status, size, r.Elapsed.Round(time.Second), name)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d of %d downloads completed (%s in %s)\n", ok, len(results),
+		utils.ConvertBytesToHumanReadable(total), elapsed.Round(time.Second))
+}
+
+// batchSummaryEvent builds the summary event of a finished batch
+func batchSummaryEvent(results []batchResult, elapsed time.Duration, failedFile string) summaryEvent {
+	var failedKinds []types.ErrorKind
+	summary := summaryEvent{
+		eventHeader: newEventHeader("summary", "", ""),
+		Total:       len(results),
+		DurationMs:  elapsed.Milliseconds(),
+		FailedFile:  failedFile,
+		Downloads:   make([]summaryDownload, 0, len(results)),
+	}
+	for _, r := range results {
+		d := summaryDownload{URL: r.URL, Path: r.DestPath, Status: "completed", Size: r.Size, DurationMs: r.Elapsed.Milliseconds()}
+		if r.Err != nil {
+			d.Status, d.Kind, d.Error = "failed", string(r.Kind), r.Err.Error()
+			failedKinds = append(failedKinds, r.Kind)
+			summary.Failed++
+		} else {
+			summary.Completed++
+			summary.Bytes += r.Size
+		}
+		summary.Downloads = append(summary.Downloads, d)
+	}
+	summary.ExitCode = exitCodeFor(failedKinds)
+	return summary
+}
+
+// writeFailedURLs writes the failed downloads of a batch to path with their options,
+// so the file can be passed back to --batch. It returns the number of downloads written.
+func writeFailedURLs(path string, results []batchResult) (int, error) {
+	var b strings.Builder
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(&b, "# %s: %v\n%s", r.Kind, r.Err, r.Entry)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write failed URLs: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+func TestRunBatch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.bin" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(bytes.Repeat([]byte("x"), 4096)))
+	}))
+	defer server.Close()
+
+	// The same name twice must not collide while both download at once
+	urls := []string{server.URL + "/a/file.bin", server.URL + "/missing.bin", server.URL + "/b/file.bin", server.URL + "/c.bin"}
+	out := t.TempDir()
+	entries := make([]download.InputEntry, len(urls))
+	for i, url := range urls {
+		entries[i] = download.InputEntry{URL: url}
+	}
+	entries[1].Filename = "renamed.bin"
+	results := runBatch(context.Background(), entries, out, download.Options{}, 3, false, config.DefaultSettings(), nil)
+
+	if len(results) != len(urls) {
+		t.Fatalf("Got %d results for %d URLs", len(results), len(urls))
+	}
+	names := map[string]bool{}
+	for i, r := range results {
+		if r.URL != urls[i] {
+			t.Errorf("Result %d is for %s, want %s", i, r.URL, urls[i])
+		}
+		if i == 1 {
+			if r.Err == nil || r.Kind != types.KindHTTP {
+				t.Errorf("Missing file: err = %v, kind = %s", r.Err, r.Kind)
+			}
+			continue
+		}
+		if r.Err != nil || r.Size != 4096 {
+			t.Errorf("Download %s: size %d, err %v", r.URL, r.Size, r.Err)
+		}
+		names[r.Filename] = true
+	}
+	if len(names) != 3 {
+		t.Errorf("Downloads share destinations: %v", names)
+	}
+
+	failedFile := filepath.Join(t.TempDir(), "urls.failed")
+	if n, err := writeFailedURLs(failedFile, results); err != nil || n != 1 {
+		t.Fatalf("writeFailedURLs = %d, %v", n, err)
+	}
+	retry, _, err := download.ReadInputFile(failedFile)
+	if err != nil || len(retry) != 1 || retry[0].URL != urls[1] || retry[0].Filename != "renamed.bin" {
+		t.Errorf("Failed URLs file reads back as %+v, %v", retry, err)
+	}
+
+	var summary strings.Builder
+	printBatchSummary(&summary, results, time.Second)
+	if !strings.Contains(summary.String(), "failed (http)") || !strings.Contains(summary.String(), "3 of 4 downloads completed") {
+		t.Errorf("Unexpected summary:\n%s", summary.String())
+	}
+}
+
+func TestRunBatch_Interrupted(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1048576")
+		w.WriteHeader(http.StatusOK)
+		w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+	done := make(chan []batchResult)
+	go func() {
+		entries := []download.InputEntry{{URL: server.URL + "/1.bin"}, {URL: server.URL + "/2.bin"}, {URL: server.URL + "/3.bin"}}
+		done <- runBatch(ctx, entries, t.TempDir(), download.Options{}, 1, false, config.DefaultSettings(), nil)
+	}()
+
+	select {
+	case results := <-done:
+		for _, r := range results {
+			if r.Kind != types.KindCancelled {
+				t.Errorf("Download %s: kind = %s, want %s", r.URL, r.Kind, types.KindCancelled)
+			}
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Interrupted batch did not stop")
+	}
+}
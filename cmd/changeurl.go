@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// sendChangeURL asks a running pulse instance to replace the URL of a download
+func sendChangeURL(id, newURL string, port int) error {
+	reqBody := ChangeURLRequest{
+		ID:  id,
+		URL: newURL,
+	}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	serverURL := fmt.Sprintf("http://127.0.0.1:%d/change-url", port)
+	resp, err := http.Post(serverURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server error: %s - %s", resp.Status, string(body))
+	}
+
+	fmt.Printf("URL change requested: %s\n", string(body))
+	return nil
+}
+
+var changeURLCmd = &cobra.Command{
+	Use:   "change-url [id] [url]",
+	Short: "Replace the URL of a paused or failed download",
+	Long: `Point a paused or failed download at a new URL, e.g. after a file host link expired.
+
+The new URL is probed and must serve the same file (size and ETag) for the
+download to continue from its existing progress.
+
+The request is sent to the running Pulse instance found in ~/.pulse/port,
+or to the one given with --port.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		port, _ := cmd.Flags().GetInt("port")
+		if port == 0 {
+			p, err := readActivePort()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			port = p
+		}
+
+		if err := sendChangeURL(args[0], args[1], port); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	changeURLCmd.Flags().IntP("port", "p", 0, "port of the running pulse instance (default: read from ~/.pulse/port)")
+}
@@ -10,10 +10,14 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/messages"
 )
 
 // =============================================================================
@@ -115,6 +119,28 @@ func TestSaveAndRemoveActivePort(t *testing.T) {
 	}
 }
 
+func TestReadActivePort(t *testing.T) {
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to ensure dirs: %v", err)
+	}
+
+	saveActivePort(23456)
+	defer removeActivePort()
+
+	port, err := readActivePort()
+	if err != nil {
+		t.Fatalf("readActivePort failed: %v", err)
+	}
+	if port != 23456 {
+		t.Errorf("Expected port 23456, got %d", port)
+	}
+
+	removeActivePort()
+	if _, err := readActivePort(); err == nil {
+		t.Error("Expected error when no port file exists")
+	}
+}
+
 // =============================================================================
 // corsMiddleware Tests
 // =============================================================================
@@ -181,7 +207,7 @@ func TestHandleDownload_MethodNotAllowed(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/download", nil)
 	rec := httptest.NewRecorder()
 
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+	handler := makeDownloadHandler(func(req DownloadRequest) {})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusMethodNotAllowed {
@@ -193,7 +219,7 @@ func TestHandleDownload_InvalidJSON(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/download", bytes.NewBufferString("not json"))
 	rec := httptest.NewRecorder()
 
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+	handler := makeDownloadHandler(func(req DownloadRequest) {})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -209,7 +235,7 @@ func TestHandleDownload_MissingURL(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/download", bytes.NewBufferString(body))
 	rec := httptest.NewRecorder()
 
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+	handler := makeDownloadHandler(func(req DownloadRequest) {})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -225,7 +251,7 @@ func TestHandleDownload_EmptyURL(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/download", bytes.NewBufferString(body))
 	rec := httptest.NewRecorder()
 
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+	handler := makeDownloadHandler(func(req DownloadRequest) {})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -233,6 +259,136 @@ func TestHandleDownload_EmptyURL(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// handleChangeURL Tests
+// =============================================================================
+
+func TestHandleChangeURL_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/change-url", nil)
+	rec := httptest.NewRecorder()
+
+	handler := makeChangeURLHandler(func(id, newURL string) error { return nil })
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
+
+func TestHandleChangeURL_MissingFields(t *testing.T) {
+	bodies := []string{`{"id": "abc"}`, `{"url": "https://example.com/f.zip"}`}
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/change-url", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+
+		handler := makeChangeURLHandler(func(id, newURL string) error { return nil })
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Body %s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestHandleChangeURL_NoChanger(t *testing.T) {
+	body := `{"id": "abc", "url": "https://example.com/f.zip"}`
+	req := httptest.NewRequest(http.MethodPost, "/change-url", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	makeChangeURLHandler(nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
+
+func TestHandleChangeURL_Success(t *testing.T) {
+	var gotID, gotURL string
+	body := `{"id": "abc", "url": "https://example.com/f.zip"}`
+	req := httptest.NewRequest(http.MethodPost, "/change-url", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler := makeChangeURLHandler(func(id, newURL string) error {
+		gotID, gotURL = id, newURL
+		return nil
+	})
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if gotID != "abc" || gotURL != "https://example.com/f.zip" {
+		t.Errorf("Changer got (%q, %q)", gotID, gotURL)
+	}
+}
+
+func TestHandleChangeURL_ChangerError(t *testing.T) {
+	body := `{"id": "abc", "url": "https://example.com/f.zip"}`
+	req := httptest.NewRequest(http.MethodPost, "/change-url", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler := makeChangeURLHandler(func(id, newURL string) error {
+		return fmt.Errorf("size mismatch")
+	})
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409, got %d", rec.Code)
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte("size mismatch")) {
+		t.Error("Expected changer error in response body")
+	}
+}
+
+// =============================================================================
+// handleMaxConcurrent Tests
+// =============================================================================
+
+func TestHandleMaxConcurrent_Validation(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		setter MaxDownloadsSetter
+		want   int
+	}{
+		{http.MethodGet, "", func(int) error { return nil }, http.StatusMethodNotAllowed},
+		{http.MethodPost, `{"max": 2}`, nil, http.StatusServiceUnavailable},
+		{http.MethodPost, `{"max": 0}`, func(int) error { return nil }, http.StatusBadRequest},
+		{http.MethodPost, `{"max": 11}`, func(int) error { return nil }, http.StatusBadRequest},
+		{http.MethodPost, `{"max": "two"}`, func(int) error { return nil }, http.StatusBadRequest},
+		{http.MethodPost, `{"max": 2}`, func(int) error { return fmt.Errorf("failed to save settings") }, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/max-concurrent", bytes.NewBufferString(tt.body))
+		rec := httptest.NewRecorder()
+
+		makeMaxConcurrentHandler(tt.setter).ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.body, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestHandleMaxConcurrent_Success(t *testing.T) {
+	got := 0
+	req := httptest.NewRequest(http.MethodPost, "/max-concurrent", bytes.NewBufferString(`{"max": 5}`))
+	rec := httptest.NewRecorder()
+
+	makeMaxConcurrentHandler(func(n int) error {
+		got = n
+		return nil
+	}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if got != 5 {
+		t.Errorf("Setter got %d, want 5", got)
+	}
+}
+
 func TestHandleDownload_PathTraversal(t *testing.T) {
 	tests := []struct {
 		name string
@@ -249,7 +405,7 @@ func TestHandleDownload_PathTraversal(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/download", bytes.NewBufferString(tt.body))
 			rec := httptest.NewRecorder()
-			handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+			handler := makeDownloadHandler(func(req DownloadRequest) {})
 			handler.ServeHTTP(rec, req)
 
 			if rec.Code != http.StatusBadRequest {
@@ -516,7 +672,7 @@ func TestStartHTTPServer_HealthEndpoint(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	// Start server in background
-	go startHTTPServer(ln, port, func(url, path, filename, quality string) {}, "")
+	go startHTTPServer(ln, port, func(req DownloadRequest) {}, "")
 
 	// Give server time to start
 	time.Sleep(50 * time.Millisecond)
@@ -552,7 +708,7 @@ func TestStartHTTPServer_NoCORSHeaders(t *testing.T) {
 	}
 	port := ln.Addr().(*net.TCPAddr).Port
 
-	go startHTTPServer(ln, port, func(url, path, filename, quality string) {}, "")
+	go startHTTPServer(ln, port, func(req DownloadRequest) {}, "")
 	time.Sleep(50 * time.Millisecond)
 
 	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
@@ -573,7 +729,7 @@ func TestStartHTTPServer_OptionsRequest(t *testing.T) {
 	}
 	port := ln.Addr().(*net.TCPAddr).Port
 
-	go startHTTPServer(ln, port, func(url, path, filename, quality string) {}, "")
+	go startHTTPServer(ln, port, func(req DownloadRequest) {}, "")
 	time.Sleep(50 * time.Millisecond)
 
 	req, _ := http.NewRequest(http.MethodOptions, fmt.Sprintf("http://127.0.0.1:%d/download", port), nil)
@@ -595,7 +751,7 @@ func TestStartHTTPServer_DownloadEndpoint_MethodNotAllowed(t *testing.T) {
 	}
 	port := ln.Addr().(*net.TCPAddr).Port
 
-	go startHTTPServer(ln, port, func(url, path, filename, quality string) {}, "")
+	go startHTTPServer(ln, port, func(req DownloadRequest) {}, "")
 	time.Sleep(50 * time.Millisecond)
 
 	// GET should not be allowed
@@ -617,7 +773,7 @@ func TestStartHTTPServer_DownloadEndpoint_BadRequest(t *testing.T) {
 	}
 	port := ln.Addr().(*net.TCPAddr).Port
 
-	go startHTTPServer(ln, port, func(url, path, filename, quality string) {}, "")
+	go startHTTPServer(ln, port, func(req DownloadRequest) {}, "")
 	time.Sleep(50 * time.Millisecond)
 
 	// POST with invalid JSON
@@ -643,7 +799,7 @@ func TestStartHTTPServer_DownloadEndpoint_MissingURL(t *testing.T) {
 	}
 	port := ln.Addr().(*net.TCPAddr).Port
 
-	go startHTTPServer(ln, port, func(url, path, filename, quality string) {}, "")
+	go startHTTPServer(ln, port, func(req DownloadRequest) {}, "")
 	time.Sleep(50 * time.Millisecond)
 
 	// POST with missing URL
@@ -669,7 +825,7 @@ func TestStartHTTPServer_NotFoundEndpoint(t *testing.T) {
 	}
 	port := ln.Addr().(*net.TCPAddr).Port
 
-	go startHTTPServer(ln, port, func(url, path, filename, quality string) {}, "")
+	go startHTTPServer(ln, port, func(req DownloadRequest) {}, "")
 	time.Sleep(50 * time.Millisecond)
 
 	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/nonexistent", port))
@@ -694,7 +850,7 @@ func TestHandleDownload_ValidRequest_NoServerProgram(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// Test that validation passes
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {
+	handler := makeDownloadHandler(func(req DownloadRequest) {
 		// Mock dispatcher
 	})
 	handler.ServeHTTP(rec, req)
@@ -704,7 +860,7 @@ func TestHandleDownload_EmptyBody(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/download", bytes.NewBufferString(""))
 	rec := httptest.NewRecorder()
 
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+	handler := makeDownloadHandler(func(req DownloadRequest) {})
 	handler.ServeHTTP(rec, req)
 
 	// Empty body causes EOF error on decode
@@ -721,7 +877,7 @@ func TestHandleDownload_LargeURL(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// This should handle large URLs gracefully (validation issues)
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+	handler := makeDownloadHandler(func(req DownloadRequest) {})
 	handler.ServeHTTP(rec, req)
 
 	// Should fail on URL validation or JSON parsing
@@ -733,7 +889,7 @@ func TestHandleDownload_SpecialCharactersInPath(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/download", bytes.NewBufferString(body))
 	rec := httptest.NewRecorder()
 
-	handler := makeDownloadHandler(func(url, path, filename, quality string) {})
+	handler := makeDownloadHandler(func(req DownloadRequest) {})
 	handler.ServeHTTP(rec, req)
 }
 
@@ -855,3 +1011,38 @@ func TestFindAvailablePort_HighPort(t *testing.T) {
 		t.Errorf("Expected port >= 60000, got %d", port)
 	}
 }
+
+// =============================================================================
+// consumeProgress Tests
+// =============================================================================
+
+func TestConsumeProgress_ErrorBeforeStartUsesConfigURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+	out := filepath.Join(t.TempDir(), "hook.out")
+	settings := config.DefaultSettings()
+	settings.Hooks = []config.HookConfig{{
+		Name:    "error",
+		Command: `echo "$PULSE_URL|$PULSE_FILE" > "` + out + `"`,
+		Events:  []string{"error"},
+	}}
+
+	ch := make(chan tea.Msg, 1)
+	ch <- messages.DownloadErrorMsg{DownloadID: "probe-fail", URL: "https://example.com/file.bin", Err: fmt.Errorf("probe failed")}
+	close(ch)
+	consumeProgress(ch, settings)
+
+	var got []byte
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if b, err := os.ReadFile(out); err == nil && len(b) > 0 {
+			got = b
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if want := "https://example.com/file.bin|"; strings.TrimSpace(string(got)) != want {
+		t.Errorf("hook saw %q, want %q", strings.TrimSpace(string(got)), want)
+	}
+}
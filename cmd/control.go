@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// DownloadLister lists the downloads of the running instance for GET /downloads
+type DownloadLister func() []download.DownloadInfo
+
+// DownloadAction pauses, resumes or removes a download of the running instance
+type DownloadAction func(id string) error
+
+// DownloadActionRequest names the download to pause, resume or remove
+type DownloadActionRequest struct {
+	ID string `json:"id"` // Download ID, or "all" for /pause and /resume
+}
+
+func makeDownloadsHandler(list DownloadLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if list == nil {
+			http.Error(w, "Listing downloads is not supported by this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		infos := list()
+		if infos == nil {
+			infos = []download.DownloadInfo{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(infos)
+	}
+}
+
+// makeDownloadActionHandler serves /pause, /resume and /remove. With a lister, the ID "all"
+// applies the action to every download whose status is allStatus.
+func makeDownloadActionHandler(action DownloadAction, list DownloadLister, allStatus, name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if action == nil {
+			http.Error(w, name+" is not supported by this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req DownloadActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.ID == "" {
+			http.Error(w, "ID is required", http.StatusBadRequest)
+			return
+		}
+
+		utils.Debug("Received %s request: ID=%s", strings.ToLower(name), req.ID)
+
+		ids := []string{req.ID}
+		if req.ID == "all" {
+			if list == nil {
+				http.Error(w, name+" all is not supported", http.StatusBadRequest)
+				return
+			}
+			ids = ids[:0]
+			for _, info := range list() {
+				if info.Status == allStatus {
+					ids = append(ids, info.ID)
+				}
+			}
+		}
+		var errs []string
+		for _, id := range ids {
+			if err := action(id); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 && (req.ID != "all" || len(errs) == len(ids)) {
+			http.Error(w, strings.Join(errs, "; "), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "accepted",
+			"message": fmt.Sprintf("%s requested for %d download(s)", name, len(ids)-len(errs)),
+		})
+	}
+}
+
+// controlPort returns the port given with --port, or the one of the running instance
+func controlPort(cmd *cobra.Command) (int, error) {
+	if port, _ := cmd.Flags().GetInt("port"); port != 0 {
+		return port, nil
+	}
+	return readActivePort()
+}
+
+// fetchDownloads asks a running pulse instance for its downloads
+func fetchDownloads(port int) ([]download.DownloadInfo, error) {
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/downloads", port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server error: %s - %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var infos []download.DownloadInfo
+	if err := json.NewDecoder(resp.Body).Decode(&infos); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return infos, nil
+}
+
+// sendDownloadAction asks a running pulse instance to pause, resume or remove a download
+func sendDownloadAction(port int, endpoint, id string) error {
+	jsonData, err := json.Marshal(DownloadActionRequest{ID: id})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	serverURL := fmt.Sprintf("http://127.0.0.1:%d/%s", port, endpoint)
+	resp, err := http.Post(serverURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server error: %s - %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var result map[string]string
+	if json.Unmarshal(body, &result) == nil && result["message"] != "" {
+		fmt.Println(result["message"])
+	}
+	return nil
+}
+
+// resolveID expands a unique prefix of a download ID, as shown by pulse ls, to the full ID
+func resolveID(infos []download.DownloadInfo, prefix string) (string, error) {
+	var matches []string
+	for _, info := range infos {
+		if info.ID == prefix {
+			return info.ID, nil
+		}
+		if strings.HasPrefix(info.ID, prefix) {
+			matches = append(matches, info.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no download with ID %s", prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("ID %s is ambiguous (%d downloads match)", prefix, len(matches))
+	}
+}
+
+// shortID shortens a download ID for display; any unique prefix is accepted back
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// printDownloads writes the downloads as a table. Completed downloads are only listed with all.
+func printDownloads(w io.Writer, infos []download.DownloadInfo, all bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tPROGRESS\tSIZE\tSPEED\tNAME")
+	for _, info := range infos {
+		if info.Status == "completed" && !all {
+			continue
+		}
+		progress, size, speed := "-", "-", "-"
+		if info.Total > 0 {
+			progress = fmt.Sprintf("%.1f%%", float64(info.Downloaded)*100/float64(info.Total))
+			size = utils.ConvertBytesToHumanReadable(info.Downloaded) + " / " + utils.ConvertBytesToHumanReadable(info.Total)
+		} else if info.Downloaded > 0 {
+			size = utils.ConvertBytesToHumanReadable(info.Downloaded)
+		}
+		if info.Speed > 0 {
+			speed = utils.ConvertBytesToHumanReadable(int64(info.Speed)) + "/s"
+		}
+		name := info.Filename
+		if name == "" {
+			name = info.URL
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", shortID(info.ID), info.Status, progress, size, speed, name)
+	}
+	tw.Flush()
+}
+
+// runDownloadAction resolves the IDs given on the command line and sends endpoint for each
+func runDownloadAction(cmd *cobra.Command, args []string, endpoint string, allowAll bool) {
+	port, err := controlPort(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	infos, err := fetchDownloads(port)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, arg := range args {
+		id := arg
+		if arg != "all" || !allowAll {
+			if id, err = resolveID(infos, arg); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed = true
+				continue
+			}
+		}
+		if err := sendDownloadAction(port, endpoint, id); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+var lsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the downloads of the running instance",
+	Long: `List the downloads of the running Pulse instance with their status and progress.
+
+IDs are shortened to their first 8 characters; pause, resume and rm accept
+any unique prefix. Completed downloads are only listed with --all.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		port, err := controlPort(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		infos, err := fetchDownloads(port)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(infos)
+			return
+		}
+		printDownloads(os.Stdout, infos, all)
+	},
+}
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause <id|all>...",
+	Short: "Pause downloads of the running instance",
+	Long:  `Pause one or more running downloads, or all of them with "all".`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDownloadAction(cmd, args, "pause", true)
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <id|all>...",
+	Short: "Resume paused downloads of the running instance",
+	Long:  `Resume one or more paused downloads, or all of them with "all".`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDownloadAction(cmd, args, "resume", true)
+	},
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <id>...",
+	Short: "Remove downloads from the running instance",
+	Long: `Cancel and remove one or more downloads, deleting their saved progress and
+partial files. Completed files are kept on disk.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDownloadAction(cmd, args, "remove", false)
+	},
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Show live progress of the running instance",
+	Long:  `Redraw the download table of the running Pulse instance until interrupted with Ctrl+C.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval <= 0 {
+			interval = time.Second
+		}
+		port, err := controlPort(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			infos, err := fetchDownloads(port)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			var buf bytes.Buffer
+			buf.WriteString("\033[H\033[2J") // Move home and clear the screen
+			fmt.Fprintf(&buf, "pulse on port %d - %s (Ctrl+C to quit)\n\n", port, time.Now().Format("15:04:05"))
+			printDownloads(&buf, infos, all)
+			os.Stdout.Write(buf.Bytes())
+
+			select {
+			case <-sigChan:
+				return
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{lsCmd, pauseCmd, resumeCmd, rmCmd, watchCmd} {
+		c.Flags().IntP("port", "p", 0, "port of the running pulse instance (default: read from ~/.pulse/port)")
+	}
+	lsCmd.Flags().BoolP("all", "a", false, "include completed downloads")
+	lsCmd.Flags().Bool("json", false, "print the downloads as JSON")
+	watchCmd.Flags().BoolP("all", "a", false, "include completed downloads")
+	watchCmd.Flags().DurationP("interval", "n", time.Second, "time between refreshes")
+}
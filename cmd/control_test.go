@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+func fakeDownloads() []download.DownloadInfo {
+	return []download.DownloadInfo{
+		{ID: "aaaa1111-run", Status: "downloading", Downloaded: 50, Total: 100},
+		{ID: "aaaa2222-run", Status: "downloading"},
+		{ID: "bbbb1111-paused", Status: "paused"},
+	}
+}
+
+func TestHandleDownloads(t *testing.T) {
+	rec := httptest.NewRecorder()
+	makeDownloadsHandler(fakeDownloads).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/downloads", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var infos []download.DownloadInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 || infos[0].Downloaded != 50 {
+		t.Errorf("Unexpected downloads: %+v", infos)
+	}
+
+	rec = httptest.NewRecorder()
+	makeDownloadsHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/downloads", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("No lister: expected 503, got %d", rec.Code)
+	}
+}
+
+func TestHandleDownloadAction(t *testing.T) {
+	var got []string
+	pause := func(id string) error {
+		if strings.HasPrefix(id, "missing") {
+			return fmt.Errorf("no running download with ID %s", id)
+		}
+		got = append(got, id)
+		return nil
+	}
+	handler := makeDownloadActionHandler(pause, fakeDownloads, "downloading", "Pause")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/pause", strings.NewReader(`{"id": "all"}`)))
+	if rec.Code != http.StatusOK || strings.Join(got, ",") != "aaaa1111-run,aaaa2222-run" {
+		t.Errorf("Pause all: got %d, paused %v", rec.Code, got)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{"one", http.MethodPost, `{"id": "bbbb1111-paused"}`, handler, http.StatusOK},
+		{"wrong method", http.MethodGet, "", handler, http.StatusMethodNotAllowed},
+		{"no action", http.MethodPost, `{"id": "a"}`, makeDownloadActionHandler(nil, fakeDownloads, "paused", "Resume"), http.StatusServiceUnavailable},
+		{"bad json", http.MethodPost, "{", handler, http.StatusBadRequest},
+		{"missing id", http.MethodPost, `{}`, handler, http.StatusBadRequest},
+		{"unknown id", http.MethodPost, `{"id": "missing"}`, handler, http.StatusConflict},
+		{"remove all", http.MethodPost, `{"id": "all"}`, makeDownloadActionHandler(pause, nil, "", "Remove"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/pause", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestResolveID(t *testing.T) {
+	infos := fakeDownloads()
+	if id, err := resolveID(infos, "bbbb"); err != nil || id != "bbbb1111-paused" {
+		t.Errorf("resolveID(bbbb) = %q, %v", id, err)
+	}
+	if _, err := resolveID(infos, "aaaa"); err == nil {
+		t.Error("expected error for an ambiguous prefix")
+	}
+	if _, err := resolveID(infos, "cccc"); err == nil {
+		t.Error("expected error for an unknown prefix")
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders([]string{"cookie: session=abc", "Authorization:Bearer x:y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers["Cookie"] != "session=abc" || headers["Authorization"] != "Bearer x:y" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+	for _, bad := range []string{"no-colon", ": value", "Bad Name: x"} {
+		if _, err := parseHeaders([]string{bad}); err == nil {
+			t.Errorf("parseHeaders(%q) should fail", bad)
+		}
+	}
+}
+
+func TestHandleDownload_Options(t *testing.T) {
+	var got DownloadRequest
+	handler := makeDownloadHandler(func(req DownloadRequest) { got = req })
+
+	body := `{"url": "https://example.com/a.zip", "filename": "b.zip", "category": "Archives",
+		"headers": {"Cookie": "session=abc"}, "checksum": "md5=d41d8cd98f00b204e9800998ecf8427e"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/download", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got.Filename != "b.zip" || got.Category != "Archives" || got.Headers["Cookie"] != "session=abc" || got.Checksum == "" {
+		t.Errorf("Options not dispatched: %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/download", strings.NewReader(`{"url": "https://example.com/a.zip", "checksum": "md5=zz"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Invalid checksum: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleDownload_UnknownCategory(t *testing.T) {
+	categoryRules = func() []types.CategoryRule { return []types.CategoryRule{{Name: "Archives"}} }
+	t.Cleanup(func() { categoryRules = nil })
+	dispatched := false
+	handler := makeDownloadHandler(func(req DownloadRequest) { dispatched = true })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/download", strings.NewReader(`{"url": "https://example.com/a.zip", "category": "Videos"}`)))
+	if rec.Code != http.StatusBadRequest || dispatched {
+		t.Errorf("Unknown category: expected 400 and no dispatch, got %d (dispatched %v)", rec.Code, dispatched)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/download", strings.NewReader(`{"url": "https://example.com/a.zip", "category": "archives"}`)))
+	if rec.Code != http.StatusOK || !dispatched {
+		t.Errorf("Known category: expected 200 and a dispatch, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
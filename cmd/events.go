@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Output formats of pulse get
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// eventWriter writes the events of headless downloads as JSON, one object per line.
+// The field names are relied on by scripts: add fields, but don't rename or remove them.
+type eventWriter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	interval time.Duration // Time between progress events of a download
+}
+
+func newEventWriter(w io.Writer, interval time.Duration) *eventWriter {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &eventWriter{enc: json.NewEncoder(w), interval: interval}
+}
+
+// emit writes one event; events of parallel downloads never interleave
+func (ew *eventWriter) emit(event any) {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if err := ew.enc.Encode(event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write event: %v\n", err)
+	}
+}
+
+// eventHeader starts every event
+type eventHeader struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	ID    string    `json:"id,omitempty"`
+	URL   string    `json:"url,omitempty"`
+}
+
+func newEventHeader(event, id, url string) eventHeader {
+	return eventHeader{Event: event, Time: time.Now().UTC(), ID: id, URL: url}
+}
+
+// startedEvent is written once the server was probed and the file is being written
+type startedEvent struct {
+	eventHeader
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"` // 0 if the server didn't tell
+	Category string `json:"category,omitempty"`
+}
+
+// progressEvent is written every progress interval while a download runs
+type progressEvent struct {
+	eventHeader
+	Downloaded int64   `json:"downloaded"`
+	Size       int64   `json:"size"`
+	Percent    float64 `json:"percent"` // 0 if the size is unknown
+	Speed      int64   `json:"speed"`   // Bytes/s since the previous progress event
+}
+
+// retryEvent is written when a failed download of a batch will be retried
+type retryEvent struct {
+	eventHeader
+	Attempt     int    `json:"attempt"`
+	MaxAttempts int    `json:"max_attempts"`
+	DelayMs     int64  `json:"delay_ms"`
+	Kind        string `json:"kind"`
+	Error       string `json:"error"`
+}
+
+// completedEvent is written when a download finished and its file is in place
+type completedEvent struct {
+	eventHeader
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	DurationMs int64  `json:"duration_ms"`
+	SHA256     string `json:"sha256,omitempty"` // Empty if the file couldn't be read
+}
+
+// errorEvent is written when a download failed for good
+type errorEvent struct {
+	eventHeader
+	Path     string `json:"path,omitempty"`
+	Kind     string `json:"kind"`
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"` // What pulse get exits with if this is the only failure
+}
+
+// queuedEvent is written when a download was handed to a running instance with --port
+type queuedEvent struct {
+	eventHeader
+	Path string `json:"path,omitempty"`
+}
+
+// summaryEvent is the last event of a batch
+type summaryEvent struct {
+	eventHeader
+	Total      int               `json:"total"`
+	Completed  int               `json:"completed"`
+	Failed     int               `json:"failed"`
+	Bytes      int64             `json:"bytes"`
+	DurationMs int64             `json:"duration_ms"`
+	ExitCode   int               `json:"exit_code"`
+	FailedFile string            `json:"failed_file,omitempty"` // Where the failed downloads were written for retrying
+	Downloads  []summaryDownload `json:"downloads"`
+}
+
+// summaryDownload is the outcome of one download of a batch
+type summaryDownload struct {
+	URL        string `json:"url"`
+	Path       string `json:"path,omitempty"`
+	Status     string `json:"status"` // "completed", "queued" or "failed"
+	Size       int64  `json:"size"`
+	DurationMs int64  `json:"duration_ms"`
+	Kind       string `json:"kind,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// fileSHA256 returns the hex SHA-256 digest of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// completed writes the completed event of a download, hashing its file
+func (ew *eventWriter) completed(id, url, path string, size int64, elapsed time.Duration) {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	ew.emit(completedEvent{
+		eventHeader: newEventHeader("completed", id, url),
+		Path:        path,
+		Size:        size,
+		DurationMs:  elapsed.Milliseconds(),
+		SHA256:      sum,
+	})
+}
+
+// progress writes a progress event from the bytes downloaded now and at the previous event
+func (ew *eventWriter) progress(id, url string, downloaded, size, lastDownloaded int64, since time.Duration) {
+	e := progressEvent{eventHeader: newEventHeader("progress", id, url), Downloaded: downloaded, Size: size}
+	if size > 0 {
+		e.Percent = math.Round(float64(downloaded)*10000/float64(size)) / 100
+	}
+	if secs := since.Seconds(); secs > 0 {
+		e.Speed = int64(float64(max(downloaded-lastDownloaded, 0)) / secs)
+	}
+	ew.emit(e)
+}
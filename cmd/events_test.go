@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+// decodeEvents parses the events written by an eventWriter
+func decodeEvents(t *testing.T, out *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var e map[string]any
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("Invalid event: %v", err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestRunHeadless_JSONEvents(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	content := bytes.Repeat([]byte("events"), 50000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file.bin" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	events := newEventWriter(&out, time.Millisecond)
+	if err := runHeadless(context.Background(), server.URL+"/file.bin", t.TempDir(), download.Options{}, false, config.DefaultSettings(), events); err != nil {
+		t.Fatalf("runHeadless failed: %v", err)
+	}
+	got := decodeEvents(t, &out)
+	if len(got) < 2 || got[0]["event"] != "started" || got[len(got)-1]["event"] != "completed" {
+		t.Fatalf("Events = %v, want started first and completed last", got)
+	}
+	sum := sha256.Sum256(content)
+	completed := got[len(got)-1]
+	if completed["sha256"] != hex.EncodeToString(sum[:]) || completed["size"] != float64(len(content)) {
+		t.Errorf("Completed event = %v", completed)
+	}
+	for _, e := range got {
+		if e["id"] != got[0]["id"] || e["url"] != server.URL+"/file.bin" || e["time"] == nil {
+			t.Errorf("Event %v doesn't carry the download's id, url and time", e)
+		}
+	}
+
+	out.Reset()
+	if err := runHeadless(context.Background(), server.URL+"/missing", t.TempDir(), download.Options{}, false, config.DefaultSettings(), events); err == nil {
+		t.Fatal("runHeadless of a missing file should fail")
+	}
+	got = decodeEvents(t, &out)
+	if len(got) != 1 || got[0]["event"] != "error" || got[0]["kind"] != "http" || got[0]["exit_code"] != float64(exitHTTP) {
+		t.Errorf("Events of a failed download = %v", got)
+	}
+}
+
+func TestBatchSummaryEvent(t *testing.T) {
+	results := []batchResult{
+		{URL: "https://example.com/a", DestPath: "/tmp/a", Size: 100, Elapsed: time.Second},
+		{URL: "https://example.com/b", Err: types.NewError(types.KindNetwork, errors.New("reset")), Kind: types.KindNetwork},
+	}
+	s := batchSummaryEvent(results, 2*time.Second, "urls.txt.failed")
+	if s.Event != "summary" || s.Total != 2 || s.Completed != 1 || s.Failed != 1 || s.Bytes != 100 || s.ExitCode != exitNetwork || s.DurationMs != 2000 {
+		t.Errorf("Summary = %+v", s)
+	}
+	if s.Downloads[0].Status != "completed" || s.Downloads[1].Status != "failed" || s.Downloads[1].Kind != "network" {
+		t.Errorf("Summary downloads = %+v", s.Downloads)
+	}
+}
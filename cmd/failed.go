@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// FailureLister exposes the downloads that stopped with an error to the HTTP API
+type FailureLister interface {
+	Failed() []download.FailedDownload
+}
+
+// FailedEntry describes a failed download in GET /failed responses
+type FailedEntry struct {
+	ID       string    `json:"id"`
+	URL      string    `json:"url"`
+	Filename string    `json:"filename,omitempty"`
+	Kind     string    `json:"kind"` // network, http, disk, integrity, cancelled, locked, config or other
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+}
+
+func makeFailedHandler(fl FailureLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if fl == nil {
+			http.Error(w, "Failed downloads are not available on this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		entries := []FailedEntry{}
+		for _, f := range fl.Failed() {
+			entries = append(entries, FailedEntry{
+				ID:       f.ID,
+				URL:      f.URL,
+				Filename: f.Filename,
+				Kind:     string(f.Kind),
+				Error:    f.Error,
+				Time:     f.Time,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// RetryRequest asks a running instance to resume a failed download
+type RetryRequest struct {
+	ID string `json:"id"`
+}
+
+// Retrier resumes a failed download of the running instance from its saved state
+type Retrier func(id string) error
+
+func makeRetryHandler(retry Retrier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if retry == nil {
+			http.Error(w, "Retrying downloads is not supported by this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req RetryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.ID == "" {
+			http.Error(w, "ID is required", http.StatusBadRequest)
+			return
+		}
+
+		utils.Debug("Received retry request: ID=%s", req.ID)
+
+		if err := retry(req.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "accepted",
+			"message": "Retry requested",
+		})
+	}
+}
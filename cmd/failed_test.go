@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+type fakeFailures []download.FailedDownload
+
+func (f fakeFailures) Failed() []download.FailedDownload { return f }
+
+func TestHandleFailed(t *testing.T) {
+	fl := fakeFailures{{ID: "a", URL: "https://example.com/a.zip", Kind: types.KindHTTP, Error: "unexpected status: 404 Not Found"}}
+	req := httptest.NewRequest(http.MethodGet, "/failed", nil)
+	rec := httptest.NewRecorder()
+
+	makeFailedHandler(fl).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var entries []FailedEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != "a" || entries[0].Kind != "http" || entries[0].Error == "" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestHandleFailed_Errors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	makeFailedHandler(fakeFailures{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/failed", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	makeFailedHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/failed", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("No lister: expected 503, got %d", rec.Code)
+	}
+}
+
+func TestHandleRetry(t *testing.T) {
+	var got string
+	retry := func(id string) error {
+		if id != "a" {
+			return fmt.Errorf("no failed download with ID %s", id)
+		}
+		got = id
+		return nil
+	}
+
+	rec := httptest.NewRecorder()
+	makeRetryHandler(retry).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/retry", strings.NewReader(`{"id": "a"}`)))
+	if rec.Code != http.StatusOK || got != "a" {
+		t.Errorf("Expected 200 and a retry of a, got %d (%q)", rec.Code, got)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		retrier Retrier
+		want    int
+	}{
+		{"wrong method", http.MethodGet, "", retry, http.StatusMethodNotAllowed},
+		{"no retrier", http.MethodPost, `{"id": "a"}`, nil, http.StatusServiceUnavailable},
+		{"bad json", http.MethodPost, "{", retry, http.StatusBadRequest},
+		{"missing id", http.MethodPost, `{}`, retry, http.StatusBadRequest},
+		{"unknown id", http.MethodPost, `{"id": "b"}`, retry, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			makeRetryHandler(tt.retrier).ServeHTTP(rec, httptest.NewRequest(tt.method, "/retry", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		kinds []types.ErrorKind
+		want  int
+	}{
+		{nil, 0},
+		{[]types.ErrorKind{types.KindNetwork}, exitNetwork},
+		{[]types.ErrorKind{types.KindHTTP, types.KindHTTP}, exitHTTP},
+		{[]types.ErrorKind{types.KindDisk}, exitDisk},
+		{[]types.ErrorKind{types.KindIntegrity}, exitIntegrity},
+		{[]types.ErrorKind{types.KindCancelled}, exitCancelled},
+		{[]types.ErrorKind{types.KindOther}, exitFailure},
+		{[]types.ErrorKind{types.KindHTTP, types.KindNetwork}, exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.kinds); got != tt.want {
+			t.Errorf("exitCodeFor(%v) = %d, want %d", tt.kinds, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,11 +8,18 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
+	"github.com/pulse-downloader/pulse/internal/config"
 	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/extract"
+	"github.com/pulse-downloader/pulse/internal/hooks"
 	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/notify"
 	"github.com/pulse-downloader/pulse/internal/utils"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -23,89 +29,241 @@ import (
 
 const progressChannelBuffer = 100
 
-// readURLsFromFile reads URLs from a file, one per line
-func readURLsFromFile(filepath string) ([]string, error) {
-	file, err := os.Open(filepath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	var urls []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line != "" && !strings.HasPrefix(line, "#") {
-			urls = append(urls, line)
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
+// headlessProgressInterval is how often runHeadless checks the progress of its download
+const headlessProgressInterval = 500 * time.Millisecond
 
-	if len(urls) == 0 {
-		return nil, fmt.Errorf("no URLs found in file")
-	}
-
-	return urls, nil
-}
-
-// runHeadless runs a download without TUI, printing progress to stderr
-func runHeadless(ctx context.Context, url, outPath, quality string, verbose bool) error {
+// runHeadless runs a download without TUI, printing progress to stderr.
+// With events set, it writes the download's events instead. When ctx is cancelled the download
+// is paused like in the TUI, so its progress is saved and a later run can continue it.
+func runHeadless(ctx context.Context, url, outPath string, opts download.Options, verbose bool, settings *config.Settings, events *eventWriter) error {
 	eventCh := make(chan tea.Msg, progressChannelBuffer)
 
 	startTime := time.Now()
 	var totalSize int64
 	var lastProgress int64
+	var resumed int64 // Bytes an earlier run downloaded
+	var lastEvent time.Time
+	var destPath, filename string
+	var started bool
+	category := opts.Category
+	id := uuid.New().String()
+	hookList := settings.Hooks
+	notifier := notify.New(settings.Notifications, nil)
+	rc := &types.RuntimeConfig{
+		MaxConnectionsPerHost: settings.Connections.MaxConnectionsPerHost,
+		MaxGlobalConnections:  settings.Connections.MaxGlobalConnections,
+		UserAgent:             settings.Connections.UserAgent,
+		Categories:            convertCategoryRules(settings.Categories),
+	}
+	progress := types.NewProgressState(id, 0)
+
+	// The download gets its own context: cancelling it before pausing would skip saving its state
+	downloadCtx, cancelDownload := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelDownload()
 
 	// Start download in background
 	errCh := make(chan error, 1)
 	go func() {
-		err := download.Download(ctx, url, outPath, quality, verbose, eventCh, uuid.New().String())
+		err := download.TUIDownload(downloadCtx, types.DownloadConfig{
+			URL:        url,
+			OutputPath: outPath,
+			ID:         id,
+			Filename:   opts.Filename,
+			Quality:    opts.Quality,
+			Category:   opts.Category,
+			Headers:    opts.Headers,
+			Checksum:   opts.Checksum,
+			Mirrors:    opts.Mirrors,
+			Output:     opts.Output,
+			Continue:   opts.Continue,
+			Verbose:    verbose,
+			ProgressCh: eventCh,
+			State:      progress,
+			Runtime:    rc,
+		})
 		errCh <- err
 		close(eventCh)
 	}()
 
+	interval := headlessProgressInterval
+	if events != nil {
+		interval = min(interval, events.interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// fail reports a failed download to hooks, notifiers and the event stream
+	fail := func(err error) error {
+		runEventHooks(hookList, hooks.Info{Event: hooks.EventError, ID: id, URL: url, File: incompletePath(destPath), Size: totalSize, Category: category, Error: err.Error()})
+		sendNotifications(notifier, notify.Notification{Event: notify.EventError, ID: id, URL: url, Filename: filename, Path: destPath, Size: totalSize, Category: category, Error: err.Error()})
+		if events != nil {
+			kind := types.Classify(err)
+			events.emit(errorEvent{eventHeader: newEventHeader("error", id, url), Path: destPath, Kind: string(kind), Error: err.Error(), ExitCode: exitCodeFor([]types.ErrorKind{kind})})
+		}
+		return err
+	}
+
 	// Process events
-	for msg := range eventCh {
-		switch m := msg.(type) {
-		case messages.DownloadStartedMsg:
-			// Reset start time to exclude probing time
-			startTime = time.Now()
-			totalSize = m.Total
-			fmt.Fprintf(os.Stderr, "Downloading: %s (%s)\n", m.Filename, utils.ConvertBytesToHumanReadable(totalSize))
-		case messages.ProgressMsg:
-			if totalSize > 0 {
-				percent := m.Downloaded * 100 / totalSize
+	interrupted := ctx.Done()
+events:
+	for {
+		select {
+		case <-interrupted:
+			interrupted = nil
+			if events == nil {
+				fmt.Fprintf(os.Stderr, "Interrupted, pausing...\n")
+			}
+			progress.Pause()
+			cancelDownload() // Still probing, or the downloader hasn't installed its cancel func
+		case msg, ok := <-eventCh:
+			if !ok {
+				break events
+			}
+			switch m := msg.(type) {
+			case messages.DownloadStartedMsg:
+				// Reset start time to exclude probing time
+				startTime = time.Now()
+				lastEvent = startTime
+				started = true
+				totalSize = m.Total
+				destPath = m.DestPath
+				filename = m.Filename
+				category = m.Category
+				resumed, lastProgress = m.Resumed, m.Resumed
+				if events != nil {
+					events.emit(startedEvent{eventHeader: newEventHeader("started", id, url), Filename: m.Filename, Path: m.DestPath, Size: m.Total, Category: m.Category})
+				} else if resumed > 0 {
+					fmt.Fprintf(os.Stderr, "Continuing: %s (%s of %s already downloaded)\n", m.Filename,
+						utils.ConvertBytesToHumanReadable(resumed), utils.ConvertBytesToHumanReadable(totalSize))
+				} else if category != "" {
+					fmt.Fprintf(os.Stderr, "Downloading: %s (%s) [%s]\n", m.Filename, utils.ConvertBytesToHumanReadable(totalSize), category)
+				} else {
+					fmt.Fprintf(os.Stderr, "Downloading: %s (%s)\n", m.Filename, utils.ConvertBytesToHumanReadable(totalSize))
+				}
+			case messages.HealthEventMsg:
+				if events == nil {
+					fmt.Fprintf(os.Stderr, "  Warning: %s\n", m.Detail)
+				}
+			case messages.DownloadErrorMsg:
+				return fail(m.Err)
+			}
+		case <-ticker.C:
+			if !started {
+				break // Still probing
+			}
+			downloaded := progress.Downloaded.Load()
+			if events != nil {
+				if since := time.Since(lastEvent); since >= events.interval {
+					events.progress(id, url, downloaded, totalSize, lastProgress, since)
+					lastProgress, lastEvent = downloaded, time.Now()
+				}
+			} else if totalSize > 0 {
+				percent := downloaded * 100 / totalSize
 				lastPercent := lastProgress * 100 / totalSize
-				if percent/10 > lastPercent/10 {
-					speed := float64(m.Downloaded) / time.Since(startTime).Seconds() / (1024 * 1024)
+				if percent/10 > lastPercent/10 && percent < 100 {
+					speed := float64(downloaded-resumed) / time.Since(startTime).Seconds() / (1024 * 1024)
 					fmt.Fprintf(os.Stderr, "  %d%% (%s) - %.2f MB/s\n", percent,
-						utils.ConvertBytesToHumanReadable(m.Downloaded), speed)
+						utils.ConvertBytesToHumanReadable(downloaded), speed)
 				}
-				lastProgress = m.Downloaded
+				lastProgress = downloaded
 			}
-		case messages.DownloadCompleteMsg:
-			elapsed := time.Since(startTime)
-			speed := float64(totalSize) / elapsed.Seconds() / (1024 * 1024)
-			fmt.Fprintf(os.Stderr, "Complete: %s in %s (%.2f MB/s)\n",
-				utils.ConvertBytesToHumanReadable(totalSize),
-				elapsed.Round(time.Millisecond), speed)
-		case messages.DownloadErrorMsg:
-			return m.Err
 		}
 	}
 
-	return <-errCh
+	err := <-errCh
+	if progress.IsPaused() {
+		// Interrupted on purpose: not a failure for hooks or notifications
+		saved := err == nil && destPath != "" // Only the concurrent downloader saves its state
+		err = types.NewError(types.KindCancelled, fmt.Errorf("interrupted: %w", context.Canceled))
+		if events != nil {
+			events.emit(errorEvent{eventHeader: newEventHeader("error", id, url), Path: destPath, Kind: string(types.KindCancelled), Error: err.Error(), ExitCode: exitCancelled})
+		} else if saved {
+			fmt.Fprintf(os.Stderr, "Paused: %s of %s saved, run the same command again to continue\n",
+				utils.ConvertBytesToHumanReadable(progress.Downloaded.Load()), utils.ConvertBytesToHumanReadable(totalSize))
+		}
+		return err
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if totalSize <= 0 {
+		totalSize = progress.Downloaded.Load()
+	}
+	elapsed := time.Since(startTime)
+	if events != nil {
+		events.completed(id, url, destPath, totalSize, elapsed)
+	} else {
+		speed := float64(totalSize-resumed) / elapsed.Seconds() / (1024 * 1024)
+		fmt.Fprintf(os.Stderr, "Complete: %s in %s (%.2f MB/s)\n",
+			utils.ConvertBytesToHumanReadable(totalSize),
+			elapsed.Round(time.Millisecond), speed)
+	}
+	sendNotifications(notifier, notify.Notification{Event: notify.EventComplete, ID: id, URL: url, Filename: filename, Path: destPath, Size: totalSize, Category: category, Elapsed: elapsed})
+	extractDir := ""
+	if settings.General.ExtractArchives {
+		extractDir = extractArchive(ctx, destPath, settings.General.DeleteAfterExtract)
+	}
+	runEventHooks(hookList, hooks.Info{Event: hooks.EventComplete, ID: id, URL: url, File: destPath, Size: totalSize, Category: category, ExtractDir: extractDir})
+	return nil
+}
+
+// extractArchive extracts a completed archive next to it and optionally deletes it.
+// It returns the extraction directory, or "" if nothing was extracted.
+func extractArchive(ctx context.Context, archivePath string, deleteAfter bool) string {
+	if archivePath == "" || !extract.IsArchive(archivePath) {
+		return ""
+	}
+	dir := extract.TargetDir(archivePath)
+	fmt.Fprintf(os.Stderr, "Extracting: %s\n", filepath.Base(archivePath))
+	p := &extract.Progress{}
+	if err := extract.Extract(ctx, archivePath, dir, p); err != nil {
+		fmt.Fprintf(os.Stderr, "Extract failed: %v\n", err)
+		return ""
+	}
+	fmt.Fprintf(os.Stderr, "Extracted %d entries to %s\n", p.Files(), dir)
+	if deleteAfter {
+		if err := os.Remove(archivePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to delete archive: %v\n", err)
+		}
+	}
+	return dir
 }
 
-// sendToServer sends a download request to a running pulse server
-func sendToServer(url, outPath string, port int) error {
+// incompletePath returns the working file of an unfinished download
+func incompletePath(destPath string) string {
+	if destPath == "" {
+		return ""
+	}
+	return destPath + types.IncompleteSuffix
+}
+
+// sendNotifications pushes a download event to the configured notifiers and prints failures to stderr
+func sendNotifications(n *notify.Notifier, note notify.Notification) {
+	for _, r := range n.Send(context.Background(), note) {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "  notify %s: %v\n", r.Notifier, r.Err)
+		}
+	}
+}
+
+// runEventHooks runs the hooks for a download event and prints their results to stderr
+func runEventHooks(hookList []config.HookConfig, info hooks.Info) {
+	for _, r := range hooks.Run(context.Background(), hookList, info) {
+		fmt.Fprintf(os.Stderr, "  %s\n", r.Summary())
+	}
+}
+
+// sendToServer sends a download request to a running pulse server, printing its reply if report is set
+func sendToServer(url, outPath string, opts download.Options, port int, report bool) error {
 	reqBody := DownloadRequest{
-		URL:  url,
-		Path: outPath,
+		URL:      url,
+		Path:     outPath,
+		Filename: opts.Filename,
+		Quality:  opts.Quality,
+		Category: opts.Category,
+		Headers:  opts.Headers,
+		Checksum: opts.Checksum,
+		Mirrors:  opts.Mirrors,
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -124,7 +282,9 @@ func sendToServer(url, outPath string, port int) error {
 		return fmt.Errorf("server error: %s - %s", resp.Status, string(body))
 	}
 
-	fmt.Printf("Download queued: %s\n", string(body))
+	if report {
+		fmt.Printf("Download queued: %s\n", string(body))
+	}
 	return nil
 }
 
@@ -135,8 +295,22 @@ var getCmd = &cobra.Command{
 
 Use --headless for CLI-only downloads (useful for scripting).
 Use --port to send the download to a running Pulse instance.
-Use --batch to download multiple URLs from a file (one URL per line).
-Use --quality to specify video quality for YouTube downloads (e.g. 720p, 1080p).`,
+Use --batch to download multiple URLs from a file (one URL per line, or an aria2 input
+file with mirrors and out=, dir=, header=, checksum= and quality= options); headless batches
+run --jobs downloads at a time and write the URLs that failed to a file for re-running.
+Use --quality to specify video quality for YouTube downloads (e.g. 720p, 1080p).
+Use --category to pick a category instead of letting the category rules decide.
+Use --name to save under a different filename.
+Use --header to send extra request headers, e.g. -H "Cookie: session=abc".
+Use --checksum to verify the finished file, e.g. --checksum sha256=<hex>.
+Use --output-format json to write one JSON event per line to stdout for scripts.
+Use --continue=false to start over instead of continuing a partial download of the same
+URL in the same place, left by an interrupted run (Ctrl+C pauses and saves progress).
+Use -O FILE to save to FILE, or -O - to write the file to stdout for piping, e.g.
+"pulse get URL -O - | tar x". Streams still use several connections when the server allows.
+
+Exit codes: 0 success, 1 unclassified or mixed failures, 3 network, 4 HTTP,
+5 disk, 6 integrity, 130 cancelled.`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		outPath, _ := cmd.Flags().GetString("output")
@@ -144,43 +318,97 @@ Use --quality to specify video quality for YouTube downloads (e.g. 720p, 1080p).
 		port, _ := cmd.Flags().GetInt("port")
 		batchFile, _ := cmd.Flags().GetString("batch")
 		quality, _ := cmd.Flags().GetString("quality")
+		category, _ := cmd.Flags().GetString("category")
+		name, _ := cmd.Flags().GetString("name")
+		headerValues, _ := cmd.Flags().GetStringArray("header")
+		checksum, _ := cmd.Flags().GetString("checksum")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		failedFile, _ := cmd.Flags().GetString("failed-file")
+		outputFormat, _ := cmd.Flags().GetString("output-format")
+		progressInterval, _ := cmd.Flags().GetDuration("progress-interval")
+		outputDocument, _ := cmd.Flags().GetString("output-document")
+		continuePartial, _ := cmd.Flags().GetBool("continue")
 
-		// Collect URLs to download
-		var urls []string
-		if batchFile != "" {
-			// Batch mode: read URLs from file
-			var err error
-			urls, err = readURLsFromFile(batchFile)
-			if err != nil {
+		// Scripts get one JSON event per line on stdout instead of the human-readable progress
+		var events *eventWriter
+		switch outputFormat {
+		case outputText:
+		case outputJSON:
+			events = newEventWriter(os.Stdout, progressInterval)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown output format %q (want %s or %s)\n", outputFormat, outputText, outputJSON)
+			os.Exit(1)
+		}
+
+		headers, err := parseHeaders(headerValues)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if checksum != "" {
+			if _, err := download.ParseChecksum(checksum); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+		}
+		opts := download.Options{
+			Filename: name,
+			Quality:  quality,
+			Category: category,
+			Headers:  headers,
+			Checksum: checksum,
+			Continue: continuePartial,
+		}
 
-			// Filter out duplicate URLs
-			seen := make(map[string]bool)
-			uniqueURLs := make([]string, 0, len(urls))
-			for _, url := range urls {
-				normalized := strings.TrimRight(url, "/")
-				if !seen[normalized] {
-					seen[normalized] = true
-					uniqueURLs = append(uniqueURLs, url)
+		// -O names the file itself, or with "-" streams it to stdout without saving it
+		if outputDocument != "" {
+			if batchFile != "" || port > 0 {
+				fmt.Fprintf(os.Stderr, "Error: --output-document applies to a single headless download, not --batch or --port\n")
+				os.Exit(1)
+			}
+			if outPath != "" || name != "" {
+				fmt.Fprintf(os.Stderr, "Error: --output-document can't be combined with --output or --name\n")
+				os.Exit(1)
+			}
+			if outputDocument == "-" {
+				if events != nil {
+					fmt.Fprintf(os.Stderr, "Error: -O - writes the file to stdout, which --output-format json needs for its events\n")
+					os.Exit(1)
 				}
+				opts.Output = os.Stdout
+			} else {
+				outPath = filepath.Dir(outputDocument)
+				opts.Filename = filepath.Base(outputDocument)
+			}
+		}
+
+		// Collect downloads
+		var entries []download.InputEntry
+		if batchFile != "" {
+			// Batch mode: read URLs and their options from file
+			var duplicates int
+			entries, duplicates, err = download.ReadInputFile(batchFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
-			duplicates := len(urls) - len(uniqueURLs)
-			urls = uniqueURLs
 
 			if duplicates > 0 {
-				fmt.Fprintf(os.Stderr, "Loaded %d URLs from %s (%d duplicates ignored)\n", len(urls), batchFile, duplicates)
+				fmt.Fprintf(os.Stderr, "Loaded %d URLs from %s (%d duplicates ignored)\n", len(entries), batchFile, duplicates)
 			} else {
-				fmt.Fprintf(os.Stderr, "Loaded %d URLs from %s\n", len(urls), batchFile)
+				fmt.Fprintf(os.Stderr, "Loaded %d URLs from %s\n", len(entries), batchFile)
 			}
 		} else if len(args) == 1 {
 			// Single URL mode
-			urls = []string{args[0]}
+			entries = []download.InputEntry{{URL: args[0]}}
 		} else {
 			fmt.Fprintf(os.Stderr, "Error: requires either a URL argument or --batch flag\n")
 			os.Exit(1)
 		}
+		if len(entries) > 1 && (name != "" || checksum != "") {
+			fmt.Fprintf(os.Stderr, "Error: --name and --checksum apply to a single download, not a batch (use out= and checksum= in the batch file)\n")
+			os.Exit(1)
+		}
 
 		if outPath == "" && port == 0 {
 			// Only default to "." for headless mode.
@@ -188,40 +416,174 @@ Use --quality to specify video quality for YouTube downloads (e.g. 720p, 1080p).
 			outPath = "."
 		}
 
+		settings, err := config.LoadSettings()
+		if err != nil {
+			settings = config.DefaultSettings()
+		} else if err := settings.ValidateCategories(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		// Headless batches run in parallel through a worker pool
+		if batchFile != "" && port == 0 {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			start := time.Now()
+			results := runBatch(ctx, entries, outPath, opts, jobs, verbose, settings, events)
+			stop()
+
+			if events == nil {
+				fmt.Fprintln(os.Stderr)
+				printBatchSummary(os.Stderr, results, time.Since(start))
+			}
+			var failedKinds []types.ErrorKind
+			for _, r := range results {
+				if r.Err != nil {
+					failedKinds = append(failedKinds, r.Kind)
+				}
+			}
+			written := ""
+			if len(failedKinds) > 0 {
+				if failedFile == "" {
+					failedFile = batchFile + ".failed"
+				}
+				if n, err := writeFailedURLs(failedFile, results); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				} else {
+					written = failedFile
+					if events == nil {
+						fmt.Fprintf(os.Stderr, "Wrote %d failed URL(s) to %s (retry with: pulse get --batch %s)\n", n, failedFile, failedFile)
+					}
+				}
+			}
+			if events != nil {
+				events.emit(batchSummaryEvent(results, time.Since(start), written))
+			}
+			if len(failedKinds) > 0 {
+				os.Exit(exitCodeFor(failedKinds))
+			}
+			return
+		}
+
+		// Ctrl+C or SIGTERM pauses a headless download, saving its progress
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
 		// Process each URL
-		var failed int
-		for i, url := range urls {
-			if len(urls) > 1 {
-				fmt.Fprintf(os.Stderr, "\n[%d/%d] %s\n", i+1, len(urls), url)
+		start := time.Now()
+		var failedKinds []types.ErrorKind
+		var outcomes []summaryDownload
+		for i, e := range entries {
+			if len(entries) > 1 && events == nil {
+				fmt.Fprintf(os.Stderr, "\n[%d/%d] %s\n", i+1, len(entries), e.URL)
 			}
 
 			if port > 0 {
 				// Send to running server
-				if err := sendToServer(url, outPath, port); err != nil {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-					failed++
+				path := e.OutputPath(outPath)
+				outcome := summaryDownload{URL: e.URL, Path: path, Status: "queued"}
+				if err := sendToServer(e.URL, path, e.Options(opts), port, events == nil); err != nil {
+					kind := types.Classify(err)
+					failedKinds = append(failedKinds, kind)
+					outcome.Status, outcome.Kind, outcome.Error = "failed", string(kind), err.Error()
+					if events != nil {
+						events.emit(errorEvent{eventHeader: newEventHeader("error", "", e.URL), Kind: string(kind), Error: err.Error(), ExitCode: exitCodeFor([]types.ErrorKind{kind})})
+					} else {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					}
+				} else if events != nil {
+					events.emit(queuedEvent{eventHeader: newEventHeader("queued", "", e.URL), Path: path})
 				}
+				outcomes = append(outcomes, outcome)
 			} else {
 				// Headless download
-				ctx := context.Background()
-				if err := runHeadless(ctx, url, outPath, quality, verbose); err != nil {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-					failed++
+				if err := runHeadless(ctx, e.URL, e.OutputPath(outPath), e.Options(opts), verbose, settings, events); err != nil {
+					kind := types.Classify(err)
+					if events == nil {
+						fmt.Fprintf(os.Stderr, "Error (%s): %v\n", kind, err)
+					}
+					failedKinds = append(failedKinds, kind)
 				}
 			}
 		}
 
-		if failed > 0 {
-			fmt.Fprintf(os.Stderr, "\n%d of %d downloads failed\n", failed, len(urls))
-			os.Exit(1)
+		if events != nil && batchFile != "" {
+			summary := summaryEvent{eventHeader: newEventHeader("summary", "", ""), Total: len(outcomes), Failed: len(failedKinds), DurationMs: time.Since(start).Milliseconds(), ExitCode: exitCodeFor(failedKinds), Downloads: outcomes}
+			events.emit(summary)
+		}
+		if len(failedKinds) > 0 {
+			if events == nil {
+				fmt.Fprintf(os.Stderr, "\n%d of %d downloads failed\n", len(failedKinds), len(entries))
+			}
+			os.Exit(exitCodeFor(failedKinds))
 		}
 	},
 }
 
+// parseHeaders parses --header values given as "Name: value"
+func parseHeaders(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, err := download.ParseHeader(v)
+		if err != nil {
+			return nil, err
+		}
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+// Exit codes of pulse get, one per error kind so scripts can tell failures apart
+const (
+	exitFailure   = 1 // Unclassified failure, or failures of different kinds in a batch
+	exitNetwork   = 3
+	exitHTTP      = 4
+	exitDisk      = 5
+	exitIntegrity = 6
+	exitCancelled = 130
+)
+
+// exitCodeFor returns the exit code for a run whose downloads failed with kinds
+func exitCodeFor(kinds []types.ErrorKind) int {
+	if len(kinds) == 0 {
+		return 0
+	}
+	for _, k := range kinds[1:] {
+		if k != kinds[0] {
+			return exitFailure
+		}
+	}
+	switch kinds[0] {
+	case types.KindNetwork:
+		return exitNetwork
+	case types.KindHTTP:
+		return exitHTTP
+	case types.KindDisk:
+		return exitDisk
+	case types.KindIntegrity:
+		return exitIntegrity
+	case types.KindCancelled:
+		return exitCancelled
+	default:
+		return exitFailure
+	}
+}
+
 func init() {
 	getCmd.Flags().StringP("output", "o", "", "output directory")
 	getCmd.Flags().BoolP("verbose", "v", false, "verbose output")
 	getCmd.Flags().IntP("port", "p", 0, "send to running pulse server on this port")
-	getCmd.Flags().StringP("batch", "b", "", "file containing URLs to download (one per line)")
+	getCmd.Flags().StringP("batch", "b", "", "file containing URLs to download (one per line, or an aria2 input file)")
+	getCmd.Flags().IntP("jobs", "j", 0, "downloads to run at once in batch mode (default: max_concurrent_downloads)")
+	getCmd.Flags().String("failed-file", "", "where to write the URLs of failed batch downloads (default: <batch file>.failed)")
 	getCmd.Flags().StringP("quality", "q", "", "video quality (e.g. 720p, 1080p)")
+	getCmd.Flags().StringP("category", "c", "", "category to file the download under (default: chosen by category rules)")
+	getCmd.Flags().StringP("name", "n", "", "save the download under this filename")
+	getCmd.Flags().StringArrayP("header", "H", nil, `extra request header as "Name: value" (repeatable)`)
+	getCmd.Flags().String("checksum", "", "expected digest of the file as algo=hex (md5, sha1, sha256 or sha512)")
+	getCmd.Flags().Bool("continue", true, "continue a partial download of the same URL left in the output directory")
+	getCmd.Flags().StringP("output-document", "O", "", "save the download as this file, or write it to stdout with -")
+	getCmd.Flags().String("output-format", outputText, "text, or json for one JSON event per line on stdout")
+	getCmd.Flags().Duration("progress-interval", time.Second, "time between progress events with --output-format json")
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+// countingWriter counts the bytes a handler sends, pausing after each write while slow is set
+type countingWriter struct {
+	http.ResponseWriter
+	sent *atomic.Int64
+	slow *atomic.Bool
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	if w.slow.Load() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.sent.Add(int64(n))
+	return n, err
+}
+
+func TestRunHeadless_InterruptAndContinue(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	content := bytes.Repeat([]byte("continue"), 512*1024)
+	var sent atomic.Int64
+	var slow atomic.Bool
+	slow.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(countingWriter{w, &sent, &slow}, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	out := t.TempDir()
+	opts := download.Options{Continue: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for sent.Load() < int64(len(content))/8 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+	}()
+	var events bytes.Buffer
+	err := runHeadless(ctx, server.URL+"/file.bin", out, opts, false, config.DefaultSettings(), newEventWriter(&events, time.Hour))
+	if kind := types.Classify(err); kind != types.KindCancelled {
+		t.Fatalf("Interrupted runHeadless = %v (%s), want a %s error", err, kind, types.KindCancelled)
+	}
+	got := decodeEvents(t, &events)
+	path, _ := got[len(got)-1]["path"].(string)
+	if _, err := os.Stat(path + types.IncompleteSuffix); err != nil {
+		t.Fatalf("Interrupted download left no partial file: %v", err)
+	}
+
+	slow.Store(false)
+	sent.Store(0)
+	events.Reset()
+	if err := runHeadless(context.Background(), server.URL+"/file.bin", out, opts, false, config.DefaultSettings(), newEventWriter(&events, time.Hour)); err != nil {
+		t.Fatalf("Continued runHeadless failed: %v", err)
+	}
+	got = decodeEvents(t, &events)
+	if completed := got[len(got)-1]; completed["path"] != path {
+		t.Errorf("Continued download completed at %v, want %s", completed["path"], path)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("Continued file differs from the original (err %v)", err)
+	}
+	if sent.Load() >= int64(len(content)) {
+		t.Errorf("Continued download fetched %d bytes, want less than the whole %d", sent.Load(), len(content))
+	}
+}
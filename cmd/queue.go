@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// QueueController exposes the worker pool's queue ordering to the HTTP API
+type QueueController interface {
+	Queued() []types.DownloadConfig
+	Move(id string, op download.MoveOp) error
+	SetPriority(id string, priority types.Priority) error
+	StartNow(id string) error
+}
+
+// QueueEntry describes a pending download in GET /queue responses
+type QueueEntry struct {
+	Position int    `json:"position"` // 1-based start order
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Filename string `json:"filename,omitempty"`
+	Priority string `json:"priority"`
+}
+
+// QueueRequest reorders, reprioritises or starts a queued download
+type QueueRequest struct {
+	ID        string `json:"id"`
+	Direction string `json:"direction,omitempty"` // "up", "down", "top" or "bottom" (/queue/move)
+	Priority  string `json:"priority,omitempty"`  // "high", "normal" or "low" (/queue/priority)
+}
+
+// registerQueueHandlers adds the queue endpoints to mux
+func registerQueueHandlers(mux *http.ServeMux, qc QueueController) {
+	mux.HandleFunc("/queue", makeQueueListHandler(qc))
+	mux.HandleFunc("/queue/move", makeQueueActionHandler(qc, "move"))
+	mux.HandleFunc("/queue/priority", makeQueueActionHandler(qc, "priority"))
+	mux.HandleFunc("/queue/start", makeQueueActionHandler(qc, "start"))
+}
+
+func makeQueueListHandler(qc QueueController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if qc == nil {
+			http.Error(w, "Queue is not available on this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		entries := []QueueEntry{}
+		for i, cfg := range qc.Queued() {
+			entries = append(entries, QueueEntry{
+				Position: i + 1,
+				ID:       cfg.ID,
+				URL:      cfg.URL,
+				Filename: cfg.Filename,
+				Priority: cfg.Priority.String(),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+func makeQueueActionHandler(qc QueueController, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if qc == nil {
+			http.Error(w, "Queue is not available on this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req QueueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.ID == "" {
+			http.Error(w, "ID is required", http.StatusBadRequest)
+			return
+		}
+
+		utils.Debug("Received queue %s request: ID=%s, Direction=%s, Priority=%s", action, req.ID, req.Direction, req.Priority)
+
+		var err error
+		switch action {
+		case "move":
+			op := download.MoveOp(strings.ToLower(req.Direction))
+			switch op {
+			case download.MoveUp, download.MoveDown, download.MoveTop, download.MoveBottom:
+			default:
+				http.Error(w, "Direction must be up, down, top or bottom", http.StatusBadRequest)
+				return
+			}
+			err = qc.Move(req.ID, op)
+		case "priority":
+			priority, perr := types.ParsePriority(req.Priority)
+			if perr != nil || req.Priority == "" {
+				http.Error(w, "Priority must be high, normal or low", http.StatusBadRequest)
+				return
+			}
+			err = qc.SetPriority(req.ID, priority)
+		case "start":
+			err = qc.StartNow(req.ID)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "ok",
+		})
+	}
+}
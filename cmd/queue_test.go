@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+// fakeQueue records the last operation applied to it
+type fakeQueue struct {
+	queued   []types.DownloadConfig
+	op       string
+	id       string
+	move     download.MoveOp
+	priority types.Priority
+	err      error
+}
+
+func (f *fakeQueue) Queued() []types.DownloadConfig { return f.queued }
+
+func (f *fakeQueue) Move(id string, op download.MoveOp) error {
+	f.op, f.id, f.move = "move", id, op
+	return f.err
+}
+
+func (f *fakeQueue) SetPriority(id string, p types.Priority) error {
+	f.op, f.id, f.priority = "priority", id, p
+	return f.err
+}
+
+func (f *fakeQueue) StartNow(id string) error {
+	f.op, f.id = "start", id
+	return f.err
+}
+
+func TestHandleQueueList(t *testing.T) {
+	fq := &fakeQueue{queued: []types.DownloadConfig{
+		{ID: "a", URL: "https://example.com/a.zip", Priority: types.PriorityHigh},
+		{ID: "b", URL: "https://example.com/b.zip", Filename: "b.zip"},
+	}}
+	req := httptest.NewRequest(http.MethodGet, "/queue", nil)
+	rec := httptest.NewRecorder()
+
+	makeQueueListHandler(fq).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var entries []QueueEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ID != "a" || entries[0].Priority != "high" || entries[1].Position != 2 || entries[1].Filename != "b.zip" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestHandleQueueList_Errors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	makeQueueListHandler(&fakeQueue{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/queue", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	makeQueueListHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/queue", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
+
+func TestHandleQueueAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		body     string
+		wantCode int
+		check    func(*fakeQueue) bool
+	}{
+		{"move", "move", `{"id": "a", "direction": "Top"}`, http.StatusOK, func(f *fakeQueue) bool { return f.op == "move" && f.move == download.MoveTop }},
+		{"bad direction", "move", `{"id": "a", "direction": "sideways"}`, http.StatusBadRequest, func(f *fakeQueue) bool { return f.op == "" }},
+		{"priority", "priority", `{"id": "a", "priority": "low"}`, http.StatusOK, func(f *fakeQueue) bool { return f.op == "priority" && f.priority == types.PriorityLow }},
+		{"missing priority", "priority", `{"id": "a"}`, http.StatusBadRequest, func(f *fakeQueue) bool { return f.op == "" }},
+		{"start", "start", `{"id": "a"}`, http.StatusOK, func(f *fakeQueue) bool { return f.op == "start" && f.id == "a" }},
+		{"missing id", "start", `{}`, http.StatusBadRequest, func(f *fakeQueue) bool { return f.op == "" }},
+		{"invalid json", "start", `{`, http.StatusBadRequest, func(f *fakeQueue) bool { return f.op == "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fq := &fakeQueue{}
+			req := httptest.NewRequest(http.MethodPost, "/queue/"+tt.action, bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			makeQueueActionHandler(fq, tt.action).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Expected %d, got %d", tt.wantCode, rec.Code)
+			}
+			if !tt.check(fq) {
+				t.Errorf("Unexpected controller state: %+v", fq)
+			}
+		})
+	}
+}
+
+func TestHandleQueueAction_Errors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	makeQueueActionHandler(&fakeQueue{}, "start").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/queue/start", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	makeQueueActionHandler(nil, "start").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/queue/start", bytes.NewBufferString(`{"id": "a"}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	fq := &fakeQueue{err: fmt.Errorf("download a is not queued")}
+	makeQueueActionHandler(fq, "start").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/queue/start", bytes.NewBufferString(`{"id": "a"}`)))
+	if rec.Code != http.StatusConflict || !bytes.Contains(rec.Body.Bytes(), []byte("not queued")) {
+		t.Errorf("Expected 409 with controller error, got %d %q", rec.Code, rec.Body.String())
+	}
+}
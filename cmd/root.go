@@ -7,9 +7,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/types"
 	"github.com/pulse-downloader/pulse/internal/tui"
 	"github.com/pulse-downloader/pulse/internal/utils"
 
@@ -26,6 +29,32 @@ var (
 // serverProgram holds the TUI program for sending messages from HTTP handler
 var serverProgram *tea.Program
 
+// urlChanger handles /change-url requests for the running instance (TUI or headless server)
+var urlChanger URLChanger
+
+// maxDownloadsSetter handles /max-concurrent requests for the running instance (TUI or headless server)
+var maxDownloadsSetter MaxDownloadsSetter
+
+// queueController handles /queue requests for the running instance (TUI or headless server)
+var queueController QueueController
+
+// failureLister handles /failed requests for the running instance (TUI or headless server)
+var failureLister FailureLister
+
+// retrier handles /retry requests for the running instance (TUI or headless server)
+var retrier Retrier
+
+// downloadLister handles /downloads requests for the running instance (TUI or headless server)
+var downloadLister DownloadLister
+
+// pauser, resumer and remover handle /pause, /resume and /remove requests for the running
+// instance (TUI or headless server)
+var pauser, resumer, remover DownloadAction
+
+// categoryRules returns the category rules of the running instance (TUI or headless server),
+// which a /download request's category must name
+var categoryRules func() []types.CategoryRule
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:     "pulse",
@@ -45,22 +74,70 @@ var rootCmd = &cobra.Command{
 
 		// Create TUI program
 		model := tui.InitialRootModel(port, Version)
-		serverProgram = tea.NewProgram(model, tea.WithAltScreen())
+		serverProgram = tea.NewProgram(model, tea.WithAltScreen(), tea.WithOutput(model.Terminal))
+
+		// The pool notifies the TUI of queue changes made over the API
+		queueController = model.Pool
+		categoryRules = model.CategoryRules
+		failureLister = model.Pool
+
+		// Limit changes go through the TUI so the setting is saved and the outcome logged
+		maxDownloadsSetter = func(n int) error {
+			if serverProgram != nil {
+				serverProgram.Send(tui.SetMaxDownloadsMsg{Max: n})
+			}
+			return nil
+		}
+
+		// URL changes are made by the pool, which reports the resumed download; the TUI only
+		// updates its listing
+		urlChanger = func(id, newURL string) error {
+			if err := model.Pool.ChangeURL(id, newURL); err != nil {
+				return err
+			}
+			if serverProgram != nil {
+				serverProgram.Send(tui.URLChangedMsg{DownloadID: id, URL: newURL})
+			}
+			return nil
+		}
+
+		// Retries are started by the pool; the TUI only updates its listing
+		retrier = func(id string) error {
+			if err := model.Pool.Retry(id); err != nil {
+				return err
+			}
+			if serverProgram != nil {
+				serverProgram.Send(tui.DownloadRetriedMsg{DownloadID: id})
+			}
+			return nil
+		}
+
+		// The pool reports pauses and resumes to the TUI; removals are passed on for its list
+		downloadLister = model.Pool.Downloads
+		pauser = model.Pool.Pause
+		resumer = model.Pool.Resume
+		remover = func(id string) error {
+			if err := model.Pool.Remove(id); err != nil {
+				return err
+			}
+			if serverProgram != nil {
+				serverProgram.Send(tui.DownloadRemovedMsg{DownloadID: id})
+			}
+			return nil
+		}
 
 		// Start HTTP server in background (reuse the listener)
-		go startHTTPServer(listener, port, func(url, path, filename, quality string) {
+		go startHTTPServer(listener, port, func(req DownloadRequest) {
 			if serverProgram != nil {
 				serverProgram.Send(tui.StartDownloadMsg{
-					URL:      url,
-					Path:     path,
-					Filename: filename,
-					// Quality: quality - Tui StartDownloadMsg doesn't have quality yet but we updated the models?
-					// Actually the previous edit to update.go added Quality support to startDownload logic but
-					// checks for YouTube URL first.
-					// StartDownloadMsg needs updating if we want to pass quality directly.
-					// But for now let's just pass basic info.
-					// Wait, if I want headless to work with quality, the dispatcher needs to handle it.
-					// The TUI dispatcher currently sends StartDownloadMsg.
+					URL:      req.URL,
+					Path:     req.Path,
+					Filename: req.Filename,
+					Quality:  req.Quality,
+					Category: req.Category,
+					Headers:  req.Headers,
+					Checksum: req.Checksum,
+					Mirrors:  req.Mirrors,
 				})
 			}
 		}, "")
@@ -94,6 +171,29 @@ func saveActivePort(port int) {
 	utils.Debug("HTTP server listening on port %d", port)
 }
 
+// readActivePort reads the port of a running Pulse instance from ~/.pulse/port
+func readActivePort() (int, error) {
+	portFile := filepath.Join(config.GetPulseDir(), "port")
+	data, err := os.ReadFile(portFile)
+	if err != nil {
+		return 0, fmt.Errorf("no running pulse instance found: %w", err)
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid port file %s: %w", portFile, err)
+	}
+	return port, nil
+}
+
+// convertCategoryRules converts configured category rules for the download engine
+func convertCategoryRules(rules []config.CategoryRule) []types.CategoryRule {
+	converted := make([]types.CategoryRule, len(rules))
+	for i, r := range rules {
+		converted[i] = types.CategoryRule(r)
+	}
+	return converted
+}
+
 // removeActivePort cleans up the port file on exit
 func removeActivePort() {
 	portFile := filepath.Join(config.GetPulseDir(), "port")
@@ -116,6 +216,25 @@ func startHTTPServer(ln net.Listener, port int, dispatcher DownloadDispatcher, s
 	// Download endpoint
 	mux.HandleFunc("/download", makeDownloadHandler(dispatcher))
 
+	// Change URL endpoint
+	mux.HandleFunc("/change-url", makeChangeURLHandler(urlChanger))
+
+	// Concurrency limit endpoint
+	mux.HandleFunc("/max-concurrent", makeMaxConcurrentHandler(maxDownloadsSetter))
+
+	// Queue inspection and reordering endpoints
+	registerQueueHandlers(mux, queueController)
+
+	// Failed downloads endpoint
+	mux.HandleFunc("/failed", makeFailedHandler(failureLister))
+	mux.HandleFunc("/retry", makeRetryHandler(retrier))
+
+	// Listing and control endpoints used by pulse ls, pause, resume, rm and watch
+	mux.HandleFunc("/downloads", makeDownloadsHandler(downloadLister))
+	mux.HandleFunc("/pause", makeDownloadActionHandler(pauser, downloadLister, "downloading", "Pause"))
+	mux.HandleFunc("/resume", makeDownloadActionHandler(resumer, downloadLister, "paused", "Resume"))
+	mux.HandleFunc("/remove", makeDownloadActionHandler(remover, nil, "", "Remove"))
+
 	// Static files endpoint (if configured)
 	if staticDir != "" {
 		fileServer := http.FileServer(http.Dir(staticDir))
@@ -156,16 +275,32 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// DownloadRequest represents a download request from the browser extension
+// DownloadRequest represents a download request from the browser extension or pulse get
 type DownloadRequest struct {
-	URL      string `json:"url"`
-	Filename string `json:"filename,omitempty"`
-	Path     string `json:"path,omitempty"`
-	Quality  string `json:"quality,omitempty"` // Added for API support
+	URL      string            `json:"url"`
+	Filename string            `json:"filename,omitempty"`
+	Path     string            `json:"path,omitempty"`
+	Quality  string            `json:"quality,omitempty"` // Added for API support
+	Category string            `json:"category,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`  // Extra request headers, e.g. cookies
+	Checksum string            `json:"checksum,omitempty"` // Expected digest as "algo=hex"
+	Mirrors  []string          `json:"mirrors,omitempty"`  // Other URLs of the same file, tried in order
+}
+
+// options returns the download options carried by the request
+func (req DownloadRequest) options() download.Options {
+	return download.Options{
+		Filename: req.Filename,
+		Quality:  req.Quality,
+		Category: req.Category,
+		Headers:  req.Headers,
+		Checksum: req.Checksum,
+		Mirrors:  req.Mirrors,
+	}
 }
 
 // DownloadDispatcher defines how to handle a download request
-type DownloadDispatcher func(url, path, filename, quality string)
+type DownloadDispatcher func(req DownloadRequest)
 
 func makeDownloadHandler(dispatcher DownloadDispatcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -198,11 +333,21 @@ func makeDownloadHandler(dispatcher DownloadDispatcher) http.HandlerFunc {
 			http.Error(w, "Invalid path", http.StatusBadRequest)
 			return
 		}
+		if req.Checksum != "" {
+			if _, err := download.ParseChecksum(req.Checksum); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Category != "" && categoryRules != nil && download.FindCategory(categoryRules(), req.Category) == nil {
+			http.Error(w, fmt.Sprintf("Unknown category %q", req.Category), http.StatusBadRequest)
+			return
+		}
 
 		utils.Debug("Received download request: URL=%s, Path=%s, Quality=%s", req.URL, req.Path, req.Quality)
 
 		// Dispatch the download
-		dispatcher(req.URL, req.Path, req.Filename, req.Quality)
+		dispatcher(req)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
@@ -212,6 +357,99 @@ func makeDownloadHandler(dispatcher DownloadDispatcher) http.HandlerFunc {
 	}
 }
 
+// ChangeURLRequest asks a running instance to point a paused or failed download at a new URL
+type ChangeURLRequest struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// URLChanger defines how to handle a change URL request
+type URLChanger func(id, newURL string) error
+
+func makeChangeURLHandler(changer URLChanger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if changer == nil {
+			http.Error(w, "Changing URLs is not supported by this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req ChangeURLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.ID == "" || req.URL == "" {
+			http.Error(w, "ID and URL are required", http.StatusBadRequest)
+			return
+		}
+
+		utils.Debug("Received change URL request: ID=%s, URL=%s", req.ID, req.URL)
+
+		if err := changer(req.ID, req.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "accepted",
+			"message": "URL change requested",
+		})
+	}
+}
+
+// MaxConcurrentRequest changes how many downloads a running instance runs at once
+type MaxConcurrentRequest struct {
+	Max int `json:"max"`
+}
+
+// MaxDownloadsSetter applies a new concurrency limit to the running instance
+type MaxDownloadsSetter func(n int) error
+
+func makeMaxConcurrentHandler(setter MaxDownloadsSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if setter == nil {
+			http.Error(w, "Changing the concurrency limit is not supported by this instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req MaxConcurrentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.Max < 1 || req.Max > 10 {
+			http.Error(w, "max must be between 1 and 10", http.StatusBadRequest)
+			return
+		}
+
+		utils.Debug("Received max concurrent request: %d", req.Max)
+
+		if err := setter(req.Max); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "ok",
+			"message": fmt.Sprintf("Max concurrent downloads set to %d", req.Max),
+		})
+	}
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -221,5 +459,7 @@ func Execute() {
 
 func init() {
 	rootCmd.AddCommand(getCmd)
+	rootCmd.AddCommand(changeURLCmd)
+	rootCmd.AddCommand(lsCmd, pauseCmd, resumeCmd, rmCmd, watchCmd)
 	rootCmd.SetVersionTemplate("Pulse version {{.Version}}\n")
 }
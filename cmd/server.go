@@ -1,20 +1,25 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/google/uuid"
 	"github.com/pulse-downloader/pulse/internal/config"
 	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/state"
 	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/hooks"
 	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/notify"
 	"github.com/pulse-downloader/pulse/internal/utils"
 	"github.com/spf13/cobra"
 )
@@ -59,6 +64,8 @@ func startHeadlessServer() {
 	settings, err := config.LoadSettings()
 	if err != nil {
 		fmt.Printf("Warning: Failed to load settings: %v\n", err)
+	} else if err := settings.ValidateCategories(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
 
 	// Create progress channel
@@ -68,7 +75,90 @@ func startHeadlessServer() {
 	pool := download.NewWorkerPool(progressChan, settings.General.MaxConcurrentDownloads)
 
 	// Start progress consumer
-	go consumeProgress(progressChan)
+	go consumeProgress(progressChan, settings)
+
+	runtimeConfig := func(s *config.Settings) *types.RuntimeConfig {
+		return &types.RuntimeConfig{
+			MaxConnectionsPerHost: s.Connections.MaxConnectionsPerHost,
+			MaxGlobalConnections:  s.Connections.MaxGlobalConnections,
+			UserAgent:             s.Connections.UserAgent,
+			DownloadRetries:       s.Performance.DownloadRetries,
+			DownloadRetryDelay:    s.Performance.DownloadRetryDelay,
+			Categories:            convertCategoryRules(s.Categories),
+		}
+	}
+	live := types.NewLiveRuntime(runtimeConfig(settings))
+
+	// Hot-reload settings.json: running downloads and the pool follow the edited values
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go config.WatchSettings(watchCtx, 2*time.Second, func(s *config.Settings) {
+		live.Set(runtimeConfig(s))
+		if s.General.MaxConcurrentDownloads != pool.MaxDownloads() {
+			if _, err := pool.SetMaxDownloads(s.General.MaxConcurrentDownloads, s.General.PauseOnShrink); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+		utils.Debug("Settings reloaded")
+	})
+
+	// Downloads cut short by a crash are kept as paused, resumable from their last checkpoint
+	if recovered, err := state.RecoverInterrupted(); err != nil {
+		utils.Debug("Failed to recover interrupted downloads: %v", err)
+	} else if len(recovered) > 0 {
+		fmt.Printf("Recovered %d interrupted download(s)\n", len(recovered))
+	}
+
+	// Paused downloads stay listed for `pulse resume`; with auto_resume they continue straight away,
+	// ahead of the queue since they started before it
+	if settings.General.AutoResume {
+		if paused, err := state.LoadPausedDownloads(); err == nil {
+			resumed := 0
+			for _, entry := range paused {
+				if state.DownloadOwner(entry.DestPath) != nil {
+					continue // Running in another pulse process
+				}
+				cfg := download.ResumedConfig(entry)
+				cfg.Verbose = headlessVerbose
+				cfg.ProgressCh = progressChan
+				cfg.Live = live
+				pool.Add(cfg)
+				resumed++
+			}
+			if resumed > 0 {
+				fmt.Printf("Resumed %d paused download(s)\n", resumed)
+			}
+		}
+	}
+
+	// Re-enqueue downloads that were still waiting, or had started without saving any
+	// progress, when the server last stopped
+	if queued, err := state.LoadQueuedDownloads(); err == nil {
+		for _, entry := range queued {
+			cfg := download.RestoredConfig(entry)
+			cfg.Verbose = headlessVerbose
+			cfg.ProgressCh = progressChan
+			cfg.State = types.NewProgressState(entry.ID, 0)
+			cfg.Live = live
+			pool.Add(cfg)
+		}
+		if len(queued) > 0 {
+			fmt.Printf("Restored %d queued download(s)\n", len(queued))
+		}
+	}
+	pool.EnableQueuePersistence()
+
+	// Downloads that failed in an earlier run can still be listed and retried
+	if failed, err := state.LoadFailedDownloads(); err == nil {
+		for _, entry := range failed {
+			cfg := download.RestoredConfig(entry)
+			cfg.Verbose = headlessVerbose
+			cfg.ProgressCh = progressChan
+			cfg.State = types.NewProgressState(entry.ID, 0)
+			cfg.Live = live
+			pool.RestoreFailed(entry, cfg)
+		}
+	}
 
 	// Create listener
 	addr := fmt.Sprintf("%s:%d", serverHost, serverPort)
@@ -79,7 +169,8 @@ func startHeadlessServer() {
 	}
 
 	// Create Dispatcher
-	dispatcher := func(url, path, filename, quality string) {
+	dispatcher := func(req DownloadRequest) {
+		url, path := req.URL, req.Path
 		// Default path if empty
 		if path == "" {
 			path = settings.General.DefaultDownloadDir
@@ -103,22 +194,45 @@ func startHeadlessServer() {
 			URL:        url,
 			OutputPath: path,
 			ID:         id,
-			Filename:   filename,
-			Quality:    quality,
+			Filename:   req.Filename,
+			Quality:    req.Quality,
+			Category:   req.Category,
+			Headers:    req.Headers,
+			Checksum:   req.Checksum,
+			Mirrors:    req.Mirrors,
 			Verbose:    headlessVerbose,
 			ProgressCh: progressChan,
 			State:      types.NewProgressState(id, 0),
-			Runtime: &types.RuntimeConfig{
-				MaxConnectionsPerHost: settings.Connections.MaxConnectionsPerHost,
-				MaxGlobalConnections:  settings.Connections.MaxGlobalConnections,
-				UserAgent:             settings.Connections.UserAgent,
-			},
+			Live:       live,
 		}
 
 		utils.Debug("Dispatching download: %s -> %s", url, path)
 		pool.Add(cfg)
 	}
 
+	urlChanger = pool.ChangeURL
+	queueController = pool
+	categoryRules = func() []types.CategoryRule { return live.Get().Categories }
+	failureLister = pool
+	retrier = pool.Retry
+	downloadLister = pool.Downloads
+	pauser = pool.Pause
+	resumer = pool.Resume
+	remover = pool.Remove
+	maxDownloadsSetter = func(n int) error {
+		paused, err := pool.SetMaxDownloads(n, settings.General.PauseOnShrink)
+		if err != nil {
+			return err
+		}
+		utils.Debug("Max concurrent downloads set to %d (paused %d)", n, len(paused))
+		settings.General.MaxConcurrentDownloads = n
+		return config.SaveSettings(settings)
+	}
+
+	// Save port so CLI commands can find this instance
+	saveActivePort(serverPort)
+	defer removeActivePort()
+
 	// Start HTTP Server
 	go startHTTPServer(ln, serverPort, dispatcher, staticDir)
 
@@ -134,17 +248,50 @@ func startHeadlessServer() {
 }
 
 // consumeProgress reads messages from the worker pool.
-// In TUI mode, BubbleTea handles this. In Headless, we just log.
+// In TUI mode, BubbleTea handles this. In Headless, we just log, extract archives, run hooks and notify.
 // A more advanced version would maintain state for API polling.
-func consumeProgress(ch <-chan tea.Msg) {
+func consumeProgress(ch <-chan tea.Msg, settings *config.Settings) {
+	hookList := settings.Hooks
+	notifier := notify.New(settings.Notifications, nil)
+	started := make(map[string]messages.DownloadStartedMsg) // Needed to describe downloads to hooks and notifiers
 	for msg := range ch {
 		switch m := msg.(type) {
 		case messages.DownloadStartedMsg:
-			utils.Debug("STARTED: %s (%s)", m.Filename, m.URL)
+			utils.Debug("STARTED: %s (%s) [%s]", m.Filename, m.URL, m.Category)
+			started[m.DownloadID] = m
 		case messages.DownloadCompleteMsg:
 			utils.Debug("COMPLETED: %s (Time: %s)", "Download", m.Elapsed)
+			if s, ok := started[m.DownloadID]; ok {
+				go sendNotifications(notifier, notify.Notification{Event: notify.EventComplete, ID: m.DownloadID, URL: s.URL, Filename: s.Filename, Path: s.DestPath, Size: m.Total, Category: s.Category, Elapsed: m.Elapsed})
+				info := hooks.Info{Event: hooks.EventComplete, ID: m.DownloadID, URL: s.URL, File: s.DestPath, Size: m.Total, Category: s.Category}
+				go func() {
+					if settings.General.ExtractArchives {
+						info.ExtractDir = extractArchive(context.Background(), info.File, settings.General.DeleteAfterExtract)
+					}
+					runEventHooks(hookList, info)
+				}()
+				delete(started, m.DownloadID)
+			}
 		case messages.DownloadErrorMsg:
-			utils.Debug("ERROR: %s: %v", m.DownloadID, m.Err)
+			utils.Debug("ERROR (%s): %s: %v", m.Kind, m.DownloadID, m.Err)
+			// A download that failed before it started (e.g. its probe) has no file yet
+			s := started[m.DownloadID]
+			if s.URL == "" {
+				s.URL = m.URL
+			}
+			go runEventHooks(hookList, hooks.Info{Event: hooks.EventError, ID: m.DownloadID, URL: s.URL, File: incompletePath(s.DestPath), Size: s.Total, Category: s.Category, Error: m.Err.Error()})
+			go sendNotifications(notifier, notify.Notification{Event: notify.EventError, ID: m.DownloadID, URL: s.URL, Filename: s.Filename, Path: s.DestPath, Size: s.Total, Category: s.Category, Error: m.Err.Error()})
+			delete(started, m.DownloadID)
+		case messages.DownloadRetryingMsg:
+			utils.Debug("RETRYING (%s): %s in %v (%d/%d): %v", m.Kind, m.DownloadID, m.Delay, m.Attempt, m.MaxAttempts, m.Err)
+		case messages.DownloadPausedMsg:
+			s := started[m.DownloadID]
+			if s.URL == "" {
+				s.URL = m.URL
+			}
+			go runEventHooks(hookList, hooks.Info{Event: hooks.EventPause, ID: m.DownloadID, URL: s.URL, File: incompletePath(s.DestPath), Size: s.Total, Category: s.Category})
+		case messages.HealthEventMsg:
+			utils.Debug("HEALTH: %s: %s", m.DownloadID, m.Detail)
 		case messages.ProgressMsg:
 			// Verbose logging only
 			// utils.Debug("Progress: %s - %.2f%%", m.DownloadID, m.Percentage*100)
@@ -11,7 +11,9 @@ require (
 	github.com/h2non/filetype v1.1.3
 	github.com/kkdai/youtube/v2 v2.10.5
 	github.com/spf13/cobra v1.10.1
+	github.com/ulikunitz/xz v0.5.17
 	github.com/vfaronov/httpheader v0.1.0
+	golang.org/x/sys v0.36.0
 )
 
 require (
@@ -40,6 +42,5 @@ require (
 	github.com/sahilm/fuzzy v0.1.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
-	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 )
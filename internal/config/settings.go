@@ -1,18 +1,25 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 )
 
 // Settings holds all user-configurable application settings organized by category.
 type Settings struct {
-	General     GeneralSettings     `json:"general"`
-	Connections ConnectionSettings  `json:"connections"`
-	Chunks      ChunkSettings       `json:"chunks"`
-	Performance PerformanceSettings `json:"performance"`
+	General       GeneralSettings     `json:"general"`
+	Connections   ConnectionSettings  `json:"connections"`
+	Chunks        ChunkSettings       `json:"chunks"`
+	Performance   PerformanceSettings `json:"performance"`
+	Hooks         []HookConfig        `json:"hooks,omitempty"`
+	Categories    []CategoryRule      `json:"categories,omitempty"`
+	Notifications []NotifierConfig    `json:"notifications,omitempty"`
 }
 
 // GeneralSettings contains application behavior settings.
@@ -23,7 +30,10 @@ type GeneralSettings struct {
 	AutoResume             bool   `json:"auto_resume"`
 	SkipUpdateCheck        bool   `json:"skip_update_check"`
 	MaxConcurrentDownloads int    `json:"max_concurrent_downloads"`
+	PauseOnShrink          bool   `json:"pause_on_shrink"`
 	ClipboardMonitor       bool   `json:"clipboard_monitor"`
+	ExtractArchives        bool   `json:"extract_archives"`
+	DeleteAfterExtract     bool   `json:"delete_after_extract"`
 }
 
 // ConnectionSettings contains network connection parameters.
@@ -48,6 +58,42 @@ type PerformanceSettings struct {
 	SlowWorkerGracePeriod time.Duration `json:"slow_worker_grace_period"`
 	StallTimeout          time.Duration `json:"stall_timeout"`
 	SpeedEmaAlpha         float64       `json:"speed_ema_alpha"`
+	DownloadRetries       int           `json:"download_retries"`     // Automatic retries of a failed download; 0 disables them
+	DownloadRetryDelay    time.Duration `json:"download_retry_delay"` // Wait before the first retry; doubles for each further one
+}
+
+// HookConfig describes a command run when a download completes, fails or is paused.
+// Hooks are edited in settings.json; they have no settings tab.
+type HookConfig struct {
+	Name       string        `json:"name"`
+	Command    string        `json:"command"`              // Run through the system shell
+	Events     []string      `json:"events"`               // "complete", "error", "pause"
+	Categories []string      `json:"categories,omitempty"` // Empty means all downloads (global hook)
+	Timeout    time.Duration `json:"timeout,omitempty"`    // Zero uses the default hook timeout
+}
+
+// CategoryRule routes matching downloads into a destination folder.
+// Rules are checked in order and the first match wins; they are edited in settings.json.
+type CategoryRule struct {
+	Name             string   `json:"name"`
+	Extensions       []string `json:"extensions,omitempty"`        // e.g. ".iso", "mp4"
+	MIMETypes        []string `json:"mime_types,omitempty"`        // Prefix match on Content-Type, e.g. "video/"
+	Hosts            []string `json:"hosts,omitempty"`             // Host or parent domain, e.g. "youtube.com"
+	URLPattern       string   `json:"url_pattern,omitempty"`       // Regular expression matched against the URL
+	Dir              string   `json:"dir,omitempty"`               // Absolute, ~/..., or relative to the download directory
+	FilenameTemplate string   `json:"filename_template,omitempty"` // Placeholders: {name} {ext} {host} {date} {category}
+}
+
+// NotifierConfig describes a sink that download complete and error events are pushed to.
+// Notifiers are edited in settings.json; they have no settings tab.
+type NotifierConfig struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`              // "webhook", "ntfy", "gotify", "osc9", "osc777"
+	URL     string   `json:"url,omitempty"`     // Webhook endpoint, ntfy topic URL or Gotify server URL
+	Secret  string   `json:"secret,omitempty"`  // Webhook HMAC-SHA256 signing key
+	Token   string   `json:"token,omitempty"`   // ntfy access token or Gotify application token
+	Events  []string `json:"events,omitempty"`  // "complete", "error"; empty means both
+	Retries int      `json:"retries,omitempty"` // Extra delivery attempts; zero uses the default, negative disables
 }
 
 // SettingMeta provides metadata for a single setting (for UI rendering).
@@ -67,8 +113,11 @@ func GetSettingsMetadata() map[string][]SettingMeta {
 			{Key: "extension_prompt", Label: "Extension Prompt", Description: "Prompt for confirmation when adding downloads via browser extension.", Type: "bool"},
 			{Key: "auto_resume", Label: "Auto Resume", Description: "Automatically resume paused downloads on startup.", Type: "bool"},
 			{Key: "skip_update_check", Label: "Skip Update Check", Description: "Disable automatic check for new versions on startup.", Type: "bool"},
-			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Requires restart.", Type: "int"},
+			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Takes effect immediately.", Type: "int"},
+			{Key: "pause_on_shrink", Label: "Pause On Shrink", Description: "When the limit is lowered, pause the lowest priority downloads instead of letting them finish.", Type: "bool"},
 			{Key: "clipboard_monitor", Label: "Clipboard Monitor", Description: "Watch clipboard for URLs and prompt to download them.", Type: "bool"},
+			{Key: "extract_archives", Label: "Extract Archives", Description: "Extract completed zip and tar archives into a folder next to the file.", Type: "bool"},
+			{Key: "delete_after_extract", Label: "Delete After Extract", Description: "Delete the archive after it has been extracted successfully.", Type: "bool"},
 		},
 		"Connections": {
 			{Key: "max_connections_per_host", Label: "Max Connections/Host", Description: "Maximum concurrent connections per host (1-64).", Type: "int"},
@@ -87,6 +136,8 @@ func GetSettingsMetadata() map[string][]SettingMeta {
 			{Key: "slow_worker_grace_period", Label: "Slow Worker Grace", Description: "Grace period before checking worker speed (e.g., 5s).", Type: "duration"},
 			{Key: "stall_timeout", Label: "Stall Timeout", Description: "Restart workers with no data for this duration (e.g., 5s).", Type: "duration"},
 			{Key: "speed_ema_alpha", Label: "Speed EMA Alpha", Description: "Exponential moving average smoothing factor (0.0-1.0).", Type: "float64"},
+			{Key: "download_retries", Label: "Download Retries", Description: "Times to retry a failed download automatically, resuming where it stopped (0 to disable).", Type: "int"},
+			{Key: "download_retry_delay", Label: "Download Retry Delay", Description: "Wait before the first automatic retry; doubles for each further retry (e.g., 10s).", Type: "duration"},
 		},
 	}
 }
@@ -132,6 +183,8 @@ func DefaultSettings() *Settings {
 			SlowWorkerGracePeriod: 5 * time.Second,
 			StallTimeout:          3 * time.Second,
 			SpeedEmaAlpha:         0.3,
+			DownloadRetries:       3,
+			DownloadRetryDelay:    10 * time.Second,
 		},
 	}
 }
@@ -185,6 +238,48 @@ func SaveSettings(s *Settings) error {
 	return os.Rename(tempPath, path)
 }
 
+// SettingsVersion identifies one revision of the settings file on disk.
+// It is the zero value when the file does not exist.
+type SettingsVersion struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// CurrentSettingsVersion returns the revision of the settings file on disk
+func CurrentSettingsVersion() SettingsVersion {
+	info, err := os.Stat(GetSettingsPath())
+	if err != nil {
+		return SettingsVersion{}
+	}
+	return SettingsVersion{ModTime: info.ModTime(), Size: info.Size()}
+}
+
+// WatchSettings polls the settings file every interval and calls onChange with the reloaded
+// settings whenever the file changes. Files that fail to parse are skipped until fixed.
+// It returns when ctx is done.
+func WatchSettings(ctx context.Context, interval time.Duration, onChange func(*Settings)) {
+	last := CurrentSettingsVersion()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := CurrentSettingsVersion()
+			if current == last {
+				continue
+			}
+			settings, err := LoadSettings()
+			if err != nil {
+				continue
+			}
+			last = current
+			onChange(settings)
+		}
+	}
+}
+
 // ToRuntimeConfig converts Settings to a downloader RuntimeConfig
 // This is used to pass user settings to the download engine
 type RuntimeConfig struct {
@@ -200,6 +295,9 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+	DownloadRetries       int
+	DownloadRetryDelay    time.Duration
+	Categories            []CategoryRule
 }
 
 // ToRuntimeConfig creates a RuntimeConfig from user Settings
@@ -217,5 +315,32 @@ func (s *Settings) ToRuntimeConfig() *RuntimeConfig {
 		SlowWorkerGracePeriod: s.Performance.SlowWorkerGracePeriod,
 		StallTimeout:          s.Performance.StallTimeout,
 		SpeedEmaAlpha:         s.Performance.SpeedEmaAlpha,
+		DownloadRetries:       s.Performance.DownloadRetries,
+		DownloadRetryDelay:    s.Performance.DownloadRetryDelay,
+		Categories:            s.Categories,
+	}
+}
+
+// ValidateCategories reports category rules that can't be applied as written, one per line.
+// The rules are still loaded; a bad url_pattern just never matches.
+func (s *Settings) ValidateCategories() error {
+	var errs []error
+	for _, c := range s.Categories {
+		if c.URLPattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(c.URLPattern); err != nil {
+			errs = append(errs, fmt.Errorf("category %q: invalid url_pattern: %w", c.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CategoryNames returns the names of the configured categories in rule order
+func (s *Settings) CategoryNames() []string {
+	names := make([]string, 0, len(s.Categories))
+	for _, c := range s.Categories {
+		names = append(names, c.Name)
 	}
+	return names
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -401,6 +402,28 @@ func TestCategoryOrder(t *testing.T) {
 	}
 }
 
+func TestValidateCategories(t *testing.T) {
+	s := DefaultSettings()
+	if err := s.ValidateCategories(); err != nil {
+		t.Errorf("default categories should be valid: %v", err)
+	}
+
+	s.Categories = append(s.Categories,
+		CategoryRule{Name: "Mirror", URLPattern: `^https://mirror\.example\.com/`},
+		CategoryRule{Name: "Broken", URLPattern: "("},
+	)
+	err := s.ValidateCategories()
+	if err == nil {
+		t.Fatal("expected an error for the invalid url_pattern")
+	}
+	if !strings.Contains(err.Error(), `category "Broken": invalid url_pattern`) {
+		t.Errorf("error = %v, want it to name the Broken category", err)
+	}
+	if strings.Contains(err.Error(), "Mirror") {
+		t.Errorf("valid pattern reported: %v", err)
+	}
+}
+
 func TestSettingsJSON_Serialization(t *testing.T) {
 	original := DefaultSettings()
 
@@ -557,3 +580,41 @@ func TestSaveAndLoadSettings_RoundTrip(t *testing.T) {
 	// Cleanup
 	_ = SaveSettings(DefaultSettings())
 }
+
+func TestWatchSettings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := SaveSettings(DefaultSettings()); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan *Settings, 4)
+	go WatchSettings(ctx, 10*time.Millisecond, func(s *Settings) { changes <- s })
+	time.Sleep(30 * time.Millisecond)
+
+	// A file that does not parse is ignored
+	if err := os.WriteFile(GetSettingsPath(), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case s := <-changes:
+		t.Fatalf("Unexpected reload of a corrupt file: %+v", s)
+	default:
+	}
+
+	edited := DefaultSettings()
+	edited.Connections.MaxConnectionsPerHost = 5
+	if err := SaveSettings(edited); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+	select {
+	case s := <-changes:
+		if s.Connections.MaxConnectionsPerHost != 5 {
+			t.Errorf("Reloaded MaxConnectionsPerHost = %d, want 5", s.Connections.MaxConnectionsPerHost)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Settings change was not detected")
+	}
+}
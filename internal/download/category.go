@@ -0,0 +1,126 @@
+package download
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// FindCategory returns the rule with the given name (case-insensitive), or nil
+func FindCategory(rules []types.CategoryRule, name string) *types.CategoryRule {
+	for i := range rules {
+		if strings.EqualFold(rules[i].Name, name) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// MatchCategory returns the first rule matching the download, or nil.
+// A rule matches if any of its extension, MIME type, host or URL pattern conditions match.
+func MatchCategory(rules []types.CategoryRule, rawurl, filename, contentType string) *types.CategoryRule {
+	ext := strings.ToLower(filepath.Ext(filename))
+	mime := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	host := ""
+	if u, err := url.Parse(rawurl); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	for i := range rules {
+		r := &rules[i]
+		for _, e := range r.Extensions {
+			e = strings.ToLower(strings.TrimSpace(e))
+			if e != "" && ext != "" && (e == ext || "."+e == ext) {
+				return r
+			}
+		}
+		for _, m := range r.MIMETypes {
+			m = strings.ToLower(strings.TrimSpace(m))
+			if m != "" && mime != "" && strings.HasPrefix(mime, m) {
+				return r
+			}
+		}
+		for _, h := range r.Hosts {
+			h = strings.ToLower(strings.TrimSpace(h))
+			if h != "" && host != "" && (host == h || strings.HasSuffix(host, "."+h)) {
+				return r
+			}
+		}
+		if r.URLPattern != "" {
+			if re := urlPattern(r); re != nil && re.MatchString(rawurl) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// urlPatterns caches compiled category URL patterns by source; invalid ones are stored as nil
+var urlPatterns sync.Map
+
+// urlPattern returns the rule's compiled URL pattern, or nil if it is invalid.
+// Settings report invalid patterns to the user when they are loaded (see ValidateCategories).
+func urlPattern(r *types.CategoryRule) *regexp.Regexp {
+	if re, ok := urlPatterns.Load(r.URLPattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(r.URLPattern)
+	if err != nil {
+		utils.Debug("Invalid URL pattern in category %s: %v", r.Name, err)
+	}
+	urlPatterns.Store(r.URLPattern, re)
+	return re
+}
+
+// categoryDir resolves the rule's directory against the chosen output directory.
+// Absolute and ~ paths replace the output directory, relative ones become a subfolder of it.
+func categoryDir(rule *types.CategoryRule, outputPath string) string {
+	dir := strings.TrimSpace(rule.Dir)
+	if dir == "" {
+		return outputPath
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, dir[1:])
+		}
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(outputPath, dir)
+}
+
+// applyFilenameTemplate renders a category filename template.
+// Supported placeholders: {name} {ext} {host} {date} {category}
+func applyFilenameTemplate(tmpl, filename, rawurl, category string) string {
+	if tmpl == "" {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	host := ""
+	if u, err := url.Parse(rawurl); err == nil {
+		host = u.Hostname()
+	}
+
+	out := strings.NewReplacer(
+		"{name}", strings.TrimSuffix(filename, ext),
+		"{ext}", ext,
+		"{host}", host,
+		"{date}", time.Now().Format("2006-01-02"),
+		"{category}", category,
+	).Replace(tmpl)
+
+	// Templates name a file, not a path
+	out = strings.TrimSpace(strings.NewReplacer("/", "_", "\\", "_").Replace(out))
+	if out == "" || out == "." || out == ".." {
+		return filename
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package download
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/testutil"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var testCategoryRules = []types.CategoryRule{
+	{Name: "ISOs", Extensions: []string{".iso", "img"}, Dir: "/srv/isos"},
+	{Name: "Video", MIMETypes: []string{"video/"}, Dir: "videos"},
+	{Name: "GitHub", Hosts: []string{"github.com"}, Dir: "code"},
+	{Name: "Nightly", URLPattern: `/nightly/\d+/`, Dir: "nightly"},
+}
+
+func TestMatchCategory(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		filename    string
+		contentType string
+		want        string
+	}{
+		{"extension with dot", "https://example.com/dl", "ubuntu.iso", "", "ISOs"},
+		{"extension without dot", "https://example.com/dl", "disk.IMG", "", "ISOs"},
+		{"mime prefix with params", "https://example.com/watch", "clip", "video/mp4; codecs=avc1", "Video"},
+		{"host exact", "https://github.com/org/repo.zip", "repo.zip", "application/zip", "GitHub"},
+		{"host subdomain", "https://objects.github.com/a/b", "b.tar", "", "GitHub"},
+		{"host suffix is not a subdomain", "https://notgithub.com/a", "a.bin", "", ""},
+		{"url regex", "https://example.com/nightly/20240101/build.tar", "build.tar", "", "Nightly"},
+		{"first rule wins", "https://github.com/x/movie.iso", "movie.iso", "video/mp4", "ISOs"},
+		{"no match", "https://example.com/readme", "readme.txt", "text/plain", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MatchCategory(testCategoryRules, tt.url, tt.filename, tt.contentType)
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("MatchCategory() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchCategory_InvalidPatternSkipped(t *testing.T) {
+	rules := []types.CategoryRule{{Name: "Broken", URLPattern: "("}}
+	if got := MatchCategory(rules, "https://example.com/(", "x", ""); got != nil {
+		t.Errorf("invalid pattern should not match, got %s", got.Name)
+	}
+}
+
+func TestFindCategory(t *testing.T) {
+	if got := FindCategory(testCategoryRules, "video"); got == nil || got.Name != "Video" {
+		t.Errorf("FindCategory should match case-insensitively, got %v", got)
+	}
+	if got := FindCategory(testCategoryRules, "Music"); got != nil {
+		t.Errorf("FindCategory for unknown name = %v, want nil", got)
+	}
+}
+
+func TestCategoryDir(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	base := filepath.Join("downloads", "base")
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"", base},
+		{"videos", filepath.Join(base, "videos")},
+		{"~/ISOs", filepath.Join(home, "ISOs")},
+	}
+	for _, tt := range tests {
+		got := categoryDir(&types.CategoryRule{Dir: tt.dir}, base)
+		if got != tt.want {
+			t.Errorf("categoryDir(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+
+	abs, _ := filepath.Abs("isos")
+	if got := categoryDir(&types.CategoryRule{Dir: abs}, base); got != abs {
+		t.Errorf("absolute dir = %q, want %q", got, abs)
+	}
+}
+
+func TestApplyFilenameTemplate(t *testing.T) {
+	date := time.Now().Format("2006-01-02")
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"", "movie.mp4"},
+		{"{name}{ext}", "movie.mp4"},
+		{"{category}-{name}{ext}", "Video-movie.mp4"},
+		{"{host}_{date}{ext}", "cdn.example.com_" + date + ".mp4"},
+		{"{name}/../evil{ext}", "movie_.._evil.mp4"},
+		{"  ", "movie.mp4"},
+	}
+	for _, tt := range tests {
+		got := applyFilenameTemplate(tt.tmpl, "movie.mp4", "https://cdn.example.com/v/movie.mp4", "Video")
+		if got != tt.want {
+			t.Errorf("applyFilenameTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestDownload_RoutesByCategory(t *testing.T) {
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(4096),
+		testutil.WithRangeSupport(false),
+		testutil.WithFilename("report.pdf"),
+	)
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	rc := &types.RuntimeConfig{Categories: []types.CategoryRule{
+		{Name: "Docs", Extensions: []string{".pdf"}, Dir: "docs", FilenameTemplate: "{category}-{name}{ext}"},
+	}}
+
+	events := make(chan tea.Msg, 100)
+	err := Download(context.Background(), server.URL()+"/report.pdf", tmpDir, Options{}, false, events, "category-test", rc)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	close(events)
+
+	var started *messages.DownloadStartedMsg
+	for msg := range events {
+		if m, ok := msg.(messages.DownloadStartedMsg); ok {
+			started = &m
+		}
+	}
+	if started == nil {
+		t.Fatal("no DownloadStartedMsg received")
+	}
+	if started.Category != "Docs" {
+		t.Errorf("Category = %q, want Docs", started.Category)
+	}
+	want := filepath.Join(tmpDir, "docs", "Docs-report.pdf")
+	if started.DestPath != want {
+		t.Errorf("DestPath = %q, want %q", started.DestPath, want)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("downloaded file missing: %v", err)
+	}
+	if strings.Contains(started.DestPath, types.IncompleteSuffix) {
+		t.Errorf("DestPath should not be the working file: %s", started.DestPath)
+	}
+}
+
+func TestDownload_RejectsUnknownCategory(t *testing.T) {
+	server := testutil.NewMockServer(testutil.WithFileSize(1024))
+	defer server.Close()
+
+	rc := &types.RuntimeConfig{Categories: []types.CategoryRule{{Name: "Docs", Extensions: []string{".pdf"}}}}
+	events := make(chan tea.Msg, 100)
+	err := Download(context.Background(), server.URL()+"/file.bin", t.TempDir(), Options{Category: "Videos"}, false, events, "unknown-category-test", rc)
+	if err == nil || !strings.Contains(err.Error(), `unknown category "Videos"`) {
+		t.Errorf("err = %v, want unknown category error", err)
+	}
+	if kind := types.Classify(err); kind != types.KindConfig {
+		t.Errorf("unknown category error kind = %s, want %s", kind, types.KindConfig)
+	}
+}
@@ -0,0 +1,86 @@
+package download
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+// checksumAlgorithms maps the accepted algorithm names to their hashes.
+// The dashed spellings are the ones used by aria2.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":     md5.New,
+	"sha1":    sha1.New,
+	"sha-1":   sha1.New,
+	"sha256":  sha256.New,
+	"sha-256": sha256.New,
+	"sha512":  sha512.New,
+	"sha-512": sha512.New,
+}
+
+// Checksum is an expected digest of a downloaded file
+type Checksum struct {
+	Algorithm string
+	Sum       []byte
+}
+
+// ParseChecksum parses a checksum given as "algo=hex" or "algo:hex", e.g. "sha256=9f86d0…".
+// The algorithm is one of md5, sha1, sha256 or sha512.
+func ParseChecksum(s string) (Checksum, error) {
+	s = strings.TrimSpace(s)
+	algo, sum, ok := strings.Cut(s, "=")
+	if !ok {
+		algo, sum, ok = strings.Cut(s, ":")
+	}
+	if !ok {
+		return Checksum{}, fmt.Errorf("invalid checksum %q (want algo=hex)", s)
+	}
+	algo = strings.ToLower(strings.TrimSpace(algo))
+	newHash, known := checksumAlgorithms[algo]
+	if !known {
+		return Checksum{}, fmt.Errorf("unsupported checksum algorithm %q (want md5, sha1, sha256 or sha512)", algo)
+	}
+	digest, err := hex.DecodeString(strings.TrimSpace(sum))
+	if err != nil || len(digest) != newHash().Size() {
+		return Checksum{}, fmt.Errorf("invalid %s checksum %q", algo, sum)
+	}
+	return Checksum{Algorithm: algo, Sum: digest}, nil
+}
+
+// Verify hashes the file at path and compares it with the checksum
+func (c Checksum) Verify(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file for checksum: %w", err)
+	}
+	defer f.Close()
+
+	h := c.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to read file for checksum: %w", err)
+	}
+	return c.match(h.Sum(nil))
+}
+
+// newHash returns an empty hash of the checksum's algorithm
+func (c Checksum) newHash() hash.Hash {
+	return checksumAlgorithms[c.Algorithm]()
+}
+
+// match compares a computed digest with the checksum
+func (c Checksum) match(got []byte) error {
+	if !bytes.Equal(got, c.Sum) {
+		return types.NewError(types.KindIntegrity, fmt.Errorf("%s checksum mismatch: expected %x, got %x", c.Algorithm, c.Sum, got))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package download
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+func TestParseChecksum(t *testing.T) {
+	sha := fmt.Sprintf("%x", sha256.Sum256([]byte("pulse")))
+	tests := []struct {
+		in      string
+		algo    string
+		wantErr bool
+	}{
+		{"sha256=" + sha, "sha256", false},
+		{"SHA-256:" + sha, "sha-256", false},
+		{"md5=d41d8cd98f00b204e9800998ecf8427e", "md5", false},
+		{"sha256=" + sha[:10], "", true},
+		{"sha256=zz" + sha[2:], "", true},
+		{"crc32=cbf43926", "", true},
+		{sha, "", true},
+	}
+	for _, tt := range tests {
+		c, err := ParseChecksum(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseChecksum(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && c.Algorithm != tt.algo {
+			t.Errorf("ParseChecksum(%q) algorithm = %q, want %q", tt.in, c.Algorithm, tt.algo)
+		}
+	}
+}
+
+func TestChecksum_Verify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("pulse"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseChecksum(fmt.Sprintf("sha256=%x", sha256.Sum256([]byte("pulse"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Verify(path); err != nil {
+		t.Errorf("Verify of matching file failed: %v", err)
+	}
+
+	c, _ = ParseChecksum(fmt.Sprintf("sha256=%x", sha256.Sum256([]byte("other"))))
+	if err := c.Verify(path); types.Classify(err) != types.KindIntegrity {
+		t.Errorf("Verify of changed file = %v, want an %s error", err, types.KindIntegrity)
+	}
+}
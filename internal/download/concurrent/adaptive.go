@@ -0,0 +1,142 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// globalWorkers counts the workers of every concurrent download in this process,
+// so the adaptive controller can keep the total under MaxGlobalConnections
+var globalWorkers atomic.Int64
+
+// scaleSample is what the adaptive controller sees at each interval
+type scaleSample struct {
+	workers   int     // Workers currently running
+	limit     int     // Most workers allowed right now by the per-host and global limits
+	speed     float64 // Aggregate throughput over the last interval, bytes/s
+	throttled int64   // 429/503 responses and connection resets during the interval
+	remaining int64   // Bytes still to download
+	minChunk  int64   // Smallest useful amount of work per worker
+}
+
+// scaler decides how many workers a download should run. It adds workers while each
+// step keeps raising aggregate throughput, undoes a step that did not help, and backs
+// off when the server starts throttling.
+type scaler struct {
+	lastSpeed float64 // Throughput measured at the previous interval
+	lastAdded int     // Workers added at the previous interval; 0 if it was not an increase
+	hold      int     // Intervals left before probing upwards again
+}
+
+// decide returns the new worker count and the reason for the change.
+// The reason is empty when the count stays the same.
+func (s *scaler) decide(in scaleSample) (int, string) {
+	prevSpeed, added := s.lastSpeed, s.lastAdded
+	s.lastSpeed, s.lastAdded = in.speed, 0
+
+	switch {
+	case in.throttled > 0:
+		s.hold = types.ScaleHoldIntervals
+		if in.workers <= 1 {
+			return in.workers, ""
+		}
+		return in.workers - max(1, in.workers/4), fmt.Sprintf("server throttling (%d errors)", in.throttled)
+	case in.workers > in.limit:
+		return max(1, in.limit), "over connection limit"
+	case added > 0 && in.speed < prevSpeed*(1+types.ScaleMinGain):
+		s.hold = types.ScaleHoldIntervals
+		return max(1, in.workers-added), fmt.Sprintf("no gain from %d more", added)
+	case s.hold > 0:
+		s.hold--
+		return in.workers, ""
+	}
+
+	// Probe upwards while there is headroom and enough work left to share
+	step := min(max(1, in.workers/4), in.limit-in.workers)
+	if step <= 0 || in.speed <= 0 || in.remaining/int64(in.workers+step) < in.minChunk {
+		return in.workers, ""
+	}
+	s.lastAdded = step
+	if added > 0 && prevSpeed > 0 {
+		return in.workers + step, fmt.Sprintf("throughput up %.0f%%", (in.speed/prevSpeed-1)*100)
+	}
+	return in.workers + step, "probing for more throughput"
+}
+
+// connectionLimit returns the most workers this download may run, given that it
+// currently runs own of the workers counted globally and what the host tolerated before
+func (d *ConcurrentDownloader) connectionLimit(own int) int {
+	rt := d.runtime()
+	perHost := rt.GetMaxConnectionsPerHost()
+	if learned := hostLimits.limit(d.host); learned > 0 {
+		perHost = min(perHost, learned)
+	}
+	globalRoom := rt.GetMaxGlobalConnections() - int(globalWorkers.Load()) + own
+	return max(1, min(perHost, globalRoom))
+}
+
+// scaleTo resizes the worker set and records the decision in the scaling trace
+func (d *ConcurrentDownloader) scaleTo(workers *workerSet, from, to int, speed float64, reason string) {
+	utils.Debug("Scaling %s from %d to %d workers: %s", d.ID, from, to, reason)
+	if d.State != nil {
+		d.State.AddScalingDecision(types.ScalingDecision{
+			Time:   time.Now(),
+			From:   from,
+			To:     to,
+			Speed:  speed,
+			Reason: reason,
+		})
+	}
+	workers.resize(to)
+}
+
+// adaptConnections runs the adaptive controller until ctx is done
+func (d *ConcurrentDownloader) adaptConnections(ctx context.Context, workers *workerSet, fileSize int64) {
+	ticker := time.NewTicker(types.ScaleInterval)
+	defer ticker.Stop()
+
+	var s scaler
+	lastBytes := d.State.Downloaded.Load()
+	lastTick := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			bytes := d.State.Downloaded.Load()
+			n := workers.count()
+			sample := scaleSample{
+				workers:   n,
+				limit:     d.connectionLimit(n),
+				speed:     float64(bytes-lastBytes) / now.Sub(lastTick).Seconds(),
+				throttled: d.throttled.Swap(0),
+				remaining: fileSize - bytes,
+				minChunk:  d.runtime().GetMinChunkSize(),
+			}
+			lastBytes, lastTick = bytes, now
+
+			if target, reason := s.decide(sample); target != n {
+				d.scaleTo(workers, n, target, sample.speed, reason)
+			}
+		}
+	}
+}
+
+// isThrottleStatus reports whether an HTTP status means the server wants fewer requests
+func isThrottleStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// isConnReset reports whether err is the server dropping the connection
+func isConnReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET)
+}
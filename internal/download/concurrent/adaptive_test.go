@@ -0,0 +1,79 @@
+package concurrent
+
+import (
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+func TestScaler_Decide(t *testing.T) {
+	const mb = float64(types.MB)
+	base := scaleSample{limit: 16, remaining: 1 * types.GB, minChunk: types.MinChunk}
+
+	sample := func(workers int, speed float64) scaleSample {
+		s := base
+		s.workers, s.speed = workers, speed
+		return s
+	}
+
+	var s scaler
+
+	// First interval probes upwards
+	if n, reason := s.decide(sample(4, 10*mb)); n != 5 || reason == "" {
+		t.Fatalf("Expected probe to 5 workers, got %d (%q)", n, reason)
+	}
+
+	// Throughput improved, keep adding
+	if n, _ := s.decide(sample(5, 12*mb)); n != 6 {
+		t.Fatalf("Expected growth to 6 workers after improvement, got %d", n)
+	}
+
+	// Throughput flat, undo the last step and hold
+	if n, _ := s.decide(sample(6, 12.1*mb)); n != 5 {
+		t.Fatalf("Expected step back to 5 workers on plateau, got %d", n)
+	}
+	for i := 0; i < types.ScaleHoldIntervals; i++ {
+		if n, reason := s.decide(sample(5, 12*mb)); n != 5 || reason != "" {
+			t.Fatalf("Expected hold at 5 workers during cooldown, got %d (%q)", n, reason)
+		}
+	}
+	if n, _ := s.decide(sample(5, 12*mb)); n != 6 {
+		t.Fatalf("Expected probing to resume after cooldown, got %d", n)
+	}
+
+	// Throttling removes a quarter of the workers and holds
+	throttled := sample(8, 12*mb)
+	throttled.throttled = 3
+	if n, _ := s.decide(throttled); n != 6 {
+		t.Fatalf("Expected 8 -> 6 workers on throttling, got %d", n)
+	}
+	if n, _ := s.decide(sample(6, 12*mb)); n != 6 {
+		t.Fatalf("Expected hold after throttling, got %d", n)
+	}
+}
+
+func TestScaler_DecideLimits(t *testing.T) {
+	var s scaler
+
+	// Never above the limit
+	if n, _ := s.decide(scaleSample{workers: 4, limit: 4, speed: 1, remaining: types.GB, minChunk: types.MinChunk}); n != 4 {
+		t.Errorf("Expected no growth at the limit, got %d", n)
+	}
+
+	// A lowered limit shrinks straight away
+	if n, _ := s.decide(scaleSample{workers: 6, limit: 2, speed: 1, remaining: types.GB, minChunk: types.MinChunk}); n != 2 {
+		t.Errorf("Expected shrink to the limit, got %d", n)
+	}
+
+	// Not enough work left to share
+	s = scaler{}
+	if n, _ := s.decide(scaleSample{workers: 2, limit: 8, speed: 1, remaining: 4 * types.MB, minChunk: types.MinChunk}); n != 2 {
+		t.Errorf("Expected no growth near the end of the file, got %d", n)
+	}
+
+	// A single worker is never throttled down to zero
+	s = scaler{}
+	if n, _ := s.decide(scaleSample{workers: 1, limit: 8, speed: 1, throttled: 5, remaining: types.GB, minChunk: types.MinChunk}); n != 1 {
+		t.Errorf("Expected to keep one worker, got %d", n)
+	}
+}
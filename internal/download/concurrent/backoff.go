@@ -0,0 +1,162 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/messages"
+)
+
+// rateLimitError is returned by downloadTask when the server answers 429 or 503
+type rateLimitError struct {
+	Status     int
+	RetryAfter time.Duration // Delay asked for in Retry-After; 0 if the header was missing or invalid
+}
+
+func (e *rateLimitError) Error() string {
+	if e.Status == http.StatusTooManyRequests {
+		return "rate limited (429)"
+	}
+	return fmt.Sprintf("server unavailable (%d)", e.Status)
+}
+
+// parseRetryAfter reads a Retry-After header given either as seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// hostState is what the session has learned about one host
+type hostState struct {
+	until   time.Time // No requests to the host before this time
+	limit   int       // Connections the host tolerates; 0 until it first throttles
+	strikes int       // Throttling windows in a row, for the exponential delay when there is no Retry-After
+}
+
+// hostLimiter coordinates back-off across every download in the process, so all
+// workers for a throttling host pause together and later downloads start gently
+type hostLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+var hostLimits = &hostLimiter{hosts: make(map[string]*hostState)}
+
+// throttle records a 429/503 from host while conns connections were open and returns
+// the delay applied. Only the first response of a back-off window lowers the
+// connection limit, so a burst of rejections from parallel workers counts once.
+func (h *hostLimiter) throttle(host string, retryAfter time.Duration, conns int, now time.Time) (delay time.Duration, newWindow bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hs := h.hosts[host]
+	if hs == nil {
+		hs = &hostState{}
+		h.hosts[host] = hs
+	}
+
+	newWindow = !now.Before(hs.until)
+	if newWindow {
+		hs.strikes++
+		limit := max(1, conns-max(1, conns/4))
+		if hs.limit == 0 || limit < hs.limit {
+			hs.limit = limit
+		}
+	}
+
+	delay = retryAfter
+	if delay <= 0 {
+		delay = types.RateLimitBaseDelay << min(hs.strikes-1, 8)
+	}
+	delay = min(delay, types.RateLimitMaxDelay)
+
+	if until := now.Add(delay); until.After(hs.until) {
+		hs.until = until
+	}
+	return delay, newWindow
+}
+
+// success clears the strike count of a host; its connection limit is kept for the session
+func (h *hostLimiter) success(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hs := h.hosts[host]; hs != nil {
+		hs.strikes = 0
+	}
+}
+
+// limit returns the remembered connection limit for host, or 0 if it never throttled
+func (h *hostLimiter) limit(host string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hs := h.hosts[host]; hs != nil {
+		return hs.limit
+	}
+	return 0
+}
+
+// wait blocks until host's back-off window has passed or ctx is done
+func (h *hostLimiter) wait(ctx context.Context, host string) error {
+	for {
+		h.mu.Lock()
+		var remaining time.Duration
+		if hs := h.hosts[host]; hs != nil {
+			remaining = time.Until(hs.until)
+		}
+		h.mu.Unlock()
+
+		if remaining <= 0 {
+			return nil
+		}
+
+		// The window may be extended while we sleep, so check again afterwards
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// recordRateLimit starts a back-off window for the download's host when err is a rate limit,
+// and clears the host's strikes after a successful request
+func (d *ConcurrentDownloader) recordRateLimit(err error, workers *workerSet) {
+	var rl *rateLimitError
+	if !errors.As(err, &rl) {
+		if err == nil {
+			hostLimits.success(d.host)
+		}
+		return
+	}
+
+	conns := 1
+	if workers != nil {
+		conns = workers.count()
+	}
+	delay, newWindow := hostLimits.throttle(d.host, rl.RetryAfter, conns, time.Now())
+	if newWindow {
+		d.reportHealth(messages.HealthThrottled, fmt.Sprintf("%s: %v, pausing %s and limiting to %d connections",
+			d.host, rl, delay.Round(time.Second), hostLimits.limit(d.host)))
+	}
+}
@@ -0,0 +1,149 @@
+package concurrent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{" 30 ", 30 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHostLimiter_Throttle(t *testing.T) {
+	h := &hostLimiter{hosts: make(map[string]*hostState)}
+	now := time.Now()
+
+	// Retry-After is honoured and the connection limit drops by a quarter
+	delay, newWindow := h.throttle("a.example", 10*time.Second, 8, now)
+	if delay != 10*time.Second || !newWindow {
+		t.Fatalf("throttle = %v, %v; want 10s, new window", delay, newWindow)
+	}
+	if l := h.limit("a.example"); l != 6 {
+		t.Errorf("limit = %d, want 6", l)
+	}
+
+	// Parallel rejections inside the same window do not lower the limit again
+	if _, newWindow := h.throttle("a.example", 0, 8, now.Add(time.Second)); newWindow {
+		t.Error("Second rejection in the window started a new window")
+	}
+	if l := h.limit("a.example"); l != 6 {
+		t.Errorf("limit after burst = %d, want 6", l)
+	}
+
+	// Without Retry-After the delay doubles per window, up to the maximum
+	d1, _ := h.throttle("b.example", 0, 1, now)
+	d2, _ := h.throttle("b.example", 0, 1, now.Add(d1))
+	if d1 != types.RateLimitBaseDelay || d2 != 2*types.RateLimitBaseDelay {
+		t.Errorf("Delays = %v, %v; want %v, %v", d1, d2, types.RateLimitBaseDelay, 2*types.RateLimitBaseDelay)
+	}
+	if l := h.limit("b.example"); l != 1 {
+		t.Errorf("limit = %d, want at least one connection", l)
+	}
+	if d, _ := h.throttle("c.example", time.Hour, 1, now); d != types.RateLimitMaxDelay {
+		t.Errorf("Delay = %v, want capped at %v", d, types.RateLimitMaxDelay)
+	}
+
+	// A success resets the delay but the learned limit stays for the session
+	h.success("b.example")
+	if d, _ := h.throttle("b.example", 0, 1, now.Add(time.Minute)); d != types.RateLimitBaseDelay {
+		t.Errorf("Delay after success = %v, want %v", d, types.RateLimitBaseDelay)
+	}
+	if l := h.limit("a.example"); l != 6 {
+		t.Errorf("limit after success = %d, want 6", l)
+	}
+}
+
+func TestHostLimiter_Wait(t *testing.T) {
+	h := &hostLimiter{hosts: make(map[string]*hostState)}
+
+	if err := h.wait(context.Background(), "unknown.example"); err != nil {
+		t.Fatalf("wait on unknown host: %v", err)
+	}
+
+	h.throttle("slow.example", 100*time.Millisecond, 2, time.Now())
+	start := time.Now()
+	if err := h.wait(context.Background(), "slow.example"); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("wait returned after %v, want about 100ms", elapsed)
+	}
+
+	h.throttle("slow.example", time.Minute, 2, time.Now())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.wait(ctx, "slow.example"); err == nil {
+		t.Error("wait ignored context cancellation")
+	}
+}
+
+func TestConcurrentDownloader_RateLimitedSucceeds(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create config dirs: %v", err)
+	}
+
+	// The server turns down the first requests, more of them than a task retries
+	const rejections = 3
+	content := bytes.Repeat([]byte("pulse"), 200*1024)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= rejections {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		http.ServeContent(w, r, "limited.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	fileSize := int64(len(content))
+	destPath := filepath.Join(t.TempDir(), "limited.bin")
+	state := types.NewProgressState("rate-limit-test", fileSize)
+	runtime := &types.RuntimeConfig{MaxConnectionsPerHost: 1, MaxTaskRetries: 1}
+	downloader := NewConcurrentDownloader("rate-limit-id", nil, state, runtime)
+	// Rate limits must not be charged, so one more failure would have ended the download
+	downloader.failures.Store(types.DownloadFailureBudget - 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := downloader.Download(ctx, server.URL+"/limited.bin", destPath, fileSize, false); err != nil {
+		t.Fatalf("Rate-limited download failed: %v", err)
+	}
+	if got, _ := os.ReadFile(destPath); !bytes.Equal(got, content) {
+		t.Error("Downloaded file differs from the served content")
+	}
+	if n := requests.Load(); n <= rejections {
+		t.Errorf("Server saw %d requests, want more than the %d rejected ones", n, rejections)
+	}
+}
@@ -0,0 +1,151 @@
+package concurrent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/state"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// span is the byte range [start, end)
+type span struct {
+	start, end int64
+}
+
+// rangeSet tracks which bytes of the file have been written, as sorted, non-touching spans
+type rangeSet struct {
+	mu    sync.Mutex
+	spans []span
+}
+
+// add marks [start, end) as written, merging it with the spans it overlaps or touches
+func (s *rangeSet) add(start, end int64) {
+	if end <= start {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Spans before i end before start and are left alone
+	i := sort.Search(len(s.spans), func(i int) bool { return s.spans[i].end >= start })
+	j := i
+	for j < len(s.spans) && s.spans[j].start <= end {
+		start = min(start, s.spans[j].start)
+		end = max(end, s.spans[j].end)
+		j++
+	}
+	s.spans = slices.Replace(s.spans, i, j, span{start, end})
+}
+
+// addComplement marks everything in [0, size) outside tasks as written, for resumed downloads
+func (s *rangeSet) addComplement(tasks []types.Task, size int64) {
+	sorted := slices.Clone(tasks)
+	slices.SortFunc(sorted, func(a, b types.Task) int { return cmp.Compare(a.Offset, b.Offset) })
+	var pos int64
+	for _, t := range sorted {
+		s.add(pos, t.Offset)
+		pos = max(pos, t.Offset+t.Length)
+	}
+	s.add(pos, size)
+}
+
+// size returns the number of bytes written
+func (s *rangeSet) size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, sp := range s.spans {
+		n += sp.end - sp.start
+	}
+	return n
+}
+
+// gaps returns the bytes of [0, size) not written yet as tasks of at most maxLen bytes
+func (s *rangeSet) gaps(size, maxLen int64) []types.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tasks []types.Task
+	addGap := func(start, end int64) {
+		for start < end {
+			n := end - start
+			if maxLen > 0 {
+				n = min(n, maxLen)
+			}
+			tasks = append(tasks, types.Task{Offset: start, Length: n})
+			start += n
+		}
+	}
+	var pos int64
+	for _, sp := range s.spans {
+		addGap(pos, min(sp.start, size))
+		pos = max(pos, sp.end)
+	}
+	addGap(pos, size)
+	return tasks
+}
+
+// checkpoint records the work left so a crash loses at most what was written since.
+// The written ranges are read before the file is synced, so the state never claims bytes
+// that are not yet on disk.
+func (d *ConcurrentDownloader) checkpoint(file *os.File, destPath string, fileSize, chunkSize int64) error {
+	remaining := d.written.gaps(fileSize, chunkSize)
+	downloaded := fileSize
+	for _, t := range remaining {
+		downloaded -= t.Length
+	}
+
+	if err := file.Sync(); err != nil {
+		return types.NewError(types.KindDisk, fmt.Errorf("failed to sync file: %w", err))
+	}
+
+	s := &types.DownloadState{
+		URL:        d.URL,
+		ID:         d.ID,
+		DestPath:   destPath,
+		TotalSize:  fileSize,
+		Downloaded: downloaded,
+		Tasks:      remaining,
+		Filename:   filepath.Base(destPath),
+		ETag:       d.ETag,
+		Headers:    d.Headers,
+		Checksum:   d.Checksum,
+		Mirrors:    d.Mirrors,
+	}
+	return state.SaveCheckpoint(d.URL, destPath, s)
+}
+
+// checkpointLoop saves a checkpoint every CheckpointInterval, or sooner once CheckpointBytes
+// more have been written, until ctx is done
+func (d *ConcurrentDownloader) checkpointLoop(ctx context.Context, file *os.File, destPath string, fileSize, chunkSize int64) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	last := time.Now()
+	lastBytes := d.written.size()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			written := d.written.size()
+			if written == lastBytes || (time.Since(last) < types.CheckpointInterval && written-lastBytes < types.CheckpointBytes) {
+				continue
+			}
+			if err := d.checkpoint(file, destPath, fileSize, chunkSize); err != nil {
+				utils.Debug("Checkpoint failed: %v", err)
+				continue
+			}
+			last, lastBytes = time.Now(), written
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package concurrent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download/state"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+func TestRangeSet(t *testing.T) {
+	var s rangeSet
+	s.add(100, 200)
+	s.add(300, 400)
+	s.add(0, 50)
+	s.add(200, 250) // Touches [100, 200)
+	s.add(350, 500) // Overlaps [300, 400)
+	s.add(10, 20)   // Already covered
+
+	want := []span{{0, 50}, {100, 250}, {300, 500}}
+	if !reflect.DeepEqual(s.spans, want) {
+		t.Errorf("spans = %v, want %v", s.spans, want)
+	}
+	if got := s.size(); got != 400 {
+		t.Errorf("size = %d, want 400", got)
+	}
+
+	gaps := s.gaps(1000, 200)
+	wantGaps := []types.Task{{Offset: 50, Length: 50}, {Offset: 250, Length: 50}, {Offset: 500, Length: 200}, {Offset: 700, Length: 200}, {Offset: 900, Length: 100}}
+	if !reflect.DeepEqual(gaps, wantGaps) {
+		t.Errorf("gaps = %v, want %v", gaps, wantGaps)
+	}
+
+	s.add(0, 1000)
+	if gaps := s.gaps(1000, 200); len(gaps) != 0 {
+		t.Errorf("complete file has gaps: %v", gaps)
+	}
+}
+
+func TestRangeSet_AddComplement(t *testing.T) {
+	var s rangeSet
+	tasks := []types.Task{{Offset: 600, Length: 100}, {Offset: 100, Length: 200}}
+	s.addComplement(tasks, 1000)
+
+	if got := s.gaps(1000, 0); !reflect.DeepEqual(got, []types.Task{{Offset: 100, Length: 200}, {Offset: 600, Length: 100}}) {
+		t.Errorf("gaps after resume = %v, want the saved tasks", got)
+	}
+}
+
+func TestConcurrentDownloader_Checkpoint(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	file, err := os.Create(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	d := NewConcurrentDownloader("checkpoint-id", nil, nil, nil)
+	d.URL = "https://example.com/file.bin"
+	d.ETag = `"v1"`
+	d.written.add(0, 400)
+	d.written.add(600, 700)
+
+	if err := d.checkpoint(file, destPath, 1000, 0); err != nil {
+		t.Fatalf("checkpoint failed: %v", err)
+	}
+
+	saved, err := state.LoadState(d.URL, destPath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	want := []types.Task{{Offset: 400, Length: 200}, {Offset: 700, Length: 300}}
+	if !reflect.DeepEqual(saved.Tasks, want) || saved.Downloaded != 500 || saved.ID != "checkpoint-id" || saved.ETag != `"v1"` {
+		t.Errorf("unexpected checkpoint: %+v", saved)
+	}
+	// Running downloads are not listed as paused
+	if entry, _ := state.GetDownloadEntry("checkpoint-id"); entry != nil {
+		t.Errorf("checkpoint added a master list entry: %+v", entry)
+	}
+}
+
+func TestConcurrentDownloader_SaveProgressSyncFailure(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	file, err := os.Create(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewConcurrentDownloader("sync-id", nil, nil, nil)
+	d.URL = "https://example.com/file.bin"
+	d.written.add(0, 400)
+	if err := d.checkpoint(file, destPath, 1000, 0); err != nil {
+		t.Fatalf("checkpoint failed: %v", err)
+	}
+
+	// Nothing written since the checkpoint is known to be on disk once syncing fails
+	file.Close()
+	queue := NewTaskQueue()
+	queue.Push(types.Task{Offset: 800, Length: 200})
+	d.saveProgress(queue, file, destPath, 1000)
+
+	saved, err := state.LoadState(d.URL, destPath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if saved.Downloaded != 400 || !reflect.DeepEqual(saved.Tasks, []types.Task{{Offset: 400, Length: 600}}) {
+		t.Errorf("Previous state replaced after a failed sync: %+v", saved)
+	}
+	if entry, _ := state.GetDownloadEntry("sync-id"); entry != nil {
+		t.Errorf("Unsynced progress listed as paused: %+v", entry)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -32,9 +33,21 @@ type ConcurrentDownloader struct {
 	State        *types.ProgressState // Shared state for TUI polling
 	activeTasks  map[int]*ActiveTask
 	activeMu     sync.Mutex
-	URL          string // For pause/resume
-	DestPath     string // For pause/resume
+	URL          string            // For pause/resume
+	DestPath     string            // For pause/resume
+	ETag         string            // Server ETag from probe, saved with state for URL replacement checks
+	Headers      map[string]string // Extra request headers, saved with state for resuming
+	Checksum     string            // Expected digest, saved with state for resuming
+	Mirrors      []string          // Other URLs of the file, saved with state for resuming
 	Runtime      *types.RuntimeConfig
+	Live         *types.LiveRuntime // When set, settings are read from here and followed while downloading
+	throttled    atomic.Int64       // Throttling responses and resets since the adaptive controller last looked
+	host         string             // Host of the download URL, for per-host error counts
+	failures     atomic.Int64       // Transient request failures, charged against DownloadFailureBudget
+	fatalMu      sync.Mutex
+	fatalErr     error              // Error that stopped the download, set by fail
+	abort        context.CancelFunc // Stops all workers after a fatal error
+	written      rangeSet           // Bytes written to the working file, for checkpoints
 }
 
 // NewConcurrentDownloader creates a new concurrent downloader with all required parameters
@@ -48,9 +61,17 @@ func NewConcurrentDownloader(id string, progressCh chan<- tea.Msg, progState *ty
 	}
 }
 
+// runtime returns the settings currently in effect
+func (d *ConcurrentDownloader) runtime() *types.RuntimeConfig {
+	if d.Live != nil {
+		return d.Live.Get()
+	}
+	return d.Runtime
+}
+
 // getInitialConnections returns the starting number of connections based on file size
 func (d *ConcurrentDownloader) getInitialConnections(fileSize int64) int {
-	maxConns := d.Runtime.GetMaxConnectionsPerHost()
+	maxConns := d.runtime().GetMaxConnectionsPerHost()
 
 	var recConns int
 	switch {
@@ -76,9 +97,9 @@ func (d *ConcurrentDownloader) calculateChunkSize(fileSize int64, numConns int)
 	chunkSize := fileSize / targetChunks
 
 	// Clamp to min/max from config
-	minChunk := d.Runtime.GetMinChunkSize()
-	maxChunk := d.Runtime.GetMaxChunkSize()
-	targetChunk := d.Runtime.GetTargetChunkSize()
+	minChunk := d.runtime().GetMinChunkSize()
+	maxChunk := d.runtime().GetMaxChunkSize()
+	targetChunk := d.runtime().GetTargetChunkSize()
 
 	// If calculating produces something wild, prefer target
 	if chunkSize == 0 {
@@ -120,7 +141,11 @@ func createTasks(fileSize, chunkSize int64) []types.Task {
 // newConcurrentClient creates an http.Client tuned for concurrent downloads
 func (d *ConcurrentDownloader) newConcurrentClient(numConns int) *http.Client {
 	// Ensure we have enough connections per host
-	maxConns := d.Runtime.GetMaxConnectionsPerHost()
+	maxConns := d.runtime().GetMaxConnectionsPerHost()
+	if d.Live != nil {
+		// Live settings may add workers later; the worker count, not the transport, bounds connections
+		maxConns = types.PerHostMax
+	}
 	if numConns > maxConns {
 		maxConns = numConns
 	}
@@ -162,6 +187,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 	// Store URL and path for pause/resume (final path without .pulse)
 	d.URL = rawurl
 	d.DestPath = destPath
+	d.host = hostOf(rawurl)
 
 	// Working file has .pulse suffix until download completes
 	workingPath := destPath + types.IncompleteSuffix
@@ -169,12 +195,15 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 	// Create cancellable context for pause support
 	downloadCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	d.fatalMu.Lock()
+	d.abort = cancel
+	d.fatalMu.Unlock()
 	if d.State != nil {
 		d.State.CancelFunc = cancel
 	}
 
-	// Determine connections and chunk size
-	numConns := d.getInitialConnections(fileSize)
+	// Determine connections and chunk size; the adaptive controller adjusts the count later
+	numConns := min(d.getInitialConnections(fileSize), d.connectionLimit(0))
 	chunkSize := d.calculateChunkSize(fileSize, numConns)
 
 	// Create tuned HTTP client for concurrent downloads
@@ -190,7 +219,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 	// Create and preallocate output file with .pulse suffix
 	outFile, err := os.OpenFile(workingPath, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return types.NewError(types.KindDisk, fmt.Errorf("failed to create file: %w", err))
 	}
 	defer outFile.Close()
 
@@ -202,6 +231,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 	if isResume {
 		// Resume: use saved tasks and restore downloaded counter
 		tasks = savedState.Tasks
+		d.written.addComplement(tasks, fileSize)
 		if d.State != nil {
 			d.State.Downloaded.Store(savedState.Downloaded)
 		}
@@ -209,12 +239,13 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 	} else {
 		// Fresh download: preallocate file and create new tasks
 		if err := outFile.Truncate(fileSize); err != nil {
-			return fmt.Errorf("failed to preallocate file: %w", err)
+			return types.NewError(types.KindDisk, fmt.Errorf("failed to preallocate file: %w", err))
 		}
 		tasks = createTasks(fileSize, chunkSize)
 	}
 	queue := NewTaskQueue()
 	queue.PushMultiple(tasks)
+	workers := &workerSet{}
 
 	// Start time for stats
 	startTime := time.Now()
@@ -262,7 +293,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 			case <-balancerCtx.Done():
 				return
 			case <-ticker.C:
-				if queue.Len() == 0 && int(queue.IdleWorkers()) == numConns {
+				if queue.Len() == 0 && int(queue.IdleWorkers()) == workers.count() {
 					queue.Close()
 					return
 				}
@@ -285,26 +316,55 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 		}
 	}()
 
-	// Start workers
-	var wg sync.WaitGroup
+	// Start workers. The error channel and queue are closed once the last one exits.
 	workerErrors := make(chan error, numConns)
-
-	for i := 0; i < numConns; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			err := d.worker(downloadCtx, workerID, rawurl, outFile, queue, fileSize, startTime, verbose, client)
-			if err != nil && err != context.Canceled {
+	workers.start = func(workerID int) {
+		globalWorkers.Add(1)
+		go func() {
+			defer globalWorkers.Add(-1)
+			err := d.worker(downloadCtx, workerID, rawurl, outFile, queue, fileSize, startTime, verbose, client, workers)
+			if err != nil && err != context.Canceled && err != errRetired {
 				workerErrors <- err
 			}
-		}(i)
+			workers.exit(err == errRetired)
+		}()
 	}
-
-	// Wait for all workers to complete
-	go func() {
-		wg.Wait()
+	workers.onEmpty = func() {
 		close(workerErrors)
 		queue.Close()
+	}
+	workers.resize(numConns)
+
+	// Adaptive scaling: add workers while throughput improves, drop them when it stops helping
+	if d.State != nil {
+		go d.adaptConnections(balancerCtx, workers, fileSize)
+	}
+
+	// Follow live setting changes: more connections start workers, fewer retire them between tasks
+	if d.Live != nil {
+		changes, unsubscribe := d.Live.Subscribe()
+		defer unsubscribe()
+		go func() {
+			for {
+				select {
+				case <-balancerCtx.Done():
+					return
+				case <-changes:
+					n := workers.count()
+					if target := min(max(n, d.getInitialConnections(fileSize)), d.connectionLimit(n)); target != n {
+						d.scaleTo(workers, n, target, 0, "settings changed")
+					}
+				}
+			}
+		}()
+	}
+
+	// Checkpoint periodically so a crash or power loss does not restart the download
+	checkpointCtx, stopCheckpoints := context.WithCancel(downloadCtx)
+	checkpointsDone := make(chan struct{})
+	go func() {
+		defer close(checkpointsDone)
+		d.checkpointLoop(checkpointCtx, outFile, destPath, fileSize, chunkSize)
 	}()
 
 	// Check for errors or pause
@@ -314,54 +374,22 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 			downloadErr = err
 		}
 	}
+	stopCheckpoints()
+	<-checkpointsDone
 
 	// Handle pause: save state and exit gracefully
 	if d.State != nil && d.State.IsPaused() {
-		// Collect remaining tasks
-		remainingTasks := queue.DrainRemaining()
-
-		// Also collect active tasks as remaining work
-		d.activeMu.Lock()
-		for _, active := range d.activeTasks {
-			if remaining := active.RemainingTask(); remaining != nil {
-				remainingTasks = append(remainingTasks, *remaining)
-			}
-		}
-		d.activeMu.Unlock()
-
-		// Calculate Downloaded from remaining tasks (ensures consistency)
-		var remainingBytes int64
-		for _, task := range remainingTasks {
-			remainingBytes += task.Length
-		}
-		computedDownloaded := fileSize - remainingBytes
-
-		// Debug: compare atomic counter vs computed value to verify fix
-		atomicDownloaded := d.State.Downloaded.Load()
-		if atomicDownloaded != computedDownloaded {
-			utils.Debug("PAUSE FIX: Atomic counter=%d, Computed from tasks=%d, Diff=%d bytes",
-				atomicDownloaded, computedDownloaded, atomicDownloaded-computedDownloaded)
-		}
-
-		// Save state for resume (use computed value for consistency)
-		s := &types.DownloadState{
-			URL:        d.URL,
-			ID:         d.ID,
-			DestPath:   destPath,
-			TotalSize:  fileSize,
-			Downloaded: computedDownloaded, // FIX: Use computed value instead of atomic counter
-			Tasks:      remainingTasks,
-			Filename:   filepath.Base(destPath),
-		}
-		if err := state.SaveState(d.URL, destPath, s); err != nil {
-			utils.Debug("Failed to save pause state: %v", err)
-		}
-
-		utils.Debug("Download paused, state saved (Downloaded=%d, RemainingTasks=%d, RemainingBytes=%d)",
-			computedDownloaded, len(remainingTasks), remainingBytes)
+		d.saveProgress(queue, outFile, destPath, fileSize)
 		return nil // Graceful exit, not an error
 	}
 
+	// A permanent error or a spent failure budget stopped the workers
+	// Its state is saved so a retry resumes where it stopped.
+	if fatal := d.fatalError(); fatal != nil {
+		d.saveProgress(queue, outFile, destPath, fileSize)
+		return fatal
+	}
+
 	// Handle cancel: context was cancelled but not via Pause() - just exit cleanly
 	// The .pulse file remains for cleanup by the TUI (which will delete it)
 	if downloadCtx.Err() == context.Canceled {
@@ -369,12 +397,13 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 	}
 
 	if downloadErr != nil {
+		d.saveProgress(queue, outFile, destPath, fileSize)
 		return downloadErr
 	}
 
 	// Final sync
 	if err := outFile.Sync(); err != nil {
-		return fmt.Errorf("failed to sync file: %w", err)
+		return types.NewError(types.KindDisk, fmt.Errorf("failed to sync file: %w", err))
 	}
 
 	// Close file before renaming
@@ -382,7 +411,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 
 	// Rename from .pulse to final destination
 	if err := os.Rename(workingPath, destPath); err != nil {
-		return fmt.Errorf("failed to rename completed file: %w", err)
+		return types.NewError(types.KindDisk, fmt.Errorf("failed to rename completed file: %w", err))
 	}
 
 	// Delete state file on successful completion
@@ -392,3 +421,62 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl, destPath st
 
 	return nil
 }
+
+// saveProgress saves the work left in the queue and in the active tasks so the download can be resumed
+func (d *ConcurrentDownloader) saveProgress(queue *TaskQueue, file *os.File, destPath string, fileSize int64) {
+	// Collect remaining tasks
+	remainingTasks := queue.DrainRemaining()
+
+	// Also collect active tasks as remaining work
+	d.activeMu.Lock()
+	for _, active := range d.activeTasks {
+		if remaining := active.RemainingTask(); remaining != nil {
+			remainingTasks = append(remainingTasks, *remaining)
+		}
+	}
+	d.activeMu.Unlock()
+
+	// Calculate Downloaded from remaining tasks (ensures consistency)
+	var remainingBytes int64
+	for _, task := range remainingTasks {
+		remainingBytes += task.Length
+	}
+	computedDownloaded := fileSize - remainingBytes
+
+	// Debug: compare atomic counter vs computed value to verify fix
+	if d.State != nil {
+		atomicDownloaded := d.State.Downloaded.Load()
+		if atomicDownloaded != computedDownloaded {
+			utils.Debug("PAUSE FIX: Atomic counter=%d, Computed from tasks=%d, Diff=%d bytes",
+				atomicDownloaded, computedDownloaded, atomicDownloaded-computedDownloaded)
+		}
+	}
+
+	// Data first, so the saved state never claims bytes that could still be lost. If that fails
+	// the last checkpoint, which only covers synced data, is kept instead.
+	if err := file.Sync(); err != nil {
+		utils.Debug("Failed to sync file, keeping the previous state: %v", err)
+		return
+	}
+
+	// Save state for resume (use computed value for consistency)
+	s := &types.DownloadState{
+		URL:        d.URL,
+		ID:         d.ID,
+		DestPath:   destPath,
+		TotalSize:  fileSize,
+		Downloaded: computedDownloaded, // FIX: Use computed value instead of atomic counter
+		Tasks:      remainingTasks,
+		Filename:   filepath.Base(destPath),
+		ETag:       d.ETag,
+		Headers:    d.Headers,
+		Checksum:   d.Checksum,
+		Mirrors:    d.Mirrors,
+	}
+	if err := state.SaveState(d.URL, destPath, s); err != nil {
+		utils.Debug("Failed to save pause state: %v", err)
+	}
+
+	utils.Debug("Download stopped, state saved (Downloaded=%d, RemainingTasks=%d, RemainingBytes=%d)",
+		computedDownloaded, len(remainingTasks), remainingBytes)
+}
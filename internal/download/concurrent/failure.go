@@ -0,0 +1,60 @@
+package concurrent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// chargeFailure counts a failed request against the download's failure budget and returns
+// a non-nil error when the download should stop: straight away for a permanent error, or
+// once DownloadFailureBudget transient failures have been spent. Rate limits are not charged;
+// the host back-off waits them out however often the server asks.
+func (d *ConcurrentDownloader) chargeFailure(err error) error {
+	kind := types.Classify(err)
+	switch {
+	case kind == types.KindCancelled, isRateLimit(err):
+		return nil
+	case !kind.Transient():
+		return err
+	}
+
+	n := d.failures.Add(1)
+	if n < types.DownloadFailureBudget {
+		return nil
+	}
+	return types.NewError(kind, fmt.Errorf("giving up after %d failed requests: %w", n, err))
+}
+
+// isRateLimit reports whether err is a 429 or 503 answer from the server
+func isRateLimit(err error) bool {
+	var rl *rateLimitError
+	return errors.As(err, &rl)
+}
+
+// fail stops every worker and makes Download return err. Only the first error is kept.
+func (d *ConcurrentDownloader) fail(err error) {
+	d.fatalMu.Lock()
+	first := d.fatalErr == nil
+	if first {
+		d.fatalErr = err
+	}
+	abort := d.abort
+	d.fatalMu.Unlock()
+
+	if first {
+		utils.Debug("Download %s failed (%s): %v", d.ID, types.Classify(err), err)
+	}
+	if abort != nil {
+		abort()
+	}
+}
+
+// fatalError returns the error passed to fail, if any
+func (d *ConcurrentDownloader) fatalError() error {
+	d.fatalMu.Lock()
+	defer d.fatalMu.Unlock()
+	return d.fatalErr
+}
@@ -0,0 +1,92 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/testutil"
+)
+
+func TestChargeFailure(t *testing.T) {
+	d := NewConcurrentDownloader("budget-id", nil, nil, nil)
+
+	// Permanent errors stop the download at once
+	notFound := types.StatusError(http.StatusNotFound)
+	if err := d.chargeFailure(notFound); !errors.Is(err, notFound) {
+		t.Errorf("chargeFailure(404) = %v, want the 404 error", err)
+	}
+	if err := d.chargeFailure(types.NewError(types.KindDisk, errors.New("no space left"))); err == nil {
+		t.Error("Disk error did not stop the download")
+	}
+
+	// Cancellation is not a failure
+	if err := d.chargeFailure(context.Canceled); err != nil {
+		t.Errorf("chargeFailure(cancelled) = %v, want nil", err)
+	}
+
+	// Rate limits are waited out, not charged
+	limited := types.NewError(types.KindNetwork, &rateLimitError{Status: http.StatusTooManyRequests})
+	for i := 0; i <= types.DownloadFailureBudget; i++ {
+		if err := d.chargeFailure(limited); err != nil {
+			t.Fatalf("Rate limit %d ended the download: %v", i+1, err)
+		}
+	}
+
+	// Transient errors are charged against the budget
+	flaky := types.StatusError(http.StatusBadGateway)
+	for i := 1; i < types.DownloadFailureBudget; i++ {
+		if err := d.chargeFailure(flaky); err != nil {
+			t.Fatalf("Failure %d ended the download early: %v", i, err)
+		}
+	}
+	err := d.chargeFailure(flaky)
+	if err == nil {
+		t.Fatal("Spent failure budget did not end the download")
+	}
+	if kind := types.Classify(err); kind != types.KindNetwork {
+		t.Errorf("Budget error kind = %s, want %s", kind, types.KindNetwork)
+	}
+}
+
+func TestConcurrentDownloader_PermanentErrorFails(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create config dirs: %v", err)
+	}
+
+	// The file disappears after the probe: every range request gets a 404
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tmpDir, cleanup, _ := testutil.TempDir("pulse-permanent-test")
+	defer cleanup()
+
+	fileSize := int64(4 * types.MB)
+	destPath := filepath.Join(tmpDir, "gone.bin")
+	state := types.NewProgressState("permanent-test", fileSize)
+	downloader := NewConcurrentDownloader("permanent-id", nil, state, &types.RuntimeConfig{MaxConnectionsPerHost: 4})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := downloader.Download(ctx, server.URL+"/gone.bin", destPath, fileSize, false)
+	if err == nil {
+		t.Fatal("Download succeeded against a server answering 404")
+	}
+	if kind := types.Classify(err); kind != types.KindHTTP {
+		t.Errorf("Error kind = %s, want %s (%v)", kind, types.KindHTTP, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Permanent error took %v to fail the download", elapsed)
+	}
+}
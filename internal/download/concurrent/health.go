@@ -1,13 +1,19 @@
 package concurrent
 
 import (
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/messages"
 	"github.com/pulse-downloader/pulse/internal/utils"
 )
 
-// checkWorkerHealth detects slow workers and cancels them
+// checkWorkerHealth restarts connections that have stalled or fall far behind the others.
+// A restarted task keeps its progress: the worker re-queues the remaining range.
 func (d *ConcurrentDownloader) checkWorkerHealth() {
 	d.activeMu.Lock()
 	defer d.activeMu.Unlock()
@@ -17,6 +23,9 @@ func (d *ConcurrentDownloader) checkWorkerHealth() {
 	}
 
 	now := time.Now()
+	stallTimeout := d.runtime().GetStallTimeout()
+	gracePeriod := d.runtime().GetSlowWorkerGracePeriod()
+	threshold := d.runtime().GetSlowWorkerThreshold()
 
 	// First pass: calculate mean speed
 	var totalSpeed float64
@@ -33,32 +42,117 @@ func (d *ConcurrentDownloader) checkWorkerHealth() {
 		meanSpeed = totalSpeed / float64(speedCount)
 	}
 
-	// Second pass: check for slow workers
+	// Second pass: check for stalled and slow workers
 	for workerID, active := range d.activeTasks {
+		if active.Restarted {
+			continue // Already cancelled, waiting for the worker to pick it up
+		}
+
+		// Until the first byte, a slow server is given FirstByteTimeout rather than the stall timeout
+		last := atomic.LoadInt64(&active.LastActivity)
+		if last == 0 {
+			if waited := now.Sub(active.StartTime); waited >= max(stallTimeout, types.FirstByteTimeout) {
+				d.restartTask(active, messages.HealthStalled,
+					fmt.Sprintf("connection %d stalled (no data %s after it started), restarting", workerID, waited.Round(100*time.Millisecond)))
+			}
+			continue
+		}
 
-		// timeSinceActivity := now.Sub(lastTime)
-		taskDuration := now.Sub(active.StartTime)
+		// A hung connection keeps its last EMA speed, so look at when data last arrived
+		idle := now.Sub(time.Unix(0, last))
+		if idle >= stallTimeout {
+			d.restartTask(active, messages.HealthStalled,
+				fmt.Sprintf("connection %d stalled (no data for %s), restarting", workerID, idle.Round(100*time.Millisecond)))
+			continue
+		}
 
 		// Skip workers that are still in their grace period
-		gracePeriod := d.Runtime.GetSlowWorkerGracePeriod()
-		if taskDuration < gracePeriod {
+		if now.Sub(active.StartTime) < gracePeriod {
 			continue
 		}
 
-		// Check for slow worker
-		// Only cancel if: below threshold
+		// Only cancel if below the threshold relative to the mean AND below the absolute floor;
+		// a connection that is merely slower than very fast siblings is still worth keeping
 		if meanSpeed > 0 {
 			workerSpeed := active.GetSpeed()
-			threshold := d.Runtime.GetSlowWorkerThreshold()
-			isBelowThreshold := workerSpeed > 0 && workerSpeed < threshold*meanSpeed
-
-			if isBelowThreshold {
-				utils.Debug("Health: Worker %d slow (%.2f KB/s vs mean %.2f KB/s, min %.2f KB/s), cancelling",
-					workerID, workerSpeed/1024, meanSpeed/1024, float64(types.KB*100)/1024)
-				if active.Cancel != nil {
-					active.Cancel()
-				}
+			if workerSpeed > 0 && workerSpeed < threshold*meanSpeed && workerSpeed < types.MinAbsoluteSpeed {
+				d.restartTask(active, messages.HealthSlow,
+					fmt.Sprintf("connection %d slow (%s/s vs mean %s/s), restarting", workerID,
+						utils.ConvertBytesToHumanReadable(int64(workerSpeed)), utils.ConvertBytesToHumanReadable(int64(meanSpeed))))
 			}
 		}
 	}
 }
+
+// restartTask cancels an active task and reports why. Must be called with activeMu held.
+func (d *ConcurrentDownloader) restartTask(active *ActiveTask, kind messages.HealthKind, detail string) {
+	utils.Debug("Health: %s", detail)
+	active.Restarted = true
+	if active.Cancel != nil {
+		active.Cancel()
+	}
+	d.reportHealth(kind, detail)
+}
+
+// reportHealth sends a health event to the progress channel. Events are advisory,
+// so they are dropped rather than blocking a download when the channel is full.
+func (d *ConcurrentDownloader) reportHealth(kind messages.HealthKind, detail string) {
+	if d.ProgressChan == nil {
+		return
+	}
+	select {
+	case d.ProgressChan <- messages.HealthEventMsg{DownloadID: d.ID, Host: d.host, Kind: kind, Detail: detail}:
+	default:
+	}
+}
+
+// recordTaskResult updates the error count of the download's host after a request.
+// Reaching HostErrorThreshold consecutive failures is reported once as a health event.
+func (d *ConcurrentDownloader) recordTaskResult(err error) {
+	if err == nil {
+		hostErrors.success(d.host)
+		return
+	}
+	if n := hostErrors.failure(d.host); n == types.HostErrorThreshold {
+		d.reportHealth(messages.HealthHostErrors, fmt.Sprintf("%d requests in a row to %s failed: %v", n, d.host, err))
+	}
+}
+
+// hostErrorCounter counts consecutive failed requests per host across all downloads
+type hostErrorCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+var hostErrors = &hostErrorCounter{counts: make(map[string]int)}
+
+// failure records a failed request and returns the number of consecutive failures
+func (h *hostErrorCounter) failure(host string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[host]++
+	return h.counts[host]
+}
+
+// success clears the failure count of a host
+func (h *hostErrorCounter) success(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.counts, host)
+}
+
+// HostErrors returns the number of consecutive failed requests to host
+func HostErrors(host string) int {
+	hostErrors.mu.Lock()
+	defer hostErrors.mu.Unlock()
+	return hostErrors.counts[host]
+}
+
+// hostOf returns the host (with port, if any) of rawurl, or rawurl itself if it does not parse
+func hostOf(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return rawurl
+	}
+	return u.Host
+}
@@ -0,0 +1,134 @@
+package concurrent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/messages"
+)
+
+// newHealthTask returns an active task that started and last received data the given times ago
+func newHealthTask(started, idle time.Duration, speed float64, cancelled *bool) *ActiveTask {
+	now := time.Now()
+	return &ActiveTask{
+		StartTime:    now.Add(-started),
+		LastActivity: now.Add(-idle).UnixNano(),
+		Speed:        speed,
+		Cancel:       func() { *cancelled = true },
+	}
+}
+
+func TestCheckWorkerHealth_Stalled(t *testing.T) {
+	events := make(chan tea.Msg, 10)
+	d := NewConcurrentDownloader("health-id", events, nil, &types.RuntimeConfig{StallTimeout: 2 * time.Second})
+	d.host = "example.com"
+
+	var stalled, healthy bool
+	// The stalled task still reports its last speed, which must not hide the stall
+	d.activeTasks[0] = newHealthTask(10*time.Second, 3*time.Second, 5*types.MB, &stalled)
+	d.activeTasks[1] = newHealthTask(10*time.Second, 100*time.Millisecond, 5*types.MB, &healthy)
+
+	d.checkWorkerHealth()
+
+	if !stalled {
+		t.Error("Stalled connection was not cancelled")
+	}
+	if healthy {
+		t.Error("Healthy connection was cancelled")
+	}
+
+	select {
+	case msg := <-events:
+		ev, ok := msg.(messages.HealthEventMsg)
+		if !ok || ev.Kind != messages.HealthStalled || ev.DownloadID != "health-id" || ev.Host != "example.com" {
+			t.Errorf("Unexpected event %#v", msg)
+		}
+	default:
+		t.Fatal("No health event sent for the stalled connection")
+	}
+
+	// A cancelled task is not reported again while its worker winds down
+	d.checkWorkerHealth()
+	if len(events) != 0 {
+		t.Errorf("Stalled connection reported twice")
+	}
+}
+
+func TestCheckWorkerHealth_FirstByte(t *testing.T) {
+	d := NewConcurrentDownloader("health-id", nil, nil, &types.RuntimeConfig{StallTimeout: 2 * time.Second})
+
+	// Connections that have received nothing yet are judged by time to first byte, not the stall timeout
+	var waiting, hung bool
+	d.activeTasks[0] = &ActiveTask{StartTime: time.Now().Add(-10 * time.Second), Cancel: func() { waiting = true }}
+	d.activeTasks[1] = &ActiveTask{StartTime: time.Now().Add(-types.FirstByteTimeout - time.Second), Cancel: func() { hung = true }}
+
+	d.checkWorkerHealth()
+
+	if waiting {
+		t.Error("Connection waiting for its first byte was cancelled after the stall timeout")
+	}
+	if !hung {
+		t.Error("Connection without data past FirstByteTimeout was not cancelled")
+	}
+}
+
+func TestCheckWorkerHealth_SlowFloor(t *testing.T) {
+	d := NewConcurrentDownloader("health-id", nil, nil, &types.RuntimeConfig{StallTimeout: time.Minute})
+
+	var fast, slowButAboveFloor, slow bool
+	d.activeTasks[0] = newHealthTask(10*time.Second, 0, 20*types.MB, &fast)
+	d.activeTasks[1] = newHealthTask(10*time.Second, 0, 2*types.MB, &slowButAboveFloor)
+	d.activeTasks[2] = newHealthTask(10*time.Second, 0, 10*types.KB, &slow)
+
+	d.checkWorkerHealth()
+
+	if !slow {
+		t.Error("Connection below the floor and the mean was not cancelled")
+	}
+	if slowButAboveFloor || fast {
+		t.Error("Connection above the absolute speed floor was cancelled")
+	}
+}
+
+func TestCheckWorkerHealth_GracePeriod(t *testing.T) {
+	d := NewConcurrentDownloader("health-id", nil, nil, &types.RuntimeConfig{StallTimeout: time.Minute})
+
+	var fast, young bool
+	d.activeTasks[0] = newHealthTask(10*time.Second, 0, 20*types.MB, &fast)
+	d.activeTasks[1] = newHealthTask(time.Second, 0, 10*types.KB, &young)
+
+	d.checkWorkerHealth()
+
+	if young {
+		t.Error("Connection inside its grace period was cancelled")
+	}
+}
+
+func TestRecordTaskResult_HostErrors(t *testing.T) {
+	events := make(chan tea.Msg, 10)
+	d := NewConcurrentDownloader("host-id", events, nil, nil)
+	d.host = "errors.example.com"
+	defer hostErrors.success(d.host)
+
+	failure := errors.New("connection refused")
+	for i := 0; i < types.HostErrorThreshold+2; i++ {
+		d.recordTaskResult(failure)
+	}
+	if n := HostErrors(d.host); n != types.HostErrorThreshold+2 {
+		t.Errorf("HostErrors = %d, want %d", n, types.HostErrorThreshold+2)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected one host-errors event, got %d", len(events))
+	}
+	if ev := (<-events).(messages.HealthEventMsg); ev.Kind != messages.HealthHostErrors {
+		t.Errorf("Kind = %s, want %s", ev.Kind, messages.HealthHostErrors)
+	}
+
+	d.recordTaskResult(nil)
+	if n := HostErrors(d.host); n != 0 {
+		t.Errorf("HostErrors after success = %d, want 0", n)
+	}
+}
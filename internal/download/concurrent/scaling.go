@@ -0,0 +1,69 @@
+package concurrent
+
+import (
+	"errors"
+	"sync"
+)
+
+// errRetired is returned by a worker that stopped because the worker count was lowered
+var errRetired = errors.New("worker retired")
+
+// workerSet tracks the running workers of one download so their number can follow
+// live setting changes. Workers are added straight away when the target grows and
+// retire between tasks when it shrinks, so no byte range is ever abandoned.
+type workerSet struct {
+	mu      sync.Mutex
+	running int
+	target  int
+	nextID  int
+	closed  bool
+	start   func(id int) // Launches a worker goroutine; called with mu held
+	onEmpty func()       // Called once, when the last worker exits
+}
+
+// resize sets the target worker count and starts workers until it is reached
+func (ws *workerSet) resize(target int) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed || target < 1 {
+		return
+	}
+	ws.target = target
+	for ws.running < ws.target {
+		ws.running++
+		ws.start(ws.nextID)
+		ws.nextID++
+	}
+}
+
+// retire reports whether the calling worker should stop because there are more workers
+// than the target. A worker that retires is no longer counted.
+func (ws *workerSet) retire() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.running > ws.target {
+		ws.running--
+		return true
+	}
+	return false
+}
+
+// exit records that a worker stopped; retired workers were already uncounted by retire
+func (ws *workerSet) exit(retired bool) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if !retired {
+		ws.running--
+	}
+	if ws.running == 0 && !ws.closed {
+		ws.closed = true
+		ws.onEmpty()
+	}
+}
+
+// count returns the number of workers currently counted as running
+func (ws *workerSet) count() int {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.running
+}
@@ -0,0 +1,115 @@
+package concurrent
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/testutil"
+)
+
+func TestWorkerSet_ResizeAndRetire(t *testing.T) {
+	var started []int
+	emptied := 0
+	ws := &workerSet{
+		start:   func(id int) { started = append(started, id) },
+		onEmpty: func() { emptied++ },
+	}
+
+	ws.resize(2)
+	ws.resize(4)
+	if ws.count() != 4 || len(started) != 4 || started[3] != 3 {
+		t.Fatalf("Expected 4 workers with IDs 0-3, got count=%d started=%v", ws.count(), started)
+	}
+
+	// Shrinking starts nothing; two workers retire at their next check, the rest keep going
+	ws.resize(2)
+	retired := 0
+	for i := 0; i < 4; i++ {
+		if ws.retire() {
+			retired++
+			ws.exit(true)
+		}
+	}
+	if retired != 2 || ws.count() != 2 {
+		t.Fatalf("Expected 2 retirements leaving 2 workers, got %d leaving %d", retired, ws.count())
+	}
+
+	ws.exit(false)
+	ws.exit(false)
+	if emptied != 1 {
+		t.Fatalf("onEmpty called %d times, want 1", emptied)
+	}
+
+	// Once every worker has exited the set is closed and cannot be revived
+	ws.resize(3)
+	if ws.count() != 0 || len(started) != 4 {
+		t.Errorf("Closed set started workers: count=%d started=%v", ws.count(), started)
+	}
+}
+
+func TestConcurrentDownloader_LiveScaleUp(t *testing.T) {
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create config dirs: %v", err)
+	}
+
+	fileSize := int64(20 * types.MB) // Large enough for 4 connections
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(fileSize),
+		testutil.WithRangeSupport(true),
+		testutil.WithByteLatency(50*time.Nanosecond),
+	)
+	defer server.Close()
+
+	tmpDir, cleanup, _ := testutil.TempDir("pulse-scale-test")
+	defer cleanup()
+
+	destPath := filepath.Join(tmpDir, "scale_test.bin")
+	progState := types.NewProgressState("scale-test", fileSize)
+	live := types.NewLiveRuntime(&types.RuntimeConfig{MaxConnectionsPerHost: 1})
+
+	downloader := NewConcurrentDownloader("scale-id", nil, progState, nil)
+	downloader.Live = live
+
+	var peak atomic.Int64
+	stopMonitor := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopMonitor:
+				return
+			case <-ticker.C:
+				if n := server.ActiveRequests.Load(); n > peak.Load() {
+					peak.Store(n)
+				}
+			}
+		}
+	}()
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		live.Set(&types.RuntimeConfig{MaxConnectionsPerHost: 4})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := downloader.Download(ctx, server.URL(), destPath, fileSize, false)
+	close(stopMonitor)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if peak.Load() < 2 {
+		t.Errorf("Expected extra connections after raising the limit, peak was %d", peak.Load())
+	}
+	if err := testutil.VerifyFileSize(destPath, fileSize); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,196 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// StreamChunkSize is the size of the pieces a streamed download is fetched in
+const StreamChunkSize = 4 * types.MB
+
+// streamChunksPerConn is how many chunks each connection may fetch ahead of the writer.
+// Memory use is bounded by connections * streamChunksPerConn * StreamChunkSize.
+const streamChunksPerConn = 2
+
+// chunkBuffer collects one chunk of a streamed download in memory
+type chunkBuffer struct {
+	offset int64 // Offset of data[0] in the file
+	data   []byte
+}
+
+// WriteAt stores p at file offset off, which must lie within the chunk
+func (c *chunkBuffer) WriteAt(p []byte, off int64) (int, error) {
+	start := off - c.offset
+	if start < 0 || start+int64(len(p)) > int64(len(c.data)) {
+		return 0, fmt.Errorf("write of %d bytes at %d is outside chunk %d-%d", len(p), off, c.offset, c.offset+int64(len(c.data)))
+	}
+	return copy(c.data[start:], p), nil
+}
+
+// Stream downloads the file over multiple connections and writes it to w in order.
+// Connections fetch chunks at most a few ahead of the one being written; when w is slow
+// they wait for it, so memory stays bounded. A stream can't be paused or resumed.
+func (d *ConcurrentDownloader) Stream(ctx context.Context, rawurl string, w io.Writer, fileSize int64, verbose bool) error {
+	utils.Debug("ConcurrentDownloader.Stream: %s (size: %d)", rawurl, fileSize)
+	d.URL = rawurl
+	d.host = hostOf(rawurl)
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if d.State != nil {
+		d.State.CancelFunc = cancel
+	}
+
+	numConns := min(d.getInitialConnections(fileSize), d.connectionLimit(0))
+	chunks := int((fileSize + StreamChunkSize - 1) / StreamChunkSize)
+	numConns = min(numConns, chunks)
+	client := d.newConcurrentClient(numConns)
+	if verbose {
+		// Stdout carries the file
+		fmt.Fprintf(os.Stderr, "File size: %s, connections: %d, chunk size: %s\n",
+			utils.ConvertBytesToHumanReadable(fileSize), numConns, utils.ConvertBytesToHumanReadable(StreamChunkSize))
+	}
+
+	// Chunks are claimed in order, and only while a slot in the window is free,
+	// so the chunk the writer waits for is always being fetched
+	slots := make(chan struct{}, numConns*streamChunksPerConn)
+	ready := make([]chan []byte, chunks)
+	for i := range ready {
+		ready[i] = make(chan []byte, 1)
+	}
+	var next atomic.Int64
+	var fetchErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+
+	for range numConns {
+		wg.Add(1)
+		globalWorkers.Add(1)
+		go func() {
+			defer wg.Done()
+			defer globalWorkers.Add(-1)
+			buf := make([]byte, d.runtime().GetWorkerBufferSize())
+			for {
+				select {
+				case slots <- struct{}{}:
+				case <-streamCtx.Done():
+					return
+				}
+				i := int(next.Add(1) - 1)
+				if i >= chunks {
+					return
+				}
+				offset := int64(i) * StreamChunkSize
+				data, err := d.fetchChunk(streamCtx, rawurl, offset, min(StreamChunkSize, fileSize-offset), buf, verbose, client)
+				if err != nil {
+					errOnce.Do(func() { fetchErr = err })
+					cancel()
+					return
+				}
+				ready[i] <- data
+			}
+		}()
+	}
+
+	var err error
+	written := 0
+	for i := range chunks {
+		select {
+		case data := <-ready[i]:
+			if _, writeErr := w.Write(data); writeErr != nil {
+				err = types.NewError(types.KindDisk, fmt.Errorf("write error: %w", writeErr))
+			} else {
+				written++
+			}
+			<-slots
+		case <-streamCtx.Done():
+		}
+		if err != nil || streamCtx.Err() != nil {
+			break
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	switch {
+	case err != nil:
+		return err
+	case fetchErr != nil && !errors.Is(fetchErr, context.Canceled):
+		return fetchErr
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case written < chunks:
+		return context.Canceled // Stopped through State.CancelFunc
+	}
+	return nil
+}
+
+// fetchChunk downloads one chunk of a streamed download, resuming within it after a failed request.
+// The chunk can't be put back for later like a task, so it is retried until the download's failure
+// budget is spent; rate limits are waited out without counting as attempts.
+func (d *ConcurrentDownloader) fetchChunk(ctx context.Context, rawurl string, offset, length int64, buf []byte, verbose bool, client *http.Client) ([]byte, error) {
+	chunk := &chunkBuffer{offset: offset, data: make([]byte, length)}
+	task := types.Task{Offset: offset, Length: length}
+	if d.State != nil {
+		d.State.ActiveWorkers.Add(1)
+		defer d.State.ActiveWorkers.Add(-1)
+	}
+
+	var lastErr error
+	maxRetries := d.runtime().GetMaxTaskRetries()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && !isRateLimit(lastErr) {
+			select {
+			case <-time.After(time.Duration(1<<min(attempt, maxRetries)) * types.RetryBaseDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if err := hostLimits.wait(ctx, d.host); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		active := &ActiveTask{
+			Task:          task,
+			CurrentOffset: task.Offset,
+			StopAt:        task.Offset + task.Length,
+			StartTime:     now,
+			WindowStart:   now,
+		}
+		lastErr = d.downloadTask(ctx, rawurl, chunk, active, buf, verbose, client)
+		current := atomic.LoadInt64(&active.CurrentOffset)
+		if lastErr == nil && current < task.Offset+task.Length {
+			lastErr = fmt.Errorf("read error: %w", io.ErrUnexpectedEOF) // The server closed the response early
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		d.recordTaskResult(lastErr)
+		d.recordRateLimit(lastErr, nil)
+		if lastErr == nil {
+			return chunk.data, nil
+		}
+
+		// Only the rest of the chunk is fetched again
+		if current > task.Offset {
+			task = types.Task{Offset: current, Length: task.Offset + task.Length - current}
+		}
+		if fatal := d.chargeFailure(lastErr); fatal != nil {
+			return nil, fatal
+		}
+		if isRateLimit(lastErr) {
+			attempt--
+		}
+	}
+}
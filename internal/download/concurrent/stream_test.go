@@ -0,0 +1,121 @@
+package concurrent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+// slowWriter collects a stream, checking on every write how far the download ran ahead of it
+type slowWriter struct {
+	t        *testing.T
+	state    *types.ProgressState
+	maxAhead int64
+	buf      bytes.Buffer
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	if ahead := w.state.Downloaded.Load() - int64(w.buf.Len()); ahead > w.maxAhead {
+		w.t.Errorf("Download is %d bytes ahead of the writer, want at most %d", ahead, w.maxAhead)
+	}
+	time.Sleep(20 * time.Millisecond)
+	return w.buf.Write(p)
+}
+
+func TestConcurrentDownloader_Stream(t *testing.T) {
+	content := make([]byte, 8*StreamChunkSize+StreamChunkSize/2)
+	for i := range content {
+		content[i] = byte(i*7 + i/251)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "stream.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	const conns = 2
+	fileSize := int64(len(content))
+	state := types.NewProgressState("stream-test", fileSize)
+	runtime := &types.RuntimeConfig{MaxConnectionsPerHost: conns}
+	downloader := NewConcurrentDownloader("stream-id", nil, state, runtime)
+
+	w := &slowWriter{t: t, state: state, maxAhead: conns * streamChunksPerConn * StreamChunkSize}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := downloader.Stream(ctx, server.URL+"/stream.bin", w, fileSize, false); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if !bytes.Equal(w.buf.Bytes(), content) {
+		t.Error("Streamed bytes differ from the file or are out of order")
+	}
+	if got := state.Downloaded.Load(); got != fileSize {
+		t.Errorf("Downloaded = %d, want %d", got, fileSize)
+	}
+}
+
+func TestConcurrentDownloader_StreamCancelled(t *testing.T) {
+	content := make([]byte, 4*StreamChunkSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "stream.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	state := types.NewProgressState("stream-cancel", int64(len(content)))
+	downloader := NewConcurrentDownloader("stream-cancel", nil, state, &types.RuntimeConfig{MaxConnectionsPerHost: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &slowWriter{t: t, state: state, maxAhead: int64(len(content))}
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		cancel()
+	}()
+
+	err := downloader.Stream(ctx, server.URL+"/stream.bin", w, int64(len(content)), false)
+	if types.Classify(err) != types.KindCancelled {
+		t.Errorf("Cancelled stream returned %v, want a cancellation", err)
+	}
+}
+
+func TestConcurrentDownloader_StreamRetriesChunk(t *testing.T) {
+	content := make([]byte, StreamChunkSize)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	// More failures than a task retries, then a rate limit, before the chunk is served
+	const failures = 4
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n := requests.Add(1); {
+		case n <= failures:
+			w.WriteHeader(http.StatusBadGateway)
+		case n == failures+1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			http.ServeContent(w, r, "stream.bin", time.Time{}, bytes.NewReader(content))
+		}
+	}))
+	defer server.Close()
+
+	fileSize := int64(len(content))
+	state := types.NewProgressState("stream-retry", fileSize)
+	runtime := &types.RuntimeConfig{MaxConnectionsPerHost: 1, MaxTaskRetries: 1}
+	downloader := NewConcurrentDownloader("stream-retry", nil, state, runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var buf bytes.Buffer
+	if err := downloader.Stream(ctx, server.URL+"/stream.bin", &buf, fileSize, false); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Error("Streamed bytes differ from the file")
+	}
+}
@@ -16,11 +16,12 @@ type ActiveTask struct {
 	StopAt        int64 // Atomic
 
 	// Health monitoring fields
-	LastActivity int64              // Atomic: Unix nano timestamp of last data received
+	LastActivity int64              // Atomic: Unix nano timestamp of last data received, 0 until the first byte
 	Speed        float64            // EMA-smoothed speed in bytes/sec (protected by mutex)
 	StartTime    time.Time          // When this task started
 	Cancel       context.CancelFunc // Cancel function to abort this task
 	SpeedMu      sync.Mutex         // Protects Speed field
+	Restarted    bool               // Set once the health monitor cancelled this task (protected by activeMu)
 
 	// Sliding window for recent speed tracking
 	WindowStart time.Time // When current measurement window started
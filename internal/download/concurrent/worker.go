@@ -14,7 +14,7 @@ import (
 )
 
 // worker downloads tasks from the queue
-func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string, file *os.File, queue *TaskQueue, totalSize int64, startTime time.Time, verbose bool, client *http.Client) error {
+func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string, file *os.File, queue *TaskQueue, totalSize int64, startTime time.Time, verbose bool, client *http.Client, workers *workerSet) error {
 	// Get pooled buffer
 	bufPtr := bufPool.Get().(*[]byte)
 	defer bufPool.Put(bufPtr)
@@ -24,6 +24,12 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string
 	defer utils.Debug("Worker %d finished", id)
 
 	for {
+		// Stop between tasks if the worker count was lowered
+		if workers != nil && workers.retire() {
+			utils.Debug("Worker %d retired", id)
+			return errRetired
+		}
+
 		// Get next task
 		task, ok := queue.Pop()
 
@@ -37,12 +43,21 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string
 		}
 
 		var lastErr error
-		maxRetries := d.Runtime.GetMaxTaskRetries()
+		maxRetries := d.runtime().GetMaxTaskRetries()
 		for attempt := 0; attempt < maxRetries; attempt++ {
-			if attempt > 0 {
+			if attempt > 0 && !isRateLimit(lastErr) {
 				time.Sleep(time.Duration(1<<attempt) * types.RetryBaseDelay) //Exponential backoff incase of failure
 			}
 
+			// Rate-limited hosts are backed off for every worker at once
+			if err := hostLimits.wait(ctx, d.host); err != nil {
+				queue.Push(task) // Not registered as active, so the pause handler would miss it
+				if d.State != nil {
+					d.State.ActiveWorkers.Add(-1)
+				}
+				return err
+			}
+
 			// Register active task with per-task cancellable context
 			taskCtx, taskCancel := context.WithCancel(ctx)
 			now := time.Now()
@@ -50,7 +65,6 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string
 				Task:          task,
 				CurrentOffset: task.Offset,
 				StopAt:        task.Offset + task.Length,
-				LastActivity:  now.UnixNano(),
 				StartTime:     now,
 				Cancel:        taskCancel,
 				WindowStart:   now, // Initialize sliding window
@@ -108,6 +122,8 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string
 			d.activeMu.Lock()
 			delete(d.activeTasks, id)
 			d.activeMu.Unlock()
+			d.recordTaskResult(lastErr)
+			d.recordRateLimit(lastErr, workers)
 
 			if lastErr == nil {
 				// Check if we stopped early due to stealing
@@ -126,6 +142,20 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string
 			if current > task.Offset {
 				task = types.Task{Offset: current, Length: task.Offset + task.Length - current}
 			}
+
+			// Permanent errors and a spent failure budget end the whole download
+			if fatal := d.chargeFailure(lastErr); fatal != nil {
+				queue.Push(task) // Keep the range for the saved state
+				if d.State != nil {
+					d.State.ActiveWorkers.Add(-1)
+				}
+				d.fail(fatal)
+				return fatal
+			}
+			// Rate limits don't use up the task's retries either
+			if isRateLimit(lastErr) {
+				attempt--
+			}
 		}
 
 		// Update active workers
@@ -144,7 +174,7 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, rawurl string
 }
 
 // downloadTask downloads a single byte range and writes to file at offset
-func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string, file *os.File, activeTask *ActiveTask, buf []byte, verbose bool, client *http.Client) error {
+func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string, file io.WriterAt, activeTask *ActiveTask, buf []byte, verbose bool, client *http.Client) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
 	if err != nil {
 		return err
@@ -152,22 +182,32 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 
 	task := activeTask.Task
 
-	req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
+	req.Header.Set("User-Agent", d.runtime().GetUserAgent())
+	types.SetHeaders(req.Header, d.Headers)
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", task.Offset, task.Offset+task.Length-1))
 
 	resp, err := client.Do(req)
 	if err != nil {
+		if isConnReset(err) {
+			d.throttled.Add(1)
+		}
 		return err
 	}
 	defer resp.Body.Close()
 
-	// Handle rate limiting explicitly
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return fmt.Errorf("rate limited (429)")
+	// Tell the adaptive controller the server is pushing back, and back off the host
+	if isThrottleStatus(resp.StatusCode) {
+		d.throttled.Add(1)
+		return types.NewError(types.KindNetwork, &rateLimitError{Status: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())})
 	}
 
 	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		return types.StatusError(resp.StatusCode)
+	}
+
+	// A full response to a range starting past zero would be written at the wrong offset
+	if resp.StatusCode == http.StatusOK && task.Offset > 0 {
+		return types.NewError(types.KindIntegrity, fmt.Errorf("server ignored the range request for offset %d", task.Offset))
 	}
 
 	// Read and write at offset
@@ -226,8 +266,9 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 
 			_, writeErr := file.WriteAt(buf[:readSoFar], offset)
 			if writeErr != nil {
-				return fmt.Errorf("write error: %w", writeErr)
+				return types.NewError(types.KindDisk, fmt.Errorf("write error: %w", writeErr))
 			}
+			d.written.add(offset, offset+int64(readSoFar))
 
 			now := time.Now()
 			oldOffset := offset
@@ -243,7 +284,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 				recentSpeed := float64(windowBytes) / windowElapsed
 
 				activeTask.SpeedMu.Lock()
-				alpha := d.Runtime.GetSpeedEmaAlpha()
+				alpha := d.runtime().GetSpeedEmaAlpha()
 				if activeTask.Speed == 0 {
 					activeTask.Speed = recentSpeed
 				} else {
@@ -273,6 +314,9 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 			break
 		}
 		if readErr != nil {
+			if isConnReset(readErr) {
+				d.throttled.Add(1)
+			}
 			return fmt.Errorf("read error: %w", readErr)
 		}
 	}
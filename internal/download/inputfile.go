@@ -0,0 +1,193 @@
+package download
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// InputEntry is one download of an input file with the options given for it
+type InputEntry struct {
+	URL      string
+	Mirrors  []string          // Other URLs of the same file, from the rest of the URL line
+	Filename string            // out=
+	Dir      string            // dir=, relative paths are taken from the output directory
+	Headers  map[string]string // header=, may be repeated
+	Checksum string            // checksum=, as "algo=hex"
+	Quality  string            // quality=
+}
+
+// Options returns the download options of the entry on top of defaults.
+// Headers from both are sent; the entry's win when they name the same header.
+func (e InputEntry) Options(defaults Options) Options {
+	opts := defaults
+	opts.Mirrors = e.Mirrors
+	if e.Filename != "" {
+		opts.Filename = e.Filename
+	}
+	if e.Checksum != "" {
+		opts.Checksum = e.Checksum
+	}
+	if e.Quality != "" {
+		opts.Quality = e.Quality
+	}
+	if len(e.Headers) > 0 {
+		opts.Headers = make(map[string]string, len(defaults.Headers)+len(e.Headers))
+		for name, value := range defaults.Headers {
+			opts.Headers[name] = value
+		}
+		for name, value := range e.Headers {
+			opts.Headers[name] = value
+		}
+	}
+	return opts
+}
+
+// OutputPath returns the directory to save the entry in, given the output directory of the batch
+func (e InputEntry) OutputPath(base string) string {
+	if e.Dir == "" {
+		return base
+	}
+	if filepath.IsAbs(e.Dir) || base == "" {
+		return e.Dir
+	}
+	return filepath.Join(base, e.Dir)
+}
+
+// String formats the entry the way ParseInputFile reads it
+func (e InputEntry) String() string {
+	var b strings.Builder
+	b.WriteString(strings.Join(append([]string{e.URL}, e.Mirrors...), "\t"))
+	b.WriteString("\n")
+	option := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "  %s=%s\n", name, value)
+		}
+	}
+	option("dir", e.Dir)
+	option("out", e.Filename)
+	option("checksum", e.Checksum)
+	option("quality", e.Quality)
+	names := make([]string, 0, len(e.Headers))
+	for name := range e.Headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		option("header", name+": "+e.Headers[name])
+	}
+	return b.String()
+}
+
+// ParseHeader parses a request header given as "Name: value"
+func ParseHeader(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (want \"Name: value\")", s)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// ParseInputFile reads an input file in the format of aria2's -i option.
+// Each download starts with a line of tab-separated URLs of the same file: the first is
+// downloaded, the others are mirrors tried when it can't be reached. Indented lines below
+// it set options for that download:
+//
+//	https://example.com/file.iso	https://mirror.example.org/file.iso
+//	  out=file.iso
+//	  dir=isos
+//	  header=Cookie: session=abc
+//	  checksum=sha-256=<hex>
+//	  quality=1080p
+//
+// Empty lines and lines starting with # are skipped. Other aria2 options are ignored.
+func ParseInputFile(r io.Reader) ([]InputEntry, error) {
+	var entries []InputEntry
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if raw[0] != ' ' && raw[0] != '\t' {
+			urls := strings.Fields(line)
+			e := InputEntry{URL: urls[0]}
+			if len(urls) > 1 {
+				e.Mirrors = urls[1:]
+			}
+			entries = append(entries, e)
+			continue
+		}
+
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("line %d: option before the first URL", n)
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid option %q (want name=value)", n, line)
+		}
+		e := &entries[len(entries)-1]
+		switch strings.TrimSpace(name) {
+		case "out":
+			e.Filename = value
+		case "dir":
+			e.Dir = value
+		case "header":
+			hName, hValue, err := ParseHeader(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			if e.Headers == nil {
+				e.Headers = make(map[string]string)
+			}
+			e.Headers[hName] = hValue
+		case "checksum":
+			if _, err := ParseChecksum(value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			e.Checksum = value
+		case "quality":
+			e.Quality = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return entries, nil
+}
+
+// ReadInputFile parses the input file at path, dropping repeated URLs (ignoring a trailing slash).
+// It returns the entries and how many were dropped.
+func ReadInputFile(path string) ([]InputEntry, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := ParseInputFile(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	seen := make(map[string]bool)
+	unique := entries[:0]
+	for _, e := range entries {
+		normalized := strings.TrimRight(e.URL, "/")
+		if !seen[normalized] {
+			seen[normalized] = true
+			unique = append(unique, e)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, 0, fmt.Errorf("no URLs found in file")
+	}
+	return unique, len(entries) - len(unique), nil
+}
@@ -0,0 +1,85 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseInputFile(t *testing.T) {
+	input := "# release manifest\n" +
+		"https://example.com/a.iso\thttps://mirror.example.org/a.iso\n" +
+		"  out=a-1.0.iso\n" +
+		"\tdir=isos\n" +
+		"  header=cookie: session=abc\n" +
+		"  header=Referer: https://example.com/\n" +
+		"  checksum=sha-256=" + strings.Repeat("ab", 32) + "\n" +
+		"  split=5\n" +
+		"\n" +
+		"https://youtube.com/watch?v=x\n" +
+		"  quality=720p\n"
+
+	entries, err := ParseInputFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseInputFile failed: %v", err)
+	}
+	want := []InputEntry{
+		{
+			URL:      "https://example.com/a.iso",
+			Mirrors:  []string{"https://mirror.example.org/a.iso"},
+			Filename: "a-1.0.iso",
+			Dir:      "isos",
+			Headers:  map[string]string{"Cookie": "session=abc", "Referer": "https://example.com/"},
+			Checksum: "sha-256=" + strings.Repeat("ab", 32),
+		},
+		{URL: "https://youtube.com/watch?v=x", Quality: "720p"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("ParseInputFile =\n%+v\nwant\n%+v", entries, want)
+	}
+
+	// Formatting an entry and parsing it again gives the same entry
+	again, err := ParseInputFile(strings.NewReader(entries[0].String()))
+	if err != nil || !reflect.DeepEqual(again, entries[:1]) {
+		t.Errorf("Round trip of %q = %+v, %v", entries[0].String(), again, err)
+	}
+}
+
+func TestParseInputFile_Errors(t *testing.T) {
+	for _, input := range []string{
+		"  out=a.iso\nhttps://example.com/a.iso\n",
+		"https://example.com/a.iso\n  out\n",
+		"https://example.com/a.iso\n  header=no colon\n",
+		"https://example.com/a.iso\n  checksum=crc32=1234\n",
+	} {
+		if _, err := ParseInputFile(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseInputFile(%q) should fail", input)
+		}
+	}
+}
+
+func TestReadInputFile_Duplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	os.WriteFile(path, []byte("https://example.com/a\nhttps://example.com/a/\nhttps://example.com/b\n"), 0644)
+
+	entries, duplicates, err := ReadInputFile(path)
+	if err != nil || len(entries) != 2 || duplicates != 1 {
+		t.Errorf("ReadInputFile = %d entries, %d duplicates, %v", len(entries), duplicates, err)
+	}
+}
+
+func TestInputEntry_Options(t *testing.T) {
+	e := InputEntry{Filename: "a.iso", Dir: "isos", Headers: map[string]string{"Cookie": "entry"}}
+	opts := e.Options(Options{Category: "Images", Headers: map[string]string{"Cookie": "cli", "Referer": "x"}})
+	if opts.Filename != "a.iso" || opts.Category != "Images" || opts.Headers["Cookie"] != "entry" || opts.Headers["Referer"] != "x" {
+		t.Errorf("Options = %+v", opts)
+	}
+	if got := e.OutputPath("downloads"); got != filepath.Join("downloads", "isos") {
+		t.Errorf("OutputPath = %s", got)
+	}
+	if got := (InputEntry{Dir: "/srv/isos"}).OutputPath("downloads"); got != "/srv/isos" {
+		t.Errorf("OutputPath with absolute dir = %s", got)
+	}
+}
@@ -3,12 +3,15 @@ package download
 import (
 	"context"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -32,10 +35,11 @@ type ProbeResult struct {
 	SupportsRange bool
 	Filename      string
 	ContentType   string
+	ETag          string
 }
 
 // probeServer sends GET with Range: bytes=0-0 to determine server capabilities
-func probeServer(ctx context.Context, rawurl string, filenameHint string) (*ProbeResult, error) {
+func probeServer(ctx context.Context, rawurl string, filenameHint string, headers map[string]string) (*ProbeResult, error) {
 	utils.Debug("Probing server: %s", rawurl)
 
 	var resp *http.Response
@@ -53,12 +57,13 @@ func probeServer(ctx context.Context, rawurl string, filenameHint string) (*Prob
 
 		req, reqErr := http.NewRequestWithContext(probeCtx, http.MethodGet, rawurl, nil)
 		if reqErr != nil {
-			err = fmt.Errorf("failed to create probe request: %w", reqErr)
+			err = types.NewError(types.KindConfig, fmt.Errorf("failed to create probe request: %w", reqErr))
 			break // Fatal error, don't retry
 		}
 
-		req.Header.Set("Range", "bytes=0-0")
 		req.Header.Set("User-Agent", ua)
+		types.SetHeaders(req.Header, headers)
+		req.Header.Set("Range", "bytes=0-0")
 
 		resp, err = probeClient.Do(req)
 		if err == nil {
@@ -106,7 +111,7 @@ func probeServer(ctx context.Context, rawurl string, filenameHint string) (*Prob
 		utils.Debug("Range NOT supported (got 200), file size: %d", result.FileSize)
 
 	default:
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, types.StatusError(resp.StatusCode)
 	}
 
 	// Determine filename using strengthened logic
@@ -123,6 +128,7 @@ func probeServer(ctx context.Context, rawurl string, filenameHint string) (*Prob
 	}
 
 	result.ContentType = resp.Header.Get("Content-Type")
+	result.ETag = resp.Header.Get("ETag")
 
 	utils.Debug("Probe complete - filename: %s, size: %d, range: %v",
 		result.Filename, result.FileSize, result.SupportsRange)
@@ -130,13 +136,46 @@ func probeServer(ctx context.Context, rawurl string, filenameHint string) (*Prob
 	return result, nil
 }
 
-// uniqueFilePath returns a unique file path by appending (1), (2), etc. if the file exists
+// reservedPaths holds the destinations picked by fresh downloads of this process that are
+// still running, so downloads started side by side never pick the same name
+var (
+	reservedMu    sync.Mutex
+	reservedPaths = make(map[string]bool)
+)
+
+// reserveFilePath picks a unique destination like uniqueFilePath and holds it until release
+// is called
+func reserveFilePath(path string) (unique string, release func()) {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	unique = uniqueFilePath(path)
+	reservedPaths[unique] = true
+	return unique, func() {
+		reservedMu.Lock()
+		delete(reservedPaths, unique)
+		reservedMu.Unlock()
+	}
+}
+
+// pathTaken reports whether a download may not use path: the file or its partial file exists,
+// or another download of this process has reserved it
+func pathTaken(path string) bool {
+	if reservedPaths[path] {
+		return true
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return true
+	}
+	_, err := os.Stat(path + types.IncompleteSuffix)
+	return !os.IsNotExist(err)
+}
+
+// uniqueFilePath returns a unique file path by appending (1), (2), etc. if the file exists.
+// Callers other than tests hold reservedMu.
 func uniqueFilePath(path string) string {
 	// Check if file exists (both final and incomplete)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		if _, err := os.Stat(path + types.IncompleteSuffix); os.IsNotExist(err) {
-			return path // Neither exists, use original
-		}
+	if !pathTaken(path) {
+		return path // Neither exists, use original
 	}
 
 	// File exists, generate unique name
@@ -161,10 +200,8 @@ func uniqueFilePath(path string) string {
 
 	for i := 0; i < 100; i++ { // Try next 100 numbers
 		candidate := filepath.Join(dir, fmt.Sprintf("%s(%d)%s", base, counter+i, ext))
-		if _, err := os.Stat(candidate); os.IsNotExist(err) {
-			if _, err := os.Stat(candidate + types.IncompleteSuffix); os.IsNotExist(err) {
-				return candidate
-			}
+		if !pathTaken(candidate) {
+			return candidate
 		}
 	}
 
@@ -175,6 +212,52 @@ func uniqueFilePath(path string) string {
 
 // TUIDownload is the main entry point for TUI downloads
 func TUIDownload(ctx context.Context, cfg types.DownloadConfig) error {
+	// Check if this is a resume (explicitly marked by TUI)
+	var savedState *types.DownloadState
+	if cfg.IsResume && cfg.DestPath != "" {
+		// Resume: use the provided destination path for state lookup
+		savedState, _ = state.LoadState(cfg.URL, cfg.DestPath)
+		// A download that switched to a mirror saved its state under the mirror's URL
+		for _, mirror := range cfg.Mirrors {
+			if savedState != nil {
+				break
+			}
+			savedState, _ = state.LoadState(mirror, cfg.DestPath)
+		}
+	}
+	if savedState != nil {
+		// Fetch and check the rest of the file the same way as its start
+		if cfg.Headers == nil {
+			cfg.Headers = savedState.Headers
+		}
+		if cfg.Checksum == "" {
+			cfg.Checksum = savedState.Checksum
+		}
+		if cfg.Mirrors == nil {
+			cfg.Mirrors = savedState.Mirrors
+		}
+	}
+	// Try the URL the saved state belongs to first, then the rest in their given order
+	urls := append([]string{cfg.URL}, cfg.Mirrors...)
+	if savedState != nil && savedState.URL != "" && savedState.URL != cfg.URL && !IsYoutubeURL(cfg.URL) {
+		urls = append([]string{savedState.URL}, slices.DeleteFunc(urls, func(u string) bool { return u == savedState.URL })...)
+	}
+	var checksum *Checksum
+	if cfg.Checksum != "" {
+		c, err := ParseChecksum(cfg.Checksum)
+		if err != nil {
+			return types.NewError(types.KindConfig, err)
+		}
+		checksum = &c
+	}
+	var rules []types.CategoryRule
+	if rc := cfg.RuntimeConfig(); rc != nil {
+		rules = rc.Categories
+		// An explicit category must name a rule; resumed downloads already have their destination
+		if cfg.Category != "" && cfg.DestPath == "" && FindCategory(rules, cfg.Category) == nil {
+			return types.NewError(types.KindConfig, fmt.Errorf("unknown category %q", cfg.Category))
+		}
+	}
 
 	// Probe server once to get all metadata
 	// Check for YouTube URL first
@@ -191,103 +274,318 @@ func TUIDownload(ctx context.Context, cfg types.DownloadConfig) error {
 		ytFilename = title
 		utils.Debug("Resolved YouTube to: %s", title)
 	} else {
-		resolvedURL = cfg.URL
+		resolvedURL = urls[0]
 	}
 
-	probe, err := probeServer(ctx, resolvedURL, cfg.Filename)
+	probe, err := probeServer(ctx, resolvedURL, cfg.Filename, cfg.Headers)
+	if err != nil && !IsYoutubeURL(cfg.URL) {
+		// Fall back to the mirrors in turn; the download continues from whichever answers
+		for _, mirror := range urls[1:] {
+			if ctx.Err() != nil {
+				break
+			}
+			utils.Debug("Probe of %s failed (%v), trying mirror %s", resolvedURL, err, mirror)
+			if p, mirrorErr := probeServer(ctx, mirror, cfg.Filename, cfg.Headers); mirrorErr == nil {
+				probe, err, resolvedURL = p, nil, mirror
+				break
+			}
+		}
+	}
 	if err != nil {
 		utils.Debug("Probe failed: %v", err)
 		return err
 	}
+	// The other URLs are kept with the saved state so a resume can still fall back to them
+	mirrors := cfg.Mirrors
+	if !IsYoutubeURL(cfg.URL) {
+		mirrors = slices.DeleteFunc(urls, func(u string) bool { return u == resolvedURL })
+	}
 
 	// Override filename if it came from YouTube and user didn't specify one
 	if ytFilename != "" && cfg.Filename == "" {
 		probe.Filename = ytFilename
 	}
 
+	if cfg.Output != nil {
+		return streamDownload(ctx, cfg, resolvedURL, probe, checksum)
+	}
+
 	// Start download timer (exclude probing time)
 	start := time.Now()
 	defer func() {
 		utils.Debug("Download %s completed in %v", cfg.URL, time.Since(start))
 	}()
 
+	// Pick a category: an explicit selection wins, otherwise the first matching rule
+	category := cfg.Category
+	var rule *types.CategoryRule
+	if category != "" {
+		rule = FindCategory(rules, category)
+	} else {
+		filename := probe.Filename
+		if cfg.Filename != "" {
+			filename = cfg.Filename
+		}
+		if rule = MatchCategory(rules, cfg.URL, filename, probe.ContentType); rule != nil {
+			category = rule.Name
+		}
+	}
+
 	// Construct proper output path
-	destPath := cfg.OutputPath
+	outputPath := cfg.OutputPath
+	if rule != nil && cfg.DestPath == "" {
+		// Only fresh downloads are routed; resumed ones keep their destination
+		outputPath = categoryDir(rule, outputPath)
+		if cfg.Filename == "" {
+			probe.Filename = applyFilenameTemplate(rule.FilenameTemplate, probe.Filename, cfg.URL, rule.Name)
+		}
+		utils.Debug("Category %s: output %s, filename %s", rule.Name, outputPath, probe.Filename)
+	}
+	destPath := outputPath
 
 	// Auto-create output directory if it doesn't exist
-	if _, err := os.Stat(cfg.OutputPath); os.IsNotExist(err) {
-		if mkErr := os.MkdirAll(cfg.OutputPath, 0755); mkErr != nil {
+	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
+		if mkErr := os.MkdirAll(outputPath, 0755); mkErr != nil {
 			utils.Debug("Failed to create output directory: %v", mkErr)
 		}
 	}
 
-	if info, err := os.Stat(cfg.OutputPath); err == nil && info.IsDir() {
+	if info, err := os.Stat(outputPath); err == nil && info.IsDir() {
 		// Use cfg.Filename if TUI provided one, otherwise use probe.Filename
 		filename := probe.Filename
 		if cfg.Filename != "" {
 			filename = cfg.Filename
 		}
-		destPath = filepath.Join(cfg.OutputPath, filename)
+		destPath = filepath.Join(outputPath, filename)
 	}
 
-	// Check if this is a resume (explicitly marked by TUI)
-	var savedState *types.DownloadState
-	if cfg.IsResume && cfg.DestPath != "" {
-		// Resume: use the provided destination path for state lookup
-		savedState, _ = state.LoadState(cfg.URL, cfg.DestPath)
-	}
 	isResume := cfg.IsResume && savedState != nil && len(savedState.Tasks) > 0 && savedState.DestPath != ""
 
+	var resumed int64 // Bytes already downloaded by an earlier run
 	if isResume {
 		// Resume: use saved destination path directly (don't generate new unique name)
 		destPath = savedState.DestPath
+		resumed = savedState.Downloaded
 		utils.Debug("Resuming download, using saved destPath: %s", destPath)
+	} else if continued := continuableState(cfg, resolvedURL, destPath, probe); continued != nil {
+		// The downloader finds the saved state itself; the earlier run's entry is replaced by this one
+		resumed = continued.Downloaded
+		if continued.ID != "" && continued.ID != cfg.ID {
+			_ = state.RemoveFromMasterList(continued.ID)
+		}
+		utils.Debug("Continuing partial download at %s (%d bytes done)", destPath, resumed)
 	} else {
 		// Fresh download without TUI-provided filename: generate unique filename if file already exists
-		destPath = uniqueFilePath(destPath)
+		var release func()
+		destPath, release = reserveFilePath(destPath)
+		defer release()
 	}
 	finalFilename := filepath.Base(destPath)
 	utils.Debug("Destination path: %s", destPath)
 
+	// Only one process may write the partial file and its saved state at a time
+	lock, err := state.LockDownload(destPath)
+	if err != nil {
+		return types.NewError(types.KindLocked, err)
+	}
+	defer lock.Unlock()
+
 	// Send download started message
 	if cfg.ProgressCh != nil {
+		startedURL := cfg.URL
+		if !IsYoutubeURL(cfg.URL) {
+			startedURL = resolvedURL // May be a mirror
+		}
 		cfg.ProgressCh <- messages.DownloadStartedMsg{
 			DownloadID: cfg.ID,
-			URL:        cfg.URL,
+			URL:        startedURL,
 			Filename:   finalFilename,
 			Total:      probe.FileSize,
 			DestPath:   destPath,
+			Category:   category,
+			Resumed:    resumed,
 		}
 	}
 
 	// Update shared state
 	if cfg.State != nil {
 		cfg.State.SetTotalSize(probe.FileSize)
+		cfg.State.SetDestPath(destPath)
+		cfg.State.SetCategory(category)
 	}
 
 	// Choose downloader based on probe results
 	if probe.SupportsRange && probe.FileSize > 0 {
 		utils.Debug("Using concurrent downloader")
 		d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
-		return d.Download(ctx, resolvedURL, destPath, probe.FileSize, cfg.Verbose)
+		d.ETag = probe.ETag
+		d.Live = cfg.Live
+		d.Headers = cfg.Headers
+		d.Checksum = cfg.Checksum
+		d.Mirrors = mirrors
+		err = d.Download(ctx, resolvedURL, destPath, probe.FileSize, cfg.Verbose)
+	} else {
+		// Fallback to single-threaded downloader
+		utils.Debug("Using single-threaded downloader")
+		d := single.NewSingleDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.RuntimeConfig())
+		d.Headers = cfg.Headers
+		err = d.Download(ctx, resolvedURL, destPath, probe.FileSize, probe.Filename, cfg.Verbose)
+	}
+
+	if err == nil && checksum != nil && !(cfg.State != nil && cfg.State.IsPaused()) {
+		utils.Debug("Verifying %s checksum of %s", checksum.Algorithm, destPath)
+		err = checksum.Verify(destPath)
+	}
+	return err
+}
+
+// continuableState returns the saved state of a partial download of rawurl at destPath that
+// cfg may continue, or nil if there is none: the partial file must exist, no other process may be
+// writing it, and the server must still serve the same file.
+func continuableState(cfg types.DownloadConfig, rawurl, destPath string, probe *ProbeResult) *types.DownloadState {
+	if !cfg.Continue {
+		return nil
+	}
+	saved, err := state.LoadState(rawurl, destPath)
+	if err != nil || len(saved.Tasks) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(destPath + types.IncompleteSuffix); err != nil {
+		return nil
+	}
+	if state.DownloadOwner(destPath) != nil {
+		return nil
+	}
+	if err := checkReplacementCompatible(saved, probe); err != nil {
+		utils.Debug("Not continuing %s: %v", destPath, err)
+		return nil
 	}
+	return saved
+}
+
+// streamDownload writes the file to cfg.Output instead of saving it. Nothing is kept on disk,
+// so the download has no destination, is not filed under a category and can't be resumed.
+func streamDownload(ctx context.Context, cfg types.DownloadConfig, rawurl string, probe *ProbeResult, checksum *Checksum) error {
+	filename := probe.Filename
+	if cfg.Filename != "" {
+		filename = cfg.Filename
+	}
+	if cfg.ProgressCh != nil {
+		startedURL := cfg.URL
+		if !IsYoutubeURL(cfg.URL) {
+			startedURL = rawurl
+		}
+		cfg.ProgressCh <- messages.DownloadStartedMsg{
+			DownloadID: cfg.ID,
+			URL:        startedURL,
+			Filename:   filename,
+			Total:      probe.FileSize,
+		}
+	}
+	if cfg.State != nil {
+		cfg.State.SetTotalSize(probe.FileSize)
+	}
+
+	// The checksum is computed from the bytes as they are written
+	w := cfg.Output
+	var h hash.Hash
+	if checksum != nil {
+		h = checksum.newHash()
+		w = io.MultiWriter(w, h)
+	}
+
+	var err error
+	if probe.SupportsRange && probe.FileSize > 0 {
+		utils.Debug("Streaming with concurrent downloader")
+		d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
+		d.Live = cfg.Live
+		d.Headers = cfg.Headers
+		err = d.Stream(ctx, rawurl, w, probe.FileSize, cfg.Verbose)
+	} else {
+		utils.Debug("Streaming with single-threaded downloader")
+		d := single.NewSingleDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.RuntimeConfig())
+		d.Headers = cfg.Headers
+		err = d.Stream(ctx, rawurl, w)
+	}
+
+	if err == nil && h != nil {
+		err = checksum.match(h.Sum(nil))
+	}
+	return err
+}
+
+// ReplaceURL points a paused or failed download at a new URL while keeping its progress.
+// The new URL is probed and checked against the saved state (size, range support and
+// ETag when both are known) before the state file is migrated to the new URL.
+// If no state was saved (e.g. the download failed before pausing), the new URL is only probed.
+func ReplaceURL(ctx context.Context, oldURL, newURL, destPath string) (*ProbeResult, error) {
+	probe, err := probeServer(ctx, newURL, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("new URL is not reachable: %w", err)
+	}
+
+	savedState, err := state.LoadState(oldURL, destPath)
+	if err != nil {
+		utils.Debug("No saved state for %s, URL change will restart the download", destPath)
+		return probe, nil
+	}
+
+	if err := checkReplacementCompatible(savedState, probe); err != nil {
+		return nil, err
+	}
+
+	if err := state.MigrateState(oldURL, newURL, destPath); err != nil {
+		return nil, fmt.Errorf("failed to migrate state: %w", err)
+	}
+
+	utils.Debug("Download %s moved from %s to %s", savedState.ID, oldURL, newURL)
+	return probe, nil
+}
+
+// checkReplacementCompatible verifies that the probed URL serves the same file as the saved state
+func checkReplacementCompatible(saved *types.DownloadState, probe *ProbeResult) error {
+	if !probe.SupportsRange {
+		return fmt.Errorf("new URL does not support range requests, cannot resume")
+	}
+	if saved.TotalSize > 0 && probe.FileSize != saved.TotalSize {
+		return types.NewError(types.KindIntegrity, fmt.Errorf("size mismatch: saved %d bytes, new URL reports %d bytes", saved.TotalSize, probe.FileSize))
+	}
+	if saved.ETag != "" && probe.ETag != "" && saved.ETag != probe.ETag {
+		return types.NewError(types.KindIntegrity, fmt.Errorf("ETag mismatch: file on the new URL has changed"))
+	}
+	return nil
+}
 
-	// Fallback to single-threaded downloader
-	utils.Debug("Using single-threaded downloader")
-	d := single.NewSingleDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
-	return d.Download(ctx, resolvedURL, destPath, probe.FileSize, probe.Filename, cfg.Verbose)
+// Options are the choices made when adding a download, on top of its URL and output directory
+type Options struct {
+	Filename string            // Overrides the name detected from the server
+	Quality  string            // Video quality for YouTube downloads
+	Category string            // Category to file the download under (default: chosen by category rules)
+	Headers  map[string]string // Extra request headers
+	Checksum string            // Expected digest as "algo=hex"
+	Mirrors  []string          // Other URLs of the same file, tried in order when the first can't be reached
+	Output   io.Writer         // Writes the file here, e.g. to stdout, instead of saving it
+	Continue bool              // Picks up a partial download of the same URL at the destination
 }
 
 // Download is the CLI entry point (non-TUI) - convenience wrapper
-func Download(ctx context.Context, url, outPath, quality string, verbose bool, progressCh chan<- tea.Msg, id string) error {
+func Download(ctx context.Context, url, outPath string, opts Options, verbose bool, progressCh chan<- tea.Msg, id string, rc *types.RuntimeConfig) error {
 	cfg := types.DownloadConfig{
 		URL:        url,
 		OutputPath: outPath,
-		Quality:    quality,
+		Filename:   opts.Filename,
+		Quality:    opts.Quality,
+		Category:   opts.Category,
+		Headers:    opts.Headers,
+		Checksum:   opts.Checksum,
+		Mirrors:    opts.Mirrors,
+		Output:     opts.Output,
+		Continue:   opts.Continue,
 		ID:         id,
 		Verbose:    verbose,
 		ProgressCh: progressCh,
 		State:      nil,
+		Runtime:    rc,
 	}
 	return TUIDownload(ctx, cfg)
 }
@@ -1,9 +1,23 @@
 package download
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download/state"
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/testutil"
 )
 
 func TestUniqueFilePath(t *testing.T) {
@@ -86,3 +100,241 @@ func TestUniqueFilePath(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceURL(t *testing.T) {
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	const fileSize = int64(64 * 1024)
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(fileSize),
+		testutil.WithRangeSupport(true),
+	)
+	defer server.Close()
+
+	oldURL := "https://expired.example.com/replace-test.bin"
+	newURL := server.URL() + "/replace-test.bin"
+	destPath := filepath.Join(os.TempDir(), "replace-test.bin")
+
+	saved := &types.DownloadState{
+		ID:         "replace-test",
+		URL:        oldURL,
+		DestPath:   destPath,
+		TotalSize:  fileSize,
+		Downloaded: fileSize / 2,
+		Tasks:      []types.Task{{Offset: fileSize / 2, Length: fileSize / 2}},
+		Filename:   "replace-test.bin",
+	}
+	if err := state.SaveState(oldURL, destPath, saved); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	defer state.DeleteState(saved.ID, newURL, destPath)
+
+	if _, err := ReplaceURL(context.Background(), oldURL, newURL, destPath); err != nil {
+		t.Fatalf("ReplaceURL failed: %v", err)
+	}
+
+	migrated, err := state.LoadState(newURL, destPath)
+	if err != nil {
+		t.Fatalf("State was not migrated to new URL: %v", err)
+	}
+	if migrated.Downloaded != saved.Downloaded || len(migrated.Tasks) != 1 {
+		t.Errorf("Migrated state lost progress: downloaded=%d tasks=%d", migrated.Downloaded, len(migrated.Tasks))
+	}
+	if _, err := state.LoadState(oldURL, destPath); err == nil {
+		t.Error("Old state file should be removed after migration")
+	}
+}
+
+func TestReplaceURL_SizeMismatch(t *testing.T) {
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(1024),
+		testutil.WithRangeSupport(true),
+	)
+	defer server.Close()
+
+	oldURL := "https://expired.example.com/mismatch-test.bin"
+	newURL := server.URL() + "/mismatch-test.bin"
+	destPath := filepath.Join(os.TempDir(), "mismatch-test.bin")
+
+	saved := &types.DownloadState{
+		ID:        "mismatch-test",
+		URL:       oldURL,
+		DestPath:  destPath,
+		TotalSize: 4096,
+		Tasks:     []types.Task{{Offset: 0, Length: 4096}},
+		Filename:  "mismatch-test.bin",
+	}
+	if err := state.SaveState(oldURL, destPath, saved); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	defer state.DeleteState(saved.ID, oldURL, destPath)
+
+	if _, err := ReplaceURL(context.Background(), oldURL, newURL, destPath); err == nil {
+		t.Fatal("Expected size mismatch error")
+	}
+	if _, err := state.LoadState(oldURL, destPath); err != nil {
+		t.Errorf("Original state should be kept on rejected replacement: %v", err)
+	}
+}
+
+func TestCheckReplacementCompatible_ETag(t *testing.T) {
+	saved := &types.DownloadState{TotalSize: 100, ETag: `"abc"`}
+
+	if err := checkReplacementCompatible(saved, &ProbeResult{FileSize: 100, SupportsRange: true, ETag: `"abc"`}); err != nil {
+		t.Errorf("Matching ETag rejected: %v", err)
+	}
+	if err := checkReplacementCompatible(saved, &ProbeResult{FileSize: 100, SupportsRange: true}); err != nil {
+		t.Errorf("Missing ETag on new URL should be accepted: %v", err)
+	}
+	if err := checkReplacementCompatible(saved, &ProbeResult{FileSize: 100, SupportsRange: true, ETag: `"def"`}); err == nil {
+		t.Error("Different ETag should be rejected")
+	}
+	if err := checkReplacementCompatible(saved, &ProbeResult{FileSize: 100}); err == nil {
+		t.Error("New URL without range support should be rejected")
+	}
+}
+
+func TestTUIDownload_LockedElsewhere(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(64*1024),
+		testutil.WithRangeSupport(true),
+	)
+	defer server.Close()
+
+	dir := t.TempDir()
+	destPath := filepath.Join(dir, "locked.bin")
+	lock, err := state.LockDownload(destPath)
+	if err != nil {
+		t.Fatalf("LockDownload failed: %v", err)
+	}
+	defer lock.Unlock()
+
+	err = TUIDownload(context.Background(), types.DownloadConfig{
+		URL:        server.URL() + "/locked.bin",
+		OutputPath: dir,
+		ID:         "locked",
+		Filename:   "locked.bin",
+		State:      types.NewProgressState("locked", 0),
+	})
+	if kind := types.Classify(err); kind != types.KindLocked {
+		t.Fatalf("TUIDownload error = %v (%s), want a %s error", err, kind, types.KindLocked)
+	}
+	if _, err := os.Stat(destPath + types.IncompleteSuffix); !os.IsNotExist(err) {
+		t.Errorf("Locked download touched the partial file: %v", err)
+	}
+}
+
+func TestTUIDownload_HeadersAndChecksum(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	content := bytes.Repeat([]byte("pulse"), 20000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	download := func(id, checksum string) error {
+		return TUIDownload(context.Background(), types.DownloadConfig{
+			URL:        server.URL + "/file.bin",
+			OutputPath: t.TempDir(),
+			ID:         id,
+			Filename:   "file.bin",
+			Headers:    map[string]string{"Authorization": "Bearer secret"},
+			Checksum:   checksum,
+			State:      types.NewProgressState(id, 0),
+		})
+	}
+
+	if err := download("good", fmt.Sprintf("sha256=%x", sha256.Sum256(content))); err != nil {
+		t.Fatalf("Download with headers and matching checksum failed: %v", err)
+	}
+	err := download("bad", fmt.Sprintf("sha256=%x", sha256.Sum256([]byte("other"))))
+	if kind := types.Classify(err); kind != types.KindIntegrity {
+		t.Errorf("Download with wrong checksum = %v (%s), want an %s error", err, kind, types.KindIntegrity)
+	}
+}
+
+func TestTUIDownload_Mirrors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	content := bytes.Repeat([]byte("mirror"), 20000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mirror/file.bin" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	events := make(chan tea.Msg, 100)
+	out := t.TempDir()
+	err := TUIDownload(context.Background(), types.DownloadConfig{
+		URL:        server.URL + "/gone/file.bin",
+		Mirrors:    []string{server.URL + "/also-gone/file.bin", server.URL + "/mirror/file.bin"},
+		OutputPath: out,
+		ID:         "mirrored",
+		ProgressCh: events,
+		State:      types.NewProgressState("mirrored", 0),
+	})
+	if err != nil {
+		t.Fatalf("Download with a working mirror failed: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(out, "file.bin"))
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Downloaded file differs from the mirror's (err %v)", err)
+	}
+	for len(events) > 0 {
+		if started, ok := (<-events).(messages.DownloadStartedMsg); ok && started.URL != server.URL+"/mirror/file.bin" {
+			t.Errorf("Started message reports %s, want the mirror in use", started.URL)
+		}
+	}
+}
+
+func TestTUIDownload_Output(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	content := bytes.Repeat([]byte("stream"), 100000)
+	for _, ranges := range []bool{true, false} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !ranges {
+				w.Write(content)
+				return
+			}
+			http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+		}))
+
+		out := t.TempDir()
+		var buf bytes.Buffer
+		err := TUIDownload(context.Background(), types.DownloadConfig{
+			URL:        server.URL + "/file.bin",
+			OutputPath: out,
+			ID:         "streamed",
+			Checksum:   fmt.Sprintf("sha256=%x", sha256.Sum256(content)),
+			Output:     &buf,
+			State:      types.NewProgressState("streamed", 0),
+		})
+		server.Close()
+		if err != nil {
+			t.Fatalf("Streamed download (ranges %v) failed: %v", ranges, err)
+		}
+		if !bytes.Equal(buf.Bytes(), content) {
+			t.Errorf("Streamed download (ranges %v) wrote different bytes", ranges)
+		}
+		if entries, _ := os.ReadDir(out); len(entries) > 0 {
+			t.Errorf("Streamed download (ranges %v) left %d files in the output directory", ranges, len(entries))
+		}
+	}
+}
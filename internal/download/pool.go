@@ -1,63 +1,595 @@
 package download
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pulse-downloader/pulse/internal/download/state"
 	"github.com/pulse-downloader/pulse/internal/download/types"
 	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/utils"
 )
 
 // activeDownload tracks a download that's currently running
 type activeDownload struct {
-	config types.DownloadConfig
-	cancel context.CancelFunc
+	config    types.DownloadConfig
+	cancel    context.CancelFunc
+	startedAt time.Time
+	listed    bool                // Listed in the master list as downloading (see saveRunning)
+	settled   bool                // Finished; its master list entry is up to the downloader and the outcome
+	lock      *state.DownloadLock // Held while listed, so other processes don't recover the entry
+	removed   bool                // Cancelled by Remove, which deletes everything the run saved
 }
 
+// MoveOp is a reordering operation on a queued download
+type MoveOp string
+
+const (
+	MoveUp     MoveOp = "up"
+	MoveDown   MoveOp = "down"
+	MoveTop    MoveOp = "top"
+	MoveBottom MoveOp = "bottom"
+)
+
 type WorkerPool struct {
-	taskChan     chan types.DownloadConfig
+	queue        []types.DownloadConfig // Pending downloads, highest priority first
 	progressCh   chan<- tea.Msg
 	downloads    map[string]*activeDownload // Track active downloads for pause/resume
 	mu           sync.RWMutex
 	wg           sync.WaitGroup //We use this to wait for all active downloads to pause before exiting the program
 	maxDownloads int
+	running      int  // Downloads currently holding a slot (may exceed maxDownloads after StartNow)
+	shuttingDown bool // Set by GracefulShutdown; nothing new is started afterwards
+	persistQueue bool // Mirror the queue into the master list (see EnableQueuePersistence)
+	persistMu    sync.Mutex
+	failed       []FailedDownload         // Downloads that stopped with an error, oldest first
+	retries      map[string]*pendingRetry // Failed downloads waiting for an automatic retry
 }
 
+// pendingRetry is a failed download that will be re-queued when its timer fires
+type pendingRetry struct {
+	config types.DownloadConfig
+	timer  *time.Timer
+}
+
+// FailedDownload describes a download that stopped with an error
+type FailedDownload struct {
+	ID       string
+	URL      string
+	Filename string
+	Kind     types.ErrorKind
+	Error    string
+	Time     time.Time
+
+	config types.DownloadConfig // Used by Retry
+}
+
+// maxFailedDownloads bounds the failure list kept by the pool
+const maxFailedDownloads = 100
+
 func NewWorkerPool(progressCh chan<- tea.Msg, maxDownloads int) *WorkerPool {
 	if maxDownloads < 1 {
 		maxDownloads = 3 // Default to 3 if invalid
 	}
-	pool := &WorkerPool{
-		taskChan:     make(chan types.DownloadConfig, 100), //We make it buffered to avoid blocking add
+	return &WorkerPool{
 		progressCh:   progressCh,
 		downloads:    make(map[string]*activeDownload),
+		retries:      make(map[string]*pendingRetry),
 		maxDownloads: maxDownloads,
 	}
-	for i := 0; i < maxDownloads; i++ {
-		go pool.worker()
-	}
-	return pool
 }
 
+// Add queues a download behind every pending download of the same or higher priority.
+// It never blocks; the queue has no length limit.
 func (p *WorkerPool) Add(cfg types.DownloadConfig) {
-	p.taskChan <- cfg
+	p.mu.Lock()
+	p.insertLocked(cfg)
+	p.mu.Unlock()
+	p.schedule()
+	p.saveQueue()
+}
+
+// insertLocked places cfg at the end of its priority band
+func (p *WorkerPool) insertLocked(cfg types.DownloadConfig) {
+	i := len(p.queue)
+	for i > 0 && p.queue[i-1].Priority < cfg.Priority {
+		i--
+	}
+	p.queue = slices.Insert(p.queue, i, cfg)
+}
+
+// indexLocked returns the queue position of a download, or -1
+func (p *WorkerPool) indexLocked(downloadID string) int {
+	return slices.IndexFunc(p.queue, func(c types.DownloadConfig) bool { return c.ID == downloadID })
+}
+
+// Failed returns the downloads that stopped with an error, oldest first
+func (p *WorkerPool) Failed() []FailedDownload {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.failed)
+}
+
+// DownloadInfo is a snapshot of a download for listing it outside the TUI
+type DownloadInfo struct {
+	ID          string  `json:"id"`
+	URL         string  `json:"url"`
+	Filename    string  `json:"filename"`
+	DestPath    string  `json:"dest_path,omitempty"`
+	Status      string  `json:"status"` // "downloading", "paused", "queued", "retrying", "error", "completed" or "elsewhere"
+	Downloaded  int64   `json:"downloaded"`
+	Total       int64   `json:"total"`
+	Speed       float64 `json:"speed"` // Bytes/s over the current session
+	Connections int32   `json:"connections,omitempty"`
+	Priority    string  `json:"priority,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// Downloads lists every download the pool knows about: running and paused ones first, in the
+// order they started, then the queue in order, pending retries and failures. Downloads only
+// recorded in the master list, such as ones paused in an earlier session, come last.
+func (p *WorkerPool) Downloads() []DownloadInfo {
+	var infos []DownloadInfo
+	seen := make(map[string]bool)
+	add := func(info DownloadInfo) {
+		if !seen[info.ID] {
+			seen[info.ID] = true
+			infos = append(infos, info)
+		}
+	}
+	fromConfig := func(cfg types.DownloadConfig, status string) DownloadInfo {
+		info := DownloadInfo{
+			ID:       cfg.ID,
+			URL:      cfg.URL,
+			Filename: cfg.Filename,
+			DestPath: cfg.DestPath,
+			Status:   status,
+			Priority: cfg.Priority.String(),
+		}
+		if cfg.State == nil {
+			return info
+		}
+		downloaded, total, elapsed, connections, sessionStart := cfg.State.GetProgress()
+		info.Downloaded, info.Total = downloaded, total
+		if dest := cfg.State.DestPath(); dest != "" {
+			info.DestPath = dest
+			info.Filename = filepath.Base(dest)
+		}
+		if status == "downloading" {
+			info.Connections = connections
+			if secs := elapsed.Seconds(); secs > 0 && downloaded > sessionStart {
+				info.Speed = float64(downloaded-sessionStart) / secs
+			}
+		}
+		if err := cfg.State.GetError(); err != nil {
+			info.Error = err.Error()
+		}
+		return info
+	}
+
+	p.mu.RLock()
+	active := make([]*activeDownload, 0, len(p.downloads))
+	for _, ad := range p.downloads {
+		if ad != nil {
+			active = append(active, ad)
+		}
+	}
+	slices.SortStableFunc(active, func(a, b *activeDownload) int { return a.startedAt.Compare(b.startedAt) })
+	for _, ad := range active {
+		status := "downloading"
+		if ad.config.State != nil && ad.config.State.IsPaused() {
+			status = "paused"
+		}
+		add(fromConfig(ad.config, status))
+	}
+	for _, cfg := range p.queue {
+		add(fromConfig(cfg, "queued"))
+	}
+	retrying := make([]types.DownloadConfig, 0, len(p.retries))
+	for _, r := range p.retries {
+		retrying = append(retrying, r.config)
+	}
+	slices.SortFunc(retrying, func(a, b types.DownloadConfig) int { return cmp.Compare(a.ID, b.ID) })
+	for _, cfg := range retrying {
+		add(fromConfig(cfg, "retrying"))
+	}
+	for _, f := range p.failed {
+		info := fromConfig(f.config, "error")
+		info.ID, info.URL, info.Error = f.ID, f.URL, f.Error
+		if info.Filename == "" {
+			info.Filename = f.Filename
+		}
+		add(info)
+	}
+	p.mu.RUnlock()
+
+	entries, err := state.LoadMasterList()
+	if err != nil {
+		utils.Debug("Failed to load master list: %v", err)
+		return infos
+	}
+	for _, e := range entries.Downloads {
+		info := DownloadInfo{
+			ID:         e.ID,
+			URL:        e.URL,
+			Filename:   e.Filename,
+			DestPath:   e.DestPath,
+			Status:     e.Status,
+			Downloaded: e.Downloaded,
+			Total:      e.TotalSize,
+			Priority:   e.Priority.String(),
+			Error:      e.Error,
+		}
+		switch e.Status {
+		case "completed":
+			info.Downloaded = e.TotalSize
+		case "paused":
+			if s, err := state.LoadState(e.URL, e.DestPath); err == nil {
+				info.Downloaded, info.Total = s.Downloaded, s.TotalSize
+			}
+			if state.DownloadOwner(e.DestPath) != nil {
+				info.Status = "elsewhere"
+			}
+		}
+		add(info)
+	}
+	return infos
+}
+
+// Queued returns the pending downloads in the order they will start
+func (p *WorkerPool) Queued() []types.DownloadConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.queue)
+}
+
+// Move reorders a queued download. Moving past a download of a different priority
+// takes on that priority, so the queue always stays ordered by priority.
+func (p *WorkerPool) Move(downloadID string, op MoveOp) error {
+	p.mu.Lock()
+	i := p.indexLocked(downloadID)
+	if i < 0 {
+		p.mu.Unlock()
+		return fmt.Errorf("download %s is not queued", downloadID)
+	}
+
+	cfg := p.queue[i]
+	switch op {
+	case MoveUp:
+		if i > 0 {
+			cfg.Priority = max(cfg.Priority, p.queue[i-1].Priority)
+			p.queue[i], p.queue[i-1] = p.queue[i-1], cfg
+		}
+	case MoveDown:
+		if i < len(p.queue)-1 {
+			cfg.Priority = min(cfg.Priority, p.queue[i+1].Priority)
+			p.queue[i], p.queue[i+1] = p.queue[i+1], cfg
+		}
+	case MoveTop:
+		cfg.Priority = max(cfg.Priority, p.queue[0].Priority)
+		p.queue = slices.Insert(slices.Delete(p.queue, i, i+1), 0, cfg)
+	case MoveBottom:
+		cfg.Priority = min(cfg.Priority, p.queue[len(p.queue)-1].Priority)
+		p.queue = append(slices.Delete(p.queue, i, i+1), cfg)
+	default:
+		p.mu.Unlock()
+		return fmt.Errorf("unknown move %q", op)
+	}
+	p.mu.Unlock()
+
+	p.saveQueue()
+	p.notifyQueueChanged()
+	return nil
+}
+
+// SetPriority changes the priority of a queued download, moving it to the end of its new band
+func (p *WorkerPool) SetPriority(downloadID string, priority types.Priority) error {
+	p.mu.Lock()
+	i := p.indexLocked(downloadID)
+	if i < 0 {
+		p.mu.Unlock()
+		return fmt.Errorf("download %s is not queued", downloadID)
+	}
+	cfg := p.queue[i]
+	p.queue = slices.Delete(p.queue, i, i+1)
+	cfg.Priority = priority
+	p.insertLocked(cfg)
+	p.mu.Unlock()
+
+	p.saveQueue()
+	p.notifyQueueChanged()
+	return nil
+}
+
+// StartNow starts a queued download immediately, even if all slots are busy
+func (p *WorkerPool) StartNow(downloadID string) error {
+	p.mu.Lock()
+	i := p.indexLocked(downloadID)
+	if i < 0 {
+		p.mu.Unlock()
+		return fmt.Errorf("download %s is not queued", downloadID)
+	}
+	cfg := p.queue[i]
+	if p.shuttingDown {
+		p.mu.Unlock()
+		return fmt.Errorf("shutting down")
+	}
+	p.queue = slices.Delete(p.queue, i, i+1)
+	p.running++
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	go p.run(cfg)
+	p.saveQueue()
+	p.notifyQueueChanged()
+	return nil
+}
+
+// schedule starts queued downloads while there are free slots and returns how many it started
+func (p *WorkerPool) schedule() int {
+	p.mu.Lock()
+	var start []types.DownloadConfig
+	for !p.shuttingDown && p.running < p.maxDownloads && len(p.queue) > 0 {
+		start = append(start, p.queue[0])
+		p.queue = p.queue[1:]
+		p.running++
+		p.wg.Add(1)
+	}
+	p.mu.Unlock()
+
+	for _, cfg := range start {
+		go p.run(cfg)
+	}
+	return len(start)
+}
+
+// EnableQueuePersistence mirrors the queue into the master list as "queued" entries from now on,
+// starting with the current queue. Call it after re-adding the entries restored with RestoredConfig
+// so a half-restored queue never overwrites the saved one.
+func (p *WorkerPool) EnableQueuePersistence() {
+	p.persistMu.Lock()
+	p.mu.Lock()
+	p.persistQueue = true
+	// Restored downloads that have started already are listed like later ones
+	var running []*activeDownload
+	for _, ad := range p.downloads {
+		if ad.listed || ad.settled || ad.config.IsResume || (ad.config.State != nil && ad.config.State.IsPaused()) {
+			continue
+		}
+		ad.listed = true
+		running = append(running, ad)
+	}
+	p.mu.Unlock()
+	for _, ad := range running {
+		p.saveRunning(ad)
+	}
+	p.persistMu.Unlock()
+	p.saveQueue()
+}
+
+// saveQueue writes the not yet started downloads to the master list.
+// Resumed downloads are skipped; they keep their "paused" entry until they run.
+func (p *WorkerPool) saveQueue() {
+	p.persistMu.Lock()
+	defer p.persistMu.Unlock()
+
+	// Snapshot under persistMu so the last write always reflects the latest queue
+	p.mu.RLock()
+	if !p.persistQueue {
+		p.mu.RUnlock()
+		return
+	}
+	entries := make([]types.DownloadEntry, 0, len(p.queue))
+	for _, cfg := range p.queue {
+		if cfg.IsResume {
+			continue
+		}
+		entries = append(entries, types.DownloadEntry{
+			ID:         cfg.ID,
+			URLHash:    state.URLHash(cfg.URL),
+			URL:        cfg.URL,
+			Filename:   cfg.Filename,
+			Category:   cfg.Category,
+			OutputPath: cfg.OutputPath,
+			Quality:    cfg.Quality,
+			Priority:   cfg.Priority,
+			Headers:    cfg.Headers,
+			Checksum:   cfg.Checksum,
+			Mirrors:    cfg.Mirrors,
+		})
+	}
+	p.mu.RUnlock()
+
+	if err := state.SaveQueue(entries); err != nil {
+		utils.Debug("Failed to save queue: %v", err)
+	}
+}
+
+// saveRunning lists a download that has started in the master list until it saves its own
+// state, so a crash before its first checkpoint doesn't lose it. The download's ID lock is held
+// until it settles so other processes don't recover the entry meanwhile. Callers hold
+// persistMu, so a queue snapshot taken while the download was still queued can't overwrite it.
+func (p *WorkerPool) saveRunning(ad *activeDownload) {
+	cfg := ad.config
+	p.mu.RLock()
+	removed := ad.removed
+	p.mu.RUnlock()
+	if removed {
+		return
+	}
+	lock, err := state.LockDownloadID(cfg.ID)
+	if err != nil {
+		utils.Debug("Not listing running download %s: %v", cfg.ID, err)
+		p.mu.Lock()
+		ad.listed = false
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Lock()
+	ad.lock = lock
+	p.mu.Unlock()
+
+	err = state.MarkDownloading(types.DownloadEntry{
+		ID:         cfg.ID,
+		URLHash:    state.URLHash(cfg.URL),
+		URL:        cfg.URL,
+		Filename:   cfg.Filename,
+		Category:   cfg.Category,
+		OutputPath: cfg.OutputPath,
+		Quality:    cfg.Quality,
+		Priority:   cfg.Priority,
+		Headers:    cfg.Headers,
+		Checksum:   cfg.Checksum,
+		Mirrors:    cfg.Mirrors,
+	})
+	if err != nil {
+		utils.Debug("Failed to save running download: %v", err)
+	}
+}
+
+// unlistRunning removes the entry saveRunning added for a download that finished. A completed
+// download also drops one left by an earlier run of it, e.g. one paused before it saved any state.
+func (p *WorkerPool) unlistRunning(ad *activeDownload, completed bool) {
+	p.persistMu.Lock()
+	defer p.persistMu.Unlock()
+	p.mu.Lock()
+	listed := ad.listed || (completed && p.persistQueue)
+	ad.listed, ad.settled = false, true
+	p.mu.Unlock()
+	if listed {
+		_ = state.RemoveFromMasterList(ad.config.ID)
+	}
+}
+
+// settle marks a download whose run has ended, once its master list entry is final, and
+// releases the ID lock saveRunning took
+func (p *WorkerPool) settle(ad *activeDownload) {
+	p.persistMu.Lock()
+	defer p.persistMu.Unlock()
+	p.mu.Lock()
+	ad.settled = true
+	lock := ad.lock
+	ad.lock = nil
+	p.mu.Unlock()
+	if lock != nil {
+		lock.Unlock()
+	}
+}
+
+// RestoredConfig rebuilds the download config of a queued master list entry.
+// The caller supplies ProgressCh, State and Runtime as for a new download.
+func RestoredConfig(entry types.DownloadEntry) types.DownloadConfig {
+	return types.DownloadConfig{
+		URL:        entry.URL,
+		OutputPath: entry.OutputPath,
+		ID:         entry.ID,
+		Filename:   entry.Filename,
+		Quality:    entry.Quality,
+		Category:   entry.Category,
+		Priority:   entry.Priority,
+		Headers:    entry.Headers,
+		Checksum:   entry.Checksum,
+		Mirrors:    entry.Mirrors,
+	}
+}
+
+// ResumedConfig rebuilds the download config of a paused master list entry so it continues
+// from its saved state. The caller supplies ProgressCh and Runtime as for a new download.
+func ResumedConfig(entry types.DownloadEntry) types.DownloadConfig {
+	cfg := RestoredConfig(entry)
+	cfg.DestPath = entry.DestPath
+	cfg.IsResume = true
+	cfg.State = types.NewProgressState(entry.ID, entry.TotalSize)
+	cfg.State.SetDestPath(entry.DestPath)
+	return cfg
+}
+
+// MaxDownloads returns the current concurrency limit
+func (p *WorkerPool) MaxDownloads() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.maxDownloads
+}
+
+// SetMaxDownloads changes the concurrency limit while running. Raising it starts queued
+// downloads straight away. Lowering it lets the extra downloads finish, unless pauseExtra
+// is set, in which case the lowest priority (then most recently started) ones are paused.
+// It returns the IDs of the paused downloads.
+func (p *WorkerPool) SetMaxDownloads(n int, pauseExtra bool) ([]string, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("max concurrent downloads must be at least 1, got %d", n)
+	}
+
+	p.mu.Lock()
+	p.maxDownloads = n
+	var active []*activeDownload
+	for _, ad := range p.downloads {
+		if ad != nil && ad.config.State != nil && !ad.config.State.IsPaused() && !ad.config.State.Done.Load() {
+			active = append(active, ad)
+		}
+	}
+	p.mu.Unlock()
+
+	if p.schedule() > 0 {
+		p.saveQueue()
+		p.notifyQueueChanged()
+	}
+
+	extra := len(active) - n
+	if !pauseExtra || extra <= 0 {
+		return nil, nil
+	}
+
+	slices.SortFunc(active, func(a, b *activeDownload) int {
+		if c := cmp.Compare(a.config.Priority, b.config.Priority); c != 0 {
+			return c
+		}
+		return b.startedAt.Compare(a.startedAt)
+	})
+	paused := make([]string, 0, extra)
+	for _, ad := range active[:extra] {
+		p.Pause(ad.config.ID)
+		paused = append(paused, ad.config.ID)
+	}
+	return paused, nil
+}
+
+func (p *WorkerPool) notifyQueueChanged() {
+	if p.progressCh != nil {
+		p.progressCh <- messages.QueueChangedMsg{}
+	}
 }
 
 // Pause pauses a specific download by ID
-func (p *WorkerPool) Pause(downloadID string) {
+func (p *WorkerPool) Pause(downloadID string) error {
 	p.mu.RLock()
 	ad, exists := p.downloads[downloadID]
+	queued := p.indexLocked(downloadID) >= 0
 	p.mu.RUnlock()
 
 	if !exists || ad == nil {
-		return
+		if queued {
+			return fmt.Errorf("download %s has not started yet", downloadID)
+		}
+		return fmt.Errorf("no running download with ID %s", downloadID)
 	}
 
 	// Set paused flag and cancel context
 	if ad.config.State != nil {
 		ad.config.State.Pause()
 	}
+	// The downloader only installs its own cancel func once it starts transferring;
+	// cancelling ours also stops a download that is still probing the server
+	if ad.cancel != nil {
+		ad.cancel()
+	}
 
 	// Send pause message
 	if p.progressCh != nil {
@@ -67,9 +599,11 @@ func (p *WorkerPool) Pause(downloadID string) {
 		}
 		p.progressCh <- messages.DownloadPausedMsg{
 			DownloadID: downloadID,
+			URL:        ad.config.URL,
 			Downloaded: downloaded,
 		}
 	}
+	return nil
 }
 
 // PauseAll pauses all active downloads (for graceful shutdown)
@@ -92,12 +626,29 @@ func (p *WorkerPool) PauseAll() {
 // Cancel cancels and removes a download by ID
 func (p *WorkerPool) Cancel(downloadID string) {
 	p.mu.Lock()
+	i := p.indexLocked(downloadID)
+	if i >= 0 {
+		// Not started yet, dropping it from the queue is enough
+		p.queue = slices.Delete(p.queue, i, i+1)
+	}
 	ad, exists := p.downloads[downloadID]
 	if exists {
 		delete(p.downloads, downloadID)
+		if ad != nil {
+			ad.removed = true // Before cancelling, so run saves nothing once it returns
+		}
+	}
+	if r, ok := p.retries[downloadID]; ok {
+		r.timer.Stop()
+		delete(p.retries, downloadID)
 	}
+	p.removeFailedLocked(downloadID)
 	p.mu.Unlock()
 
+	if i >= 0 {
+		p.saveQueue()
+	}
+
 	if !exists || ad == nil {
 		return
 	}
@@ -113,23 +664,40 @@ func (p *WorkerPool) Cancel(downloadID string) {
 	}
 }
 
-// Resume resumes a paused download by ID
-func (p *WorkerPool) Resume(downloadID string) {
+// Resume resumes a paused download by ID. Downloads paused in a previous session are looked
+// up in the master list.
+func (p *WorkerPool) Resume(downloadID string) error {
 	p.mu.RLock()
 	ad, exists := p.downloads[downloadID]
 	p.mu.RUnlock()
 
-	if !exists || ad == nil {
-		return
+	var cfg types.DownloadConfig
+	if exists && ad != nil {
+		cfg = resumeConfig(ad.config)
+	} else {
+		entry, err := state.GetDownloadEntry(downloadID)
+		if err != nil {
+			return err
+		}
+		if entry == nil || entry.Status != "paused" {
+			return fmt.Errorf("no paused download with ID %s", downloadID)
+		}
+		cfg = ResumedConfig(*entry)
+		cfg.ProgressCh = p.progressCh
+	}
+	if cfg.DestPath != "" {
+		if owner := state.DownloadOwner(cfg.DestPath); owner != nil {
+			return fmt.Errorf("download %s is running in pid %d on %s", downloadID, owner.PID, owner.Host)
+		}
 	}
 
 	// Clear paused flag
-	if ad.config.State != nil {
-		ad.config.State.Resume()
+	if cfg.State != nil {
+		cfg.State.Resume()
 	}
 
 	// Re-queue the download
-	p.Add(ad.config)
+	p.Add(cfg)
 
 	// Send resume message
 	if p.progressCh != nil {
@@ -137,59 +705,504 @@ func (p *WorkerPool) Resume(downloadID string) {
 			DownloadID: downloadID,
 		}
 	}
+	return nil
 }
 
-func (p *WorkerPool) worker() {
-	for cfg := range p.taskChan {
-		p.wg.Add(1)
-		// Create cancellable context
-		ctx, cancel := context.WithCancel(context.Background())
+// ChangeURL replaces the URL of a paused or failed download and resumes it from its saved
+// state. Downloads paused in a previous session are looked up in the master list.
+func (p *WorkerPool) ChangeURL(downloadID, newURL string) error {
+	p.mu.RLock()
+	ad, exists := p.downloads[downloadID]
+	fi := slices.IndexFunc(p.failed, func(f FailedDownload) bool { return f.ID == downloadID })
+	var failed FailedDownload
+	if fi >= 0 {
+		failed = p.failed[fi]
+	}
+	p.mu.RUnlock()
 
-		// Register active download
-		ad := &activeDownload{
-			config: cfg,
-			cancel: cancel,
+	entry, err := state.GetDownloadEntry(downloadID)
+	if err != nil {
+		return err
+	}
+
+	var cfg types.DownloadConfig
+	if exists && ad != nil {
+		if ad.config.State == nil || !ad.config.State.IsPaused() {
+			return fmt.Errorf("download %s is still running, pause it first", downloadID)
+		}
+		cfg = ad.config
+	} else if fi >= 0 {
+		// The failed download keeps its progress state, so its listing follows the new attempt
+		cfg = resumeConfig(failed.config)
+		cfg.Attempt = 0
+		if cfg.State == nil {
+			cfg.State = types.NewProgressState(downloadID, 0)
 		}
+		cfg.State.Pause()
+	} else {
+		if entry == nil || (entry.Status != "paused" && entry.Status != "error") {
+			return fmt.Errorf("no paused or failed download with ID %s", downloadID)
+		}
+		cfg = types.DownloadConfig{
+			URL:        entry.URL,
+			OutputPath: filepath.Dir(entry.DestPath),
+			ID:         entry.ID,
+			Filename:   entry.Filename,
+			ProgressCh: p.progressCh,
+			State:      types.NewProgressState(entry.ID, 0),
+		}
+		cfg.State.Pause()
+	}
+
+	// The destination is only known once the download has started (set via the master list on pause)
+	destPath := cfg.DestPath
+	if destPath == "" && entry != nil {
+		destPath = entry.DestPath
+	}
+	if destPath == "" {
+		return fmt.Errorf("download %s has no saved destination", downloadID)
+	}
+
+	if _, err := ReplaceURL(context.Background(), cfg.URL, newURL, destPath); err != nil {
+		return err
+	}
+
+	cfg.URL = newURL
+	cfg.DestPath = destPath
+	cfg.IsResume = true
+	if cfg.State != nil {
+		cfg.State.SetDestPath(destPath)
+		cfg.State.ClearError()
+	}
+	p.mu.Lock()
+	p.downloads[downloadID] = &activeDownload{config: cfg}
+	p.removeFailedLocked(downloadID)
+	p.mu.Unlock()
+
+	return p.Resume(downloadID)
+}
+
+// Remove cancels a download and deletes its saved state, its partial file and its master
+// list entry. A completed file is left in place.
+func (p *WorkerPool) Remove(downloadID string) error {
+	var url, destPath string
+	tracked := true
+	p.mu.RLock()
+	if ad, ok := p.downloads[downloadID]; ok && ad != nil {
+		url, destPath = ad.config.URL, ad.config.DestPath
+		if ad.config.State != nil && ad.config.State.DestPath() != "" {
+			destPath = ad.config.State.DestPath()
+		}
+	} else if i := p.indexLocked(downloadID); i >= 0 {
+		url = p.queue[i].URL
+	} else if r, ok := p.retries[downloadID]; ok {
+		url, destPath = r.config.URL, r.config.DestPath
+	} else if i := slices.IndexFunc(p.failed, func(f FailedDownload) bool { return f.ID == downloadID }); i >= 0 {
+		url = p.failed[i].URL
+		if p.failed[i].config.State != nil {
+			destPath = p.failed[i].config.State.DestPath()
+		}
+	} else {
+		tracked = false
+	}
+	p.mu.RUnlock()
+
+	entry, err := state.GetDownloadEntry(downloadID)
+	if err != nil {
+		return err
+	}
+	if !tracked && entry == nil {
+		return fmt.Errorf("no download with ID %s", downloadID)
+	}
+	completed := false
+	if entry != nil {
+		if url == "" {
+			url = entry.URL
+		}
+		if destPath == "" {
+			destPath = entry.DestPath
+		}
+		completed = entry.Status == "completed"
+		if entry.Status == "paused" && state.DownloadOwner(entry.DestPath) != nil {
+			return fmt.Errorf("download %s is running in another pulse process", downloadID)
+		}
+	}
+
+	p.Cancel(downloadID)
+
+	if url != "" && destPath != "" {
+		_ = state.DeleteStateByURL(downloadID, url, destPath)
+	}
+	if !completed && destPath != "" {
+		// The worker may still hold the partial file briefly after Cancel on Windows
+		pulseFile := destPath + types.IncompleteSuffix
+		for i := 0; i < 5; i++ {
+			if err := os.Remove(pulseFile); err == nil || os.IsNotExist(err) {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	if entry != nil {
+		// Under persistMu, so a run listing or failing at the same time sees it removed first
+		p.persistMu.Lock()
+		err := state.RemoveFromMasterList(downloadID)
+		p.persistMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	p.notifyQueueChanged()
+	return nil
+}
+
+// run downloads cfg in the slot reserved for it, then hands the slot to the next queued download
+func (p *WorkerPool) run(cfg types.DownloadConfig) {
+	defer func() {
 		p.mu.Lock()
-		p.downloads[cfg.ID] = ad
+		p.running--
 		p.mu.Unlock()
+		// Start the next download before releasing ours so GracefulShutdown never sees an idle gap
+		if p.schedule() > 0 {
+			p.saveQueue()
+		}
+		p.wg.Done()
+	}()
 
-		err := TUIDownload(ctx, cfg)
+	// Create cancellable context
+	ctx, cancel := context.WithCancel(context.Background())
 
-		// Check if this was a pause (not an error)
-		isPaused := cfg.State != nil && cfg.State.IsPaused()
+	// Register active download
+	ad := &activeDownload{
+		config:    cfg,
+		cancel:    cancel,
+		startedAt: time.Now(),
+	}
+	p.mu.Lock()
+	p.downloads[cfg.ID] = ad
+	ad.listed = p.persistQueue && !cfg.IsResume
+	listed := ad.listed
+	p.mu.Unlock()
+	defer p.settle(ad)
+	if listed {
+		p.persistMu.Lock()
+		p.saveRunning(ad)
+		p.persistMu.Unlock()
+	}
 
-		if err != nil && !isPaused {
-			if cfg.State != nil {
-				cfg.State.SetError(err)
-			}
+	err := TUIDownload(ctx, cfg)
+
+	p.mu.RLock()
+	removed := ad.removed
+	p.mu.RUnlock()
+	if removed {
+		// Remove deletes its state and master list entry; nothing is reported or saved
+		return
+	}
+
+	// Check if this was a pause (not an error)
+	isPaused := cfg.State != nil && cfg.State.IsPaused()
+
+	if err != nil && !isPaused {
+		kind := types.Classify(err)
+		p.mu.Lock()
+		delete(p.downloads, cfg.ID)
+		p.mu.Unlock()
+		if kind == types.KindCancelled {
+			// Stopped on purpose, not a failure: nothing to retry or report
+			return
+		}
+		if p.scheduleRetry(cfg, err, kind) {
+			return
+		}
+		if kind == types.KindLocked {
+			// The process downloading it owns its saved state and master list entry
+			p.unlistRunning(ad, false)
 			if p.progressCh != nil {
-				p.progressCh <- messages.DownloadErrorMsg{DownloadID: cfg.ID, Err: err}
+				p.progressCh <- messages.DownloadErrorMsg{DownloadID: cfg.ID, URL: cfg.URL, Err: err, Kind: kind}
+			}
+			return
+		}
+
+		if cfg.State != nil {
+			cfg.State.SetError(err)
+		}
+		if p.progressCh != nil {
+			p.progressCh <- messages.DownloadErrorMsg{DownloadID: cfg.ID, URL: cfg.URL, Err: err, Kind: kind}
+		}
+		// Keep the failure for the API and for Retry; its state file stays on disk
+		failure := FailedDownload{
+			ID:       cfg.ID,
+			URL:      cfg.URL,
+			Filename: cfg.Filename,
+			Kind:     kind,
+			Error:    err.Error(),
+			Time:     time.Now(),
+			config:   cfg,
+		}
+		p.mu.Lock()
+		removed := ad.removed
+		if !removed {
+			p.addFailedLocked(failure)
+		}
+		persist := p.persistQueue && !removed
+		p.mu.Unlock()
+		if persist {
+			// Remove may have run since; it deletes the entry under persistMu after marking the download
+			p.persistMu.Lock()
+			p.mu.RLock()
+			removed = ad.removed
+			p.mu.RUnlock()
+			if !removed {
+				saveFailure(failure)
 			}
-			// Clean up errored download from tracking (don't save to .pulse)
-			p.mu.Lock()
-			delete(p.downloads, cfg.ID)
-			p.mu.Unlock()
+			p.persistMu.Unlock()
+		}
 
-		} else if !isPaused {
-			// Only mark as done if not paused
+	} else if !isPaused {
+		// The concurrent downloader also returns nil when cancelled; only a finished file is complete
+		p.mu.RLock()
+		current := p.downloads[cfg.ID] == ad
+		p.mu.RUnlock()
+		if ctx.Err() != nil || !current {
+			return
+		}
+		// Before completion is reported, so the entry the TUI adds for it stays
+		p.unlistRunning(ad, true)
+		// Only mark as done if not paused
+		if cfg.State != nil {
+			cfg.State.Done.Store(true)
+		}
+		// The TUI progress reporter also reports completion when it detects Done=true;
+		// this message covers headless consumers, and the TUI ignores whichever arrives second
+		if p.progressCh != nil {
+			msg := messages.DownloadCompleteMsg{DownloadID: cfg.ID, Filename: cfg.Filename}
 			if cfg.State != nil {
-				cfg.State.Done.Store(true)
+				msg.Elapsed = time.Since(cfg.State.StartTime)
+				msg.Total = cfg.State.TotalSize
+				if msg.Total <= 0 {
+					msg.Total = cfg.State.Downloaded.Load()
+				}
 			}
-			// Note: DownloadCompleteMsg is sent by the progress reporter when it detects Done=true
+			p.progressCh <- msg
+		}
+
+		// Clean up from tracking
+		p.mu.Lock()
+		delete(p.downloads, cfg.ID)
+		p.mu.Unlock()
+	}
+	// If paused, we keep it in downloads map for potential resume
+}
+
+// addFailedLocked appends to the failure list, dropping the oldest entries beyond maxFailedDownloads
+func (p *WorkerPool) addFailedLocked(f FailedDownload) {
+	p.failed = append(p.failed, f)
+	if len(p.failed) > maxFailedDownloads {
+		p.failed = p.failed[len(p.failed)-maxFailedDownloads:]
+	}
+}
+
+// removeFailedLocked drops a download from the failure list and reports whether it was there
+func (p *WorkerPool) removeFailedLocked(downloadID string) (FailedDownload, bool) {
+	i := slices.IndexFunc(p.failed, func(f FailedDownload) bool { return f.ID == downloadID })
+	if i < 0 {
+		return FailedDownload{}, false
+	}
+	f := p.failed[i]
+	p.failed = slices.Delete(p.failed, i, i+1)
+	return f, true
+}
 
-			// Clean up from tracking
-			p.mu.Lock()
-			delete(p.downloads, cfg.ID)
-			p.mu.Unlock()
+// saveFailure records a failed download in the master list so it is still listed,
+// and can be retried, after a restart. Only pools that persist their queue keep failures;
+// a throwaway pool, such as a batch run's, leaves the master list alone.
+func saveFailure(f FailedDownload) {
+	cfg := f.config
+	entry := types.DownloadEntry{
+		ID:         f.ID,
+		URLHash:    state.URLHash(f.URL),
+		URL:        f.URL,
+		Filename:   f.Filename,
+		Category:   cfg.Category,
+		Error:      f.Error,
+		ErrorKind:  string(f.Kind),
+		FailedAt:   f.Time.Unix(),
+		OutputPath: cfg.OutputPath,
+		Quality:    cfg.Quality,
+		Priority:   cfg.Priority,
+		Headers:    cfg.Headers,
+		Checksum:   cfg.Checksum,
+		Mirrors:    cfg.Mirrors,
+	}
+	if cfg.State != nil {
+		entry.Downloaded, entry.TotalSize, _, _, _ = cfg.State.GetProgress()
+		entry.DestPath = cfg.State.DestPath()
+		if entry.DestPath != "" {
+			entry.Filename = filepath.Base(entry.DestPath)
 		}
-		// If paused, we keep it in downloads map for potential resume
-		p.wg.Done()
+		if category := cfg.State.Category(); category != "" {
+			entry.Category = category
+		}
+	}
+	if err := state.MarkFailed(entry); err != nil {
+		utils.Debug("Failed to save failed download %s: %v", f.ID, err)
+	}
+}
+
+// RestoreFailed lists a download that failed in a previous session so it can be retried.
+// cfg is built from the entry with RestoredConfig and is given ProgressCh and State as usual.
+func (p *WorkerPool) RestoreFailed(entry types.DownloadEntry, cfg types.DownloadConfig) {
+	if cfg.State != nil && entry.DestPath != "" {
+		cfg.State.SetDestPath(entry.DestPath)
+	}
+	p.mu.Lock()
+	p.addFailedLocked(FailedDownload{
+		ID:       entry.ID,
+		URL:      entry.URL,
+		Filename: entry.Filename,
+		Kind:     types.ErrorKind(entry.ErrorKind),
+		Error:    entry.Error,
+		Time:     time.Unix(entry.FailedAt, 0),
+		config:   cfg,
+	})
+	p.mu.Unlock()
+}
+
+// RestorePaused tracks a download paused in a previous session, so resuming it or changing
+// its URL keeps the caller's progress state. cfg is built from the entry with ResumedConfig
+// and is given ProgressCh and State as usual.
+func (p *WorkerPool) RestorePaused(cfg types.DownloadConfig) {
+	if cfg.State != nil {
+		cfg.State.SetDestPath(cfg.DestPath)
+		cfg.State.Pause()
+	}
+	p.mu.Lock()
+	if _, ok := p.downloads[cfg.ID]; !ok {
+		p.downloads[cfg.ID] = &activeDownload{config: cfg}
+	}
+	p.mu.Unlock()
+}
+
+// scheduleRetry arranges for a failed download to be resumed after a delay that doubles with
+// every attempt. It returns false if the error is permanent or the retries are used up.
+func (p *WorkerPool) scheduleRetry(cfg types.DownloadConfig, err error, kind types.ErrorKind) bool {
+	rc := cfg.RuntimeConfig()
+	maxAttempts := rc.GetDownloadRetries()
+	if !kind.Transient() || cfg.Attempt >= maxAttempts {
+		return false
+	}
+	delay := retryDelay(rc.GetDownloadRetryDelay(), cfg.Attempt)
+	next := resumeConfig(cfg)
+	next.Attempt++
+
+	p.mu.Lock()
+	if p.shuttingDown {
+		p.mu.Unlock()
+		return false
+	}
+	p.retries[cfg.ID] = &pendingRetry{
+		config: next,
+		timer:  time.AfterFunc(delay, func() { p.startRetry(cfg.ID) }),
+	}
+	p.mu.Unlock()
+
+	utils.Debug("Retrying %s in %v (attempt %d/%d): %v", cfg.ID, delay, next.Attempt, maxAttempts, err)
+	if p.progressCh != nil {
+		p.progressCh <- messages.DownloadRetryingMsg{
+			DownloadID:  cfg.ID,
+			Attempt:     next.Attempt,
+			MaxAttempts: maxAttempts,
+			Delay:       delay,
+			Err:         err,
+			Kind:        kind,
+		}
+	}
+	return true
+}
+
+// retryDelay doubles base for every retry already made, up to MaxDownloadRetryDelay
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < types.MaxDownloadRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, types.MaxDownloadRetryDelay)
+}
+
+// resumeConfig points cfg at the state saved by its previous run. Downloads that failed
+// before writing anything start over.
+func resumeConfig(cfg types.DownloadConfig) types.DownloadConfig {
+	if cfg.State == nil {
+		return cfg
+	}
+	if dest := cfg.State.DestPath(); dest != "" {
+		cfg.DestPath = dest
+		cfg.OutputPath = filepath.Dir(dest)
+		cfg.Filename = filepath.Base(dest)
+		cfg.IsResume = true
+	} else {
+		cfg.State.Downloaded.Store(0)
 	}
+	return cfg
 }
 
-// GracefulShutdown pauses all downloads and waits for them to save state
+// startRetry queues a download whose retry is pending. It returns false if there is none,
+// for instance because the download was cancelled meanwhile.
+func (p *WorkerPool) startRetry(downloadID string) bool {
+	p.mu.Lock()
+	r, ok := p.retries[downloadID]
+	if ok {
+		r.timer.Stop()
+		delete(p.retries, downloadID)
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	p.Add(r.config)
+	return true
+}
+
+// Retry resumes a failed download from its saved state. A download waiting for an automatic
+// retry starts straight away; one that ran out of retries gets a fresh set of them.
+func (p *WorkerPool) Retry(downloadID string) error {
+	if p.startRetry(downloadID) {
+		return nil
+	}
+
+	p.mu.Lock()
+	f, ok := p.removeFailedLocked(downloadID)
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no failed download with ID %s", downloadID)
+	}
+
+	cfg := resumeConfig(f.config)
+	cfg.Attempt = 0
+	if cfg.State != nil {
+		cfg.State.ClearError()
+	}
+	if err := state.ClearFailure(downloadID); err != nil {
+		utils.Debug("Failed to clear failure of %s: %v", downloadID, err)
+	}
+	p.Add(cfg)
+	return nil
+}
+
+// GracefulShutdown pauses all downloads and waits for them to save state.
+// With queue persistence enabled, the downloads that never started are saved as "queued".
+// Pending automatic retries are dropped; their downloads keep their saved state.
 func (p *WorkerPool) GracefulShutdown() {
+	p.mu.Lock()
+	p.shuttingDown = true
+	for id, r := range p.retries {
+		r.timer.Stop()
+		delete(p.retries, id)
+	}
+	p.mu.Unlock()
+	p.saveQueue()
 	p.PauseAll()
 	p.wg.Wait() // Blocks until all workers call Done()
 }
@@ -2,13 +2,21 @@ package download
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pulse-downloader/pulse/internal/download/state"
 	"github.com/pulse-downloader/pulse/internal/download/types"
 	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/testutil"
 )
 
 func TestNewWorkerPool(t *testing.T) {
@@ -19,8 +27,8 @@ func TestNewWorkerPool(t *testing.T) {
 		t.Fatal("Expected non-nil WorkerPool")
 	}
 
-	if pool.taskChan == nil {
-		t.Error("Expected taskChan to be initialized")
+	if len(pool.Queued()) != 0 {
+		t.Error("Expected empty queue")
 	}
 
 	if pool.progressCh != ch {
@@ -418,6 +426,7 @@ func TestWorkerPool_Cancel_MarksDone(t *testing.T) {
 }
 
 func TestWorkerPool_Resume_NonExistentDownload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	ch := make(chan tea.Msg, 10)
 	pool := NewWorkerPool(ch, 3)
 
@@ -604,3 +613,773 @@ func TestWorkerPool_ConcurrentPauseCancel(t *testing.T) {
 		t.Errorf("Expected 0 remaining downloads, got %d", remaining)
 	}
 }
+
+// busyPool returns a pool whose slots are all taken, so added downloads stay queued
+func busyPool(ch chan tea.Msg) *WorkerPool {
+	pool := NewWorkerPool(ch, 1)
+	pool.running = 1
+	return pool
+}
+
+func queuedIDs(pool *WorkerPool) string {
+	var ids []string
+	for _, cfg := range pool.Queued() {
+		ids = append(ids, cfg.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestWorkerPool_Add_OrdersByPriority(t *testing.T) {
+	pool := busyPool(make(chan tea.Msg, 10))
+
+	pool.Add(types.DownloadConfig{ID: "n1"})
+	pool.Add(types.DownloadConfig{ID: "l1", Priority: types.PriorityLow})
+	pool.Add(types.DownloadConfig{ID: "h1", Priority: types.PriorityHigh})
+	pool.Add(types.DownloadConfig{ID: "n2"})
+	pool.Add(types.DownloadConfig{ID: "h2", Priority: types.PriorityHigh})
+
+	if got, want := queuedIDs(pool), "h1,h2,n1,n2,l1"; got != want {
+		t.Errorf("queue = %s, want %s", got, want)
+	}
+}
+
+func TestWorkerPool_Add_NoQueueLimit(t *testing.T) {
+	pool := busyPool(nil)
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 500; i++ {
+			pool.Add(types.DownloadConfig{ID: fmt.Sprintf("d%d", i)})
+		}
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Add() blocked with a long queue")
+	}
+	if n := len(pool.Queued()); n != 500 {
+		t.Errorf("queued = %d, want 500", n)
+	}
+}
+
+func TestWorkerPool_Move(t *testing.T) {
+	tests := []struct {
+		id           string
+		op           MoveOp
+		want         string
+		wantPriority types.Priority
+	}{
+		{"b", MoveUp, "b,a,c,d", types.PriorityHigh},
+		{"a", MoveUp, "a,b,c,d", types.PriorityHigh},
+		{"b", MoveDown, "a,c,b,d", types.PriorityNormal},
+		{"c", MoveDown, "a,b,d,c", types.PriorityLow},
+		{"d", MoveTop, "d,a,b,c", types.PriorityHigh},
+		{"a", MoveBottom, "b,c,d,a", types.PriorityLow},
+		{"d", MoveBottom, "a,b,c,d", types.PriorityLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.op)+"_"+tt.id, func(t *testing.T) {
+			ch := make(chan tea.Msg, 10)
+			pool := busyPool(ch)
+			pool.Add(types.DownloadConfig{ID: "a", Priority: types.PriorityHigh})
+			pool.Add(types.DownloadConfig{ID: "b", Priority: types.PriorityHigh})
+			pool.Add(types.DownloadConfig{ID: "c"})
+			pool.Add(types.DownloadConfig{ID: "d", Priority: types.PriorityLow})
+
+			if err := pool.Move(tt.id, tt.op); err != nil {
+				t.Fatalf("Move failed: %v", err)
+			}
+			if got := queuedIDs(pool); got != tt.want {
+				t.Errorf("queue = %s, want %s", got, tt.want)
+			}
+			for _, cfg := range pool.Queued() {
+				if cfg.ID == tt.id && cfg.Priority != tt.wantPriority {
+					t.Errorf("priority = %s, want %s", cfg.Priority, tt.wantPriority)
+				}
+			}
+
+			select {
+			case msg := <-ch:
+				if _, ok := msg.(messages.QueueChangedMsg); !ok {
+					t.Errorf("expected QueueChangedMsg, got %T", msg)
+				}
+			default:
+				t.Error("expected QueueChangedMsg")
+			}
+		})
+	}
+}
+
+func TestWorkerPool_Move_Errors(t *testing.T) {
+	pool := busyPool(nil)
+	pool.Add(types.DownloadConfig{ID: "a"})
+
+	if err := pool.Move("missing", MoveUp); err == nil {
+		t.Error("expected error for unknown download")
+	}
+	if err := pool.Move("a", MoveOp("sideways")); err == nil {
+		t.Error("expected error for unknown move")
+	}
+}
+
+func TestWorkerPool_SetPriority(t *testing.T) {
+	pool := busyPool(nil)
+	pool.Add(types.DownloadConfig{ID: "a"})
+	pool.Add(types.DownloadConfig{ID: "b"})
+	pool.Add(types.DownloadConfig{ID: "c", Priority: types.PriorityHigh})
+
+	if err := pool.SetPriority("b", types.PriorityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := queuedIDs(pool), "c,b,a"; got != want {
+		t.Errorf("queue = %s, want %s", got, want)
+	}
+	if err := pool.SetPriority("c", types.PriorityLow); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := queuedIDs(pool), "b,a,c"; got != want {
+		t.Errorf("queue = %s, want %s", got, want)
+	}
+}
+
+func TestWorkerPool_Cancel_RemovesQueued(t *testing.T) {
+	pool := busyPool(nil)
+	pool.Add(types.DownloadConfig{ID: "a"})
+	pool.Add(types.DownloadConfig{ID: "b"})
+
+	pool.Cancel("a")
+
+	if got := queuedIDs(pool); got != "b" {
+		t.Errorf("queue = %s, want b", got)
+	}
+}
+
+func TestWorkerPool_StartNow_BypassesLimit(t *testing.T) {
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(2048),
+		testutil.WithRangeSupport(false),
+	)
+	defer server.Close()
+
+	ch := make(chan tea.Msg, 100)
+	pool := busyPool(ch)
+	pool.Add(types.DownloadConfig{
+		ID:         "now",
+		URL:        server.URL() + "/file.bin",
+		OutputPath: t.TempDir(),
+		ProgressCh: ch,
+		State:      types.NewProgressState("now", 0),
+	})
+
+	if err := pool.StartNow("now"); err != nil {
+		t.Fatalf("StartNow failed: %v", err)
+	}
+	if len(pool.Queued()) != 0 {
+		t.Error("started download should leave the queue")
+	}
+	if err := pool.StartNow("now"); err == nil {
+		t.Error("expected error starting a download that is not queued")
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-ch:
+			switch m := msg.(type) {
+			case messages.DownloadCompleteMsg:
+				return
+			case messages.DownloadErrorMsg:
+				t.Fatalf("download failed: %v", m.Err)
+			}
+		case <-timeout:
+			t.Fatal("download started with StartNow did not complete")
+		}
+	}
+}
+
+func TestWorkerPool_QueuePersistence(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pool := busyPool(make(chan tea.Msg, 10))
+
+	// Nothing is written until persistence is enabled
+	pool.Add(types.DownloadConfig{ID: "a", URL: "https://example.com/a.zip", OutputPath: "/dl", Quality: "1080p"})
+	if queued, _ := state.LoadQueuedDownloads(); len(queued) != 0 {
+		t.Fatalf("Queue saved before persistence was enabled: %+v", queued)
+	}
+
+	pool.EnableQueuePersistence()
+	pool.Add(types.DownloadConfig{ID: "b", URL: "https://example.com/b.zip", Priority: types.PriorityHigh})
+	pool.Add(types.DownloadConfig{ID: "r", URL: "https://example.com/r.zip", IsResume: true})
+	pool.Cancel("r")
+	pool.Add(types.DownloadConfig{ID: "r", URL: "https://example.com/r.zip", IsResume: true})
+
+	queued, err := state.LoadQueuedDownloads()
+	if err != nil {
+		t.Fatalf("LoadQueuedDownloads failed: %v", err)
+	}
+	if len(queued) != 2 || queued[0].ID != "b" || queued[1].ID != "a" {
+		t.Fatalf("Unexpected saved queue: %+v", queued)
+	}
+
+	// Restoring into a fresh pool reproduces the order and options
+	restored := busyPool(make(chan tea.Msg, 10))
+	for _, entry := range queued {
+		restored.Add(RestoredConfig(entry))
+	}
+	got := restored.Queued()
+	if queuedIDs(restored) != "b,a" || got[0].Priority != types.PriorityHigh || got[1].OutputPath != "/dl" || got[1].Quality != "1080p" {
+		t.Errorf("Unexpected restored queue: %+v", got)
+	}
+
+	if err := pool.Move("a", MoveTop); err != nil {
+		t.Fatal(err)
+	}
+	if queued, _ := state.LoadQueuedDownloads(); len(queued) != 2 || queued[0].ID != "a" {
+		t.Errorf("Move was not persisted: %+v", queued)
+	}
+}
+
+func TestWorkerPool_SetMaxDownloads_Grow(t *testing.T) {
+	ch := make(chan tea.Msg, 10)
+	pool := busyPool(ch)
+	pool.Add(types.DownloadConfig{ID: "a"})
+	pool.Add(types.DownloadConfig{ID: "b"})
+
+	if _, err := pool.SetMaxDownloads(3, false); err != nil {
+		t.Fatal(err)
+	}
+	if pool.MaxDownloads() != 3 {
+		t.Errorf("MaxDownloads() = %d, want 3", pool.MaxDownloads())
+	}
+	if q := queuedIDs(pool); q != "" {
+		t.Errorf("Queued downloads should have started, still queued: %s", q)
+	}
+	pool.wg.Wait()
+
+	if _, err := pool.SetMaxDownloads(0, false); err == nil {
+		t.Error("Expected error for a limit below 1")
+	}
+}
+
+func TestWorkerPool_SetMaxDownloads_Shrink(t *testing.T) {
+	now := time.Now()
+	active := func(id string, priority types.Priority, age time.Duration) *activeDownload {
+		return &activeDownload{
+			config:    types.DownloadConfig{ID: id, Priority: priority, State: types.NewProgressState(id, 100)},
+			startedAt: now.Add(-age),
+		}
+	}
+
+	for _, pauseExtra := range []bool{false, true} {
+		ch := make(chan tea.Msg, 10)
+		pool := NewWorkerPool(ch, 4)
+		pool.downloads["high"] = active("high", types.PriorityHigh, time.Second)
+		pool.downloads["old"] = active("old", types.PriorityNormal, time.Hour)
+		pool.downloads["new"] = active("new", types.PriorityNormal, time.Second)
+		pool.downloads["low"] = active("low", types.PriorityLow, time.Hour)
+
+		paused, err := pool.SetMaxDownloads(2, pauseExtra)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := ""
+		if pauseExtra {
+			// Lowest priority first, then the most recently started
+			want = "low,new"
+		}
+		if got := strings.Join(paused, ","); got != want {
+			t.Errorf("pauseExtra=%v: paused %q, want %q", pauseExtra, got, want)
+		}
+		for _, id := range paused {
+			if !pool.downloads[id].config.State.IsPaused() {
+				t.Errorf("%s was reported paused but is not", id)
+			}
+		}
+		if pool.downloads["high"].config.State.IsPaused() || pool.downloads["old"].config.State.IsPaused() {
+			t.Error("Higher priority downloads should keep running")
+		}
+	}
+}
+
+func TestWorkerPool_ScheduleRetry(t *testing.T) {
+	ch := make(chan tea.Msg, 10)
+	pool := busyPool(ch)
+	ps := types.NewProgressState("a", 1000)
+	ps.SetDestPath("/downloads/file.bin")
+	cfg := types.DownloadConfig{
+		ID:      "a",
+		URL:     "https://example.com/file.bin",
+		State:   ps,
+		Runtime: &types.RuntimeConfig{DownloadRetries: 2, DownloadRetryDelay: 10 * time.Millisecond},
+	}
+	err := types.StatusError(503)
+
+	if pool.scheduleRetry(cfg, types.StatusError(404), types.KindHTTP) {
+		t.Error("permanent errors should not be retried")
+	}
+	if !pool.scheduleRetry(cfg, err, types.KindNetwork) {
+		t.Fatal("expected a retry to be scheduled")
+	}
+
+	msg, ok := (<-ch).(messages.DownloadRetryingMsg)
+	if !ok || msg.Attempt != 1 || msg.MaxAttempts != 2 || msg.Delay != 10*time.Millisecond {
+		t.Errorf("unexpected retry message: %+v", msg)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(pool.Queued()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	queued := pool.Queued()
+	if len(queued) != 1 {
+		t.Fatalf("retry was not queued: %v", queuedIDs(pool))
+	}
+	if got := queued[0]; !got.IsResume || got.DestPath != "/downloads/file.bin" || got.Filename != "file.bin" || got.Attempt != 1 {
+		t.Errorf("retry should resume from the saved state: %+v", got)
+	}
+
+	cfg.Attempt = 2
+	if pool.scheduleRetry(cfg, err, types.KindNetwork) {
+		t.Error("retries beyond DownloadRetries should not be scheduled")
+	}
+}
+
+func TestWorkerPool_RestorePaused(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pool := busyPool(make(chan tea.Msg, 10))
+	ps := types.NewProgressState("a", 1000)
+	dest := filepath.Join(t.TempDir(), "a.bin")
+	pool.RestorePaused(types.DownloadConfig{ID: "a", URL: "http://example.com/a.bin", DestPath: dest, IsResume: true, State: ps})
+
+	if !ps.IsPaused() || ps.DestPath() != dest {
+		t.Errorf("Restored state should be paused at %s, got paused=%v dest=%q", dest, ps.IsPaused(), ps.DestPath())
+	}
+	if got := pool.Downloads(); len(got) != 1 || got[0].Status != "paused" {
+		t.Errorf("Restored download should be listed as paused: %+v", got)
+	}
+
+	if err := pool.Resume("a"); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	queued := pool.Queued()
+	if len(queued) != 1 || queued[0].State != ps || !queued[0].IsResume {
+		t.Errorf("Resume should continue with the restored progress state: %+v", queued)
+	}
+}
+
+func TestWorkerPool_Retry(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pool := busyPool(make(chan tea.Msg, 10))
+	ps := types.NewProgressState("a", 1000)
+	ps.SetError(fmt.Errorf("boom"))
+	cfg := types.DownloadConfig{ID: "a", State: ps, Attempt: 3}
+	pool.failed = append(pool.failed, FailedDownload{ID: "a", config: cfg})
+
+	if err := pool.Retry("a"); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	queued := pool.Queued()
+	if len(queued) != 1 || queued[0].Attempt != 0 {
+		t.Errorf("Retry should queue the download with fresh retries: %+v", queued)
+	}
+	if ps.GetError() != nil {
+		t.Error("Retry should clear the previous error")
+	}
+	if len(pool.Failed()) != 0 {
+		t.Error("Retry should remove the download from the failed list")
+	}
+	if err := pool.Retry("a"); err == nil {
+		t.Error("expected error retrying a download that is not failed")
+	}
+}
+
+func TestWorkerPool_Retry_Pending(t *testing.T) {
+	pool := busyPool(make(chan tea.Msg, 10))
+	cfg := types.DownloadConfig{
+		ID:      "a",
+		State:   types.NewProgressState("a", 0),
+		Runtime: &types.RuntimeConfig{DownloadRetries: 1, DownloadRetryDelay: time.Hour},
+	}
+
+	pool.scheduleRetry(cfg, types.StatusError(503), types.KindNetwork)
+	if err := pool.Retry("a"); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if got := queuedIDs(pool); got != "a" {
+		t.Errorf("pending retry should start straight away, queue = %s", got)
+	}
+
+	pool.scheduleRetry(types.DownloadConfig{ID: "b", Runtime: cfg.Runtime}, types.StatusError(503), types.KindNetwork)
+	pool.Cancel("b")
+	if err := pool.Retry("b"); err == nil {
+		t.Error("cancelled downloads should not be retried")
+	}
+}
+
+func TestWorkerPool_FailedDownloadsPersist(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	ps := types.NewProgressState("a", 0)
+	ps.SetTotalSize(1000)
+	ps.Downloaded.Store(400)
+	ps.SetDestPath("/downloads/a.bin")
+	ps.SetCategory("Archives")
+	saveFailure(FailedDownload{
+		ID:    "a",
+		URL:   "https://example.com/a.bin",
+		Kind:  types.KindNetwork,
+		Error: "connection reset by peer",
+		Time:  time.Unix(1700000000, 0),
+		config: types.DownloadConfig{
+			ID:    "a",
+			URL:   "https://example.com/a.bin",
+			State: ps,
+		},
+	})
+
+	entries, err := state.LoadFailedDownloads()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("LoadFailedDownloads = %+v, %v", entries, err)
+	}
+	entry := entries[0]
+	if entry.Filename != "a.bin" || entry.DestPath != "/downloads/a.bin" || entry.Category != "Archives" ||
+		entry.Downloaded != 400 || entry.TotalSize != 1000 || entry.ErrorKind != "network" || entry.FailedAt != 1700000000 {
+		t.Errorf("Unexpected failed entry: %+v", entry)
+	}
+
+	// A new session lists it again and resumes it from the saved destination
+	pool := busyPool(make(chan tea.Msg, 10))
+	cfg := RestoredConfig(entry)
+	cfg.State = types.NewProgressState(entry.ID, 0)
+	pool.RestoreFailed(entry, cfg)
+	if failed := pool.Failed(); len(failed) != 1 || failed[0].Kind != types.KindNetwork || failed[0].Error != entry.Error {
+		t.Fatalf("Restored failure not listed: %+v", failed)
+	}
+	if err := pool.Retry("a"); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if queued := pool.Queued(); len(queued) != 1 || !queued[0].IsResume || queued[0].DestPath != "/downloads/a.bin" {
+		t.Errorf("Retry should resume from the saved destination: %+v", queued)
+	}
+	if e, _ := state.GetDownloadEntry("a"); e == nil || e.Status != "paused" {
+		t.Errorf("Retried download should no longer be listed as failed: %+v", e)
+	}
+}
+
+func TestWorkerPool_FailuresPersistWithQueue(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	for _, persist := range []bool{false, true} {
+		id := fmt.Sprintf("persist-%v", persist)
+		pool := NewWorkerPool(nil, 1)
+		if persist {
+			pool.EnableQueuePersistence()
+		}
+		pool.Add(types.DownloadConfig{
+			ID:         id,
+			URL:        server.URL + "/missing.bin",
+			OutputPath: t.TempDir(),
+			State:      types.NewProgressState(id, 0),
+		})
+		pool.wg.Wait()
+
+		if failed := pool.Failed(); len(failed) != 1 {
+			t.Fatalf("Failure not kept by the pool (persist=%v): %+v", persist, failed)
+		}
+		e, _ := state.GetDownloadEntry(id)
+		if persist && (e == nil || e.Status != "error") {
+			t.Errorf("Persisting pool did not list the failure: %+v", e)
+		}
+		if !persist && e != nil {
+			t.Errorf("Throwaway pool listed its failure in the master list: %+v", e)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	base := 10 * time.Second
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 20 * time.Second},
+		{2, 40 * time.Second},
+		{10, types.MaxDownloadRetryDelay},
+		{100, types.MaxDownloadRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(base, tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%v, %d) = %v, want %v", base, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, s := range []string{"high", "HIGH", "normal", "", "low"} {
+		p, err := types.ParsePriority(s)
+		if err != nil {
+			t.Errorf("ParsePriority(%q) failed: %v", s, err)
+		}
+		if s != "" && p.String() != strings.ToLower(s) {
+			t.Errorf("ParsePriority(%q).String() = %s", s, p)
+		}
+	}
+	if _, err := types.ParsePriority("urgent"); err == nil {
+		t.Error("expected error for unknown priority")
+	}
+}
+
+func TestWorkerPool_Downloads(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pool := busyPool(make(chan tea.Msg, 10))
+
+	running := types.NewProgressState("run", 0)
+	running.SetTotalSize(1000)
+	running.Downloaded.Store(250)
+	running.SetDestPath("/downloads/run.bin")
+	paused := types.NewProgressState("pause", 0)
+	paused.Pause()
+	pool.mu.Lock()
+	pool.downloads["run"] = &activeDownload{config: types.DownloadConfig{ID: "run", State: running}, startedAt: time.Now().Add(-time.Minute)}
+	pool.downloads["pause"] = &activeDownload{config: types.DownloadConfig{ID: "pause", State: paused}, startedAt: time.Now()}
+	pool.mu.Unlock()
+	pool.Add(types.DownloadConfig{ID: "queued", URL: "https://example.com/q.zip"})
+	state.AddToMasterList(types.DownloadEntry{ID: "done", Status: "completed", TotalSize: 500})
+
+	var got []string
+	for _, info := range pool.Downloads() {
+		got = append(got, info.ID+"="+info.Status)
+		if info.ID == "run" && (info.Downloaded != 250 || info.Total != 1000 || info.Filename != "run.bin") {
+			t.Errorf("Unexpected running download: %+v", info)
+		}
+	}
+	if want := "run=downloading,pause=paused,queued=queued,done=completed"; strings.Join(got, ",") != want {
+		t.Errorf("Downloads = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestWorkerPool_PauseResume_Errors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pool := busyPool(make(chan tea.Msg, 10))
+	pool.Add(types.DownloadConfig{ID: "queued"})
+
+	if err := pool.Pause("queued"); err == nil {
+		t.Error("expected error pausing a download that has not started")
+	}
+	if err := pool.Pause("missing"); err == nil {
+		t.Error("expected error pausing an unknown download")
+	}
+	if err := pool.Resume("missing"); err == nil {
+		t.Error("expected error resuming an unknown download")
+	}
+
+	// Downloads paused in an earlier session are resumed from the master list
+	state.AddToMasterList(types.DownloadEntry{ID: "old", URL: "https://example.com/old.zip", DestPath: "/downloads/old.zip", Filename: "old.zip", Status: "paused"})
+	if err := pool.Resume("old"); err != nil {
+		t.Fatalf("Resume of earlier session failed: %v", err)
+	}
+	queued := pool.Queued()
+	if len(queued) != 2 || queued[1].ID != "old" || !queued[1].IsResume || queued[1].DestPath != "/downloads/old.zip" {
+		t.Errorf("Resume should queue the saved download: %+v", queued)
+	}
+}
+
+func TestWorkerPool_Remove(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pool := busyPool(make(chan tea.Msg, 10))
+
+	dir := t.TempDir()
+	destPath := filepath.Join(dir, "file.bin")
+	partial := destPath + types.IncompleteSuffix
+	if err := os.WriteFile(partial, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	url := "https://example.com/file.bin"
+	if err := state.SaveState(url, destPath, &types.DownloadState{ID: "a", URL: url, DestPath: destPath}); err != nil {
+		t.Fatal(err)
+	}
+	pool.Add(types.DownloadConfig{ID: "b"})
+
+	if err := pool.Remove("a"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("Partial file not removed: %v", err)
+	}
+	if s, _ := state.LoadState(url, destPath); s != nil {
+		t.Error("Saved state not removed")
+	}
+	if e, _ := state.GetDownloadEntry("a"); e != nil {
+		t.Errorf("Master list entry not removed: %+v", e)
+	}
+
+	if err := pool.Remove("b"); err != nil || len(pool.Queued()) != 0 {
+		t.Errorf("Remove of queued download = %v, queue %s", err, queuedIDs(pool))
+	}
+	if err := pool.Remove("missing"); err == nil {
+		t.Error("expected error removing an unknown download")
+	}
+}
+
+func TestWorkerPool_RemoveRunning(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(1024*1024),
+		testutil.WithRangeSupport(false),
+		testutil.WithByteLatency(10*time.Microsecond),
+	)
+	defer server.Close()
+
+	ch := make(chan tea.Msg, 1000)
+	pool := NewWorkerPool(ch, 1)
+	pool.EnableQueuePersistence()
+	pool.Add(types.DownloadConfig{
+		ID:         "single",
+		URL:        server.URL() + "/single.bin",
+		OutputPath: t.TempDir(),
+		ProgressCh: ch,
+		State:      types.NewProgressState("single", 0),
+	})
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if e, _ := state.GetDownloadEntry("single"); e != nil && e.Status == "downloading" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond) // Let it start transferring
+
+	if err := pool.Remove("single"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	pool.wg.Wait()
+
+	if failed := pool.Failed(); len(failed) != 0 {
+		t.Errorf("Removed download recorded as failed: %+v", failed)
+	}
+	if e, _ := state.GetDownloadEntry("single"); e != nil {
+		t.Errorf("Removed download is still listed: %+v", e)
+	}
+	close(ch)
+	for msg := range ch {
+		switch m := msg.(type) {
+		case messages.DownloadErrorMsg:
+			t.Errorf("Removed download reported an error: %v", m.Err)
+		case messages.DownloadCompleteMsg:
+			t.Error("Removed download reported completion")
+		}
+	}
+}
+
+func TestWorkerPool_CancelledConcurrentDownloadNotCompleted(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(4*1024*1024),
+		testutil.WithRangeSupport(true),
+		testutil.WithByteLatency(10*time.Microsecond),
+	)
+	defer server.Close()
+
+	ch := make(chan tea.Msg, 1000)
+	pool := NewWorkerPool(ch, 1)
+	pool.Add(types.DownloadConfig{
+		ID:         "concurrent",
+		URL:        server.URL() + "/concurrent.bin",
+		OutputPath: t.TempDir(),
+		ProgressCh: ch,
+		State:      types.NewProgressState("concurrent", 0),
+	})
+	var ad *activeDownload
+	deadline := time.Now().Add(2 * time.Second)
+	for ad == nil && time.Now().Before(deadline) {
+		pool.mu.RLock()
+		ad = pool.downloads["concurrent"]
+		pool.mu.RUnlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ad == nil {
+		t.Fatal("download did not start")
+	}
+	time.Sleep(300 * time.Millisecond) // Let it start transferring
+
+	// The concurrent downloader returns nil when its context is cancelled
+	ad.cancel()
+	pool.wg.Wait()
+
+	close(ch)
+	for msg := range ch {
+		if _, ok := msg.(messages.DownloadCompleteMsg); ok {
+			t.Error("Cancelled download reported completion")
+		}
+	}
+}
+
+func TestWorkerPool_RunningDownloadsPersist(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(256*1024),
+		testutil.WithRangeSupport(true),
+		testutil.WithLatency(300*time.Millisecond),
+	)
+	defer server.Close()
+
+	ch := make(chan tea.Msg, 100)
+	pool := NewWorkerPool(ch, 2)
+	add := func(id string) {
+		pool.Add(types.DownloadConfig{
+			ID:         id,
+			URL:        server.URL() + "/" + id + ".bin",
+			OutputPath: t.TempDir(),
+			Category:   "Archives",
+			ProgressCh: ch,
+			State:      types.NewProgressState(id, 0),
+		})
+	}
+	status := func(id string) string {
+		e, _ := state.GetDownloadEntry(id)
+		if e == nil {
+			return ""
+		}
+		return e.Status
+	}
+
+	// A restored download may start before persistence is enabled; it is listed all the same
+	add("restored")
+	pool.EnableQueuePersistence()
+	add("dispatched")
+	for _, id := range []string{"restored", "dispatched"} {
+		deadline := time.Now().Add(time.Second)
+		for status(id) != "downloading" && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := status(id); got != "downloading" {
+			t.Errorf("Running download %s is listed as %q, want downloading", id, got)
+		}
+	}
+	if e, _ := state.GetDownloadEntry("dispatched"); e == nil || e.Category != "Archives" || e.OutputPath == "" {
+		t.Errorf("Running download was listed without its options: %+v", e)
+	}
+
+	// A crash now restarts them on the next start
+	if _, err := state.RecoverInterrupted(); err != nil {
+		t.Fatalf("RecoverInterrupted failed: %v", err)
+	}
+	if queued, _ := state.LoadQueuedDownloads(); len(queued) != 2 {
+		t.Errorf("Interrupted downloads were not queued again: %+v", queued)
+	}
+
+	// Finished downloads leave the list
+	pool.wg.Wait()
+	for _, id := range []string{"restored", "dispatched"} {
+		if got := status(id); got != "" {
+			t.Errorf("Completed download %s is still listed as %q", id, got)
+		}
+	}
+}
@@ -23,6 +23,7 @@ type SingleDownloader struct {
 	ID           string               // Download ID
 	State        *types.ProgressState // Shared state for TUI polling
 	Runtime      *types.RuntimeConfig
+	Headers      map[string]string // Extra request headers
 }
 
 // NewSingleDownloader creates a new single-threaded downloader with all required parameters
@@ -40,23 +41,12 @@ func NewSingleDownloader(id string, progressCh chan<- tea.Msg, state *types.Prog
 // This is used for servers that don't support Range requests.
 // If interrupted, the download cannot be resumed and must restart from the beginning.
 func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string, fileSize int64, filename string, verbose bool) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
-
-	resp, err := d.Client.Do(req)
+	resp, err := d.request(ctx, rawurl)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	// Use .pulse extension for incomplete file
 	workingPath := destPath + types.IncompleteSuffix
 	outFile, err := os.Create(workingPath)
@@ -76,54 +66,23 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	start := time.Now()
 
 	// Copy response body to file with context cancellation support
-	var written int64
-	buf := make([]byte, d.Runtime.GetWorkerBufferSize())
-
-	for {
-		// Check for context cancellation (allows clean shutdown)
-		select {
-		case <-ctx.Done():
-			// Can't resume - server doesn't support Range requests
-			return ctx.Err()
-		default:
-		}
-
-		nr, readErr := resp.Body.Read(buf)
-		if nr > 0 {
-			nw, writeErr := outFile.Write(buf[0:nr])
-			if nw > 0 {
-				written += int64(nw)
-				if d.State != nil {
-					d.State.Downloaded.Store(written)
-				}
-			}
-			if writeErr != nil {
-				return fmt.Errorf("write error: %w", writeErr)
-			}
-			if nr != nw {
-				return io.ErrShortWrite
-			}
-		}
-		if readErr != nil {
-			if readErr == io.EOF {
-				break // Done reading
-			}
-			return fmt.Errorf("read error: %w", readErr)
-		}
+	written, err := d.copyBody(ctx, resp.Body, outFile)
+	if err != nil {
+		return err
 	}
 
 	if err := outFile.Sync(); err != nil {
-		return fmt.Errorf("sync error: %w", err)
+		return types.NewError(types.KindDisk, fmt.Errorf("sync error: %w", err))
 	}
 	if err := outFile.Close(); err != nil {
-		return fmt.Errorf("close error: %w", err)
+		return types.NewError(types.KindDisk, fmt.Errorf("close error: %w", err))
 	}
 
 	// Rename .pulse file to final destination
 	if err := os.Rename(workingPath, destPath); err != nil {
 		// Fallback: copy if rename fails (cross-device)
 		if copyErr := copyFile(workingPath, destPath); copyErr != nil {
-			return fmt.Errorf("failed to finalize file: %w", copyErr)
+			return types.NewError(types.KindDisk, fmt.Errorf("failed to finalize file: %w", copyErr))
 		}
 		os.Remove(workingPath)
 	}
@@ -144,6 +103,77 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	return nil
 }
 
+// Stream downloads the file using a single connection and writes it to w as it arrives
+func (d *SingleDownloader) Stream(ctx context.Context, rawurl string, w io.Writer) error {
+	resp, err := d.request(ctx, rawurl)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = d.copyBody(ctx, resp.Body, w)
+	return err
+}
+
+// request starts the download of the whole file
+func (d *SingleDownloader) request(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
+	types.SetHeaders(req.Header, d.Headers)
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, types.StatusError(resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// copyBody copies the response body to w, updating the progress, and returns the bytes written
+func (d *SingleDownloader) copyBody(ctx context.Context, body io.Reader, w io.Writer) (int64, error) {
+	var written int64
+	buf := make([]byte, d.Runtime.GetWorkerBufferSize())
+
+	for {
+		// Check for context cancellation (allows clean shutdown)
+		select {
+		case <-ctx.Done():
+			// Can't resume - server doesn't support Range requests
+			return written, ctx.Err()
+		default:
+		}
+
+		nr, readErr := body.Read(buf)
+		if nr > 0 {
+			nw, writeErr := w.Write(buf[0:nr])
+			if nw > 0 {
+				written += int64(nw)
+				if d.State != nil {
+					d.State.Downloaded.Store(written)
+				}
+			}
+			if writeErr != nil {
+				return written, types.NewError(types.KindDisk, fmt.Errorf("write error: %w", writeErr))
+			}
+			if nr != nw {
+				return written, io.ErrShortWrite
+			}
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				return written, nil // Done reading
+			}
+			return written, fmt.Errorf("read error: %w", readErr)
+		}
+	}
+}
+
 // copyFile copies a file from src to dst (fallback when rename fails)
 func copyFile(src, dst string) error {
 	in, err := os.Open(src)
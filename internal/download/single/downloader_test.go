@@ -1,6 +1,7 @@
 package single
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"os"
@@ -457,6 +458,33 @@ func TestSingleDownloader_StreamingServer(t *testing.T) {
 	}
 }
 
+func TestSingleDownloader_Stream(t *testing.T) {
+	fileSize := int64(256 * types.KB)
+	server := testutil.NewMockServer(
+		testutil.WithFileSize(fileSize),
+		testutil.WithRangeSupport(false),
+		testutil.WithRandomData(true),
+	)
+	defer server.Close()
+
+	state := types.NewProgressState("stream-out", fileSize)
+	downloader := NewSingleDownloader("stream-out", nil, state, &types.RuntimeConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var out bytes.Buffer
+	if err := downloader.Stream(ctx, server.URL(), &out); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if int64(out.Len()) != fileSize {
+		t.Errorf("Streamed %d bytes, want %d", out.Len(), fileSize)
+	}
+	if state.Downloaded.Load() != fileSize {
+		t.Errorf("Downloaded %d != fileSize %d", state.Downloaded.Load(), fileSize)
+	}
+}
+
 // =============================================================================
 // SingleDownloader - FailAfterBytes
 // =============================================================================
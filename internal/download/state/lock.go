@@ -0,0 +1,179 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// ErrRunningElsewhere is returned by LockDownload when another process is downloading to the
+// same destination
+var ErrRunningElsewhere = errors.New("download is running in another pulse process")
+
+// errLockHeld is returned by tryLock when another handle holds a conflicting lock
+var errLockHeld = errors.New("lock is held")
+
+// LockInfo describes the process holding a download lock
+type LockInfo struct {
+	PID     int    `json:"pid"`
+	Host    string `json:"host"`
+	Started int64  `json:"started"` // Unix timestamp
+}
+
+// DownloadLock marks a download as owned by this process until Unlock is called.
+// The lock is advisory and held by the operating system, so it is released even if the
+// process crashes; the next process to lock the download takes it over.
+type DownloadLock struct {
+	f *os.File
+}
+
+// lockFilePath returns the lock file guarding downloads to destPath
+func lockFilePath(destPath string) string {
+	return filepath.Join(getSurgeDir(), "locks", URLHash(destPath)+".lock")
+}
+
+// idLockFilePath returns the lock file guarding the master list entry of download id
+func idLockFilePath(id string) string {
+	return filepath.Join(getSurgeDir(), "locks", "id-"+URLHash(id)+".lock")
+}
+
+// LockDownload takes ownership of the download to destPath, including its partial file and
+// saved state. It fails with ErrRunningElsewhere if another process owns it.
+func LockDownload(destPath string) (*DownloadLock, error) {
+	return lockAt(lockFilePath(destPath), "download to "+destPath)
+}
+
+// LockDownloadID takes ownership of the master list entry of download id while it is running
+// and has no destination yet. It fails with ErrRunningElsewhere if another process owns it.
+func LockDownloadID(id string) (*DownloadLock, error) {
+	return lockAt(idLockFilePath(id), "download "+id)
+}
+
+// lockAt takes the lock file at path; what names the download in errors and logs
+func lockAt(path, what string) (*DownloadLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(f, true, false); err != nil {
+		owner := readLockInfo(f)
+		f.Close()
+		if !errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("failed to lock %s: %w", what, err)
+		}
+		if owner == nil {
+			return nil, ErrRunningElsewhere
+		}
+		if owner.PID == os.Getpid() {
+			return nil, fmt.Errorf("%s is already running", what)
+		}
+		return nil, fmt.Errorf("%w (pid %d on %s)", ErrRunningElsewhere, owner.PID, owner.Host)
+	}
+
+	// Anything left in the file belongs to a process that stopped without unlocking
+	if prev := readLockInfo(f); prev != nil {
+		utils.Debug("Taking over %s from pid %d, which no longer holds it", what, prev.PID)
+	}
+
+	// Overwrite before truncating, so the file is never empty while a crashed owner's details are replaced
+	host, _ := os.Hostname()
+	data, _ := json.Marshal(LockInfo{PID: os.Getpid(), Host: host, Started: time.Now().Unix()})
+	_, err = f.WriteAt(data, 0)
+	if err == nil {
+		err = f.Truncate(int64(len(data)))
+	}
+	if err != nil {
+		utils.Debug("Failed to record lock owner for %s: %v", what, err)
+	}
+	return &DownloadLock{f: f}, nil
+}
+
+// Unlock gives up ownership of the download
+func (l *DownloadLock) Unlock() error {
+	l.f.Truncate(0) // Only a crashed owner leaves its details behind
+	unlockFile(l.f)
+	return l.f.Close()
+}
+
+// DownloadOwner returns the process downloading to destPath, or nil if no other process is
+func DownloadOwner(destPath string) *LockInfo {
+	return ownerAt(lockFilePath(destPath))
+}
+
+// DownloadIDOwner returns the process running download id, or nil if no other process is
+func DownloadIDOwner(id string) *LockInfo {
+	return ownerAt(idLockFilePath(id))
+}
+
+// ownerLookups is how often ownerAt looks at a held lock without owner details, which it has
+// while the owner is still writing them or already unlocking
+const ownerLookups = 3
+
+// ownerAt returns the process holding the lock file at path, or nil if no other process is.
+// A holder that never records its details is not trusted to be another process.
+func ownerAt(path string) *LockInfo {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	for i := 0; i < ownerLookups; i++ {
+		if i > 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := lockFile(f, false, false); err == nil {
+			unlockFile(f)
+			return nil
+		}
+		owner := readLockInfo(f)
+		if owner == nil || owner.PID == 0 {
+			continue
+		}
+		if owner.PID == os.Getpid() {
+			return nil
+		}
+		return owner
+	}
+	utils.Debug("Lock %s is held without owner details, ignoring it", path)
+	return nil
+}
+
+// readLockInfo returns the owner recorded in a lock file, or nil if there is none
+func readLockInfo(f *os.File) *LockInfo {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 4096))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+// withFileLock runs fn while holding a lock on path, waiting for other processes to release
+// conflicting locks first
+func withFileLock(path string, exclusive bool, fn func() error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f, exclusive, true); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer unlockFile(f)
+	return fn()
+}
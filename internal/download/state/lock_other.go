@@ -0,0 +1,14 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package state
+
+import "os"
+
+// lockFile does nothing on platforms without file locking, leaving processes uncoordinated
+func lockFile(f *os.File, exclusive, wait bool) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
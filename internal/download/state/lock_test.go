@@ -0,0 +1,251 @@
+package state
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+// TestLockHelperProcess holds a download lock for the cross-process tests. It is not a test
+// by itself and only runs when started by them.
+func TestLockHelperProcess(t *testing.T) {
+	dest, id := os.Getenv("PULSE_LOCK_HELPER_DEST"), os.Getenv("PULSE_LOCK_HELPER_ID")
+	var lock *DownloadLock
+	var err error
+	switch {
+	case dest != "":
+		lock, err = LockDownload(dest)
+	case id != "":
+		lock, err = LockDownloadID(id)
+	default:
+		t.Skip("helper process")
+	}
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+	fmt.Println("locked")
+	// Hold the lock until the parent closes stdin
+	bufio.NewReader(os.Stdin).ReadString('\n')
+	lock.Unlock()
+	os.Exit(0)
+}
+
+// startLockHolder starts a process that locks dest and returns a function that stops it
+func startLockHolder(t *testing.T, dest string) (stop func(kill bool)) {
+	t.Helper()
+	return startLockHelper(t, "PULSE_LOCK_HELPER_DEST="+dest)
+}
+
+// startIDLockHolder starts a process that locks download id and returns a function that stops it
+func startIDLockHolder(t *testing.T, id string) (stop func(kill bool)) {
+	t.Helper()
+	return startLockHelper(t, "PULSE_LOCK_HELPER_ID="+id)
+}
+
+func startLockHelper(t *testing.T, env string) (stop func(kill bool)) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	cmd.Env = append(os.Environ(), env)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	if line != "locked\n" {
+		cmd.Process.Kill()
+		cmd.Wait()
+		t.Fatalf("Helper did not take the lock: %q", line)
+	}
+	return func(kill bool) {
+		if kill {
+			cmd.Process.Kill()
+		} else {
+			stdin.Close()
+		}
+		cmd.Wait()
+	}
+}
+
+func TestLockDownload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dest := filepath.Join(t.TempDir(), "file.zip")
+
+	lock, err := LockDownload(dest)
+	if err != nil {
+		t.Fatalf("LockDownload failed: %v", err)
+	}
+	if _, err := LockDownload(dest); err == nil {
+		t.Fatal("Download locked twice")
+	}
+	if owner := DownloadOwner(dest); owner != nil {
+		t.Errorf("Own download reported as running elsewhere: %+v", owner)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	lock, err = LockDownload(dest)
+	if err != nil {
+		t.Fatalf("LockDownload after Unlock failed: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestLockDownload_OtherProcess(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dest := filepath.Join(t.TempDir(), "file.zip")
+
+	stop := startLockHolder(t, dest)
+	defer stop(true)
+
+	if _, err := LockDownload(dest); !errors.Is(err, ErrRunningElsewhere) {
+		t.Fatalf("LockDownload error = %v, want %v", err, ErrRunningElsewhere)
+	}
+	owner := DownloadOwner(dest)
+	if owner == nil {
+		t.Fatal("DownloadOwner found no owner")
+	}
+	if owner.PID == os.Getpid() || owner.PID == 0 {
+		t.Errorf("Owner PID = %d, want the helper's", owner.PID)
+	}
+
+	stop(false)
+	if owner := DownloadOwner(dest); owner != nil {
+		t.Errorf("Released download still owned by %+v", owner)
+	}
+	lock, err := LockDownload(dest)
+	if err != nil {
+		t.Fatalf("LockDownload after release failed: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestRecoverInterrupted_RunningInOtherProcess(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// Both are listed as downloading; only the first is still running elsewhere
+	for _, id := range []string{"running", "crashed"} {
+		if err := MarkDownloading(types.DownloadEntry{ID: id, URL: "https://example.com/" + id}); err != nil {
+			t.Fatalf("MarkDownloading failed: %v", err)
+		}
+	}
+	stop := startIDLockHolder(t, "running")
+	defer stop(true)
+
+	if _, err := LockDownloadID("running"); !errors.Is(err, ErrRunningElsewhere) {
+		t.Fatalf("LockDownloadID error = %v, want %v", err, ErrRunningElsewhere)
+	}
+	if _, err := RecoverInterrupted(); err != nil {
+		t.Fatalf("RecoverInterrupted failed: %v", err)
+	}
+	queued, _ := LoadQueuedDownloads()
+	if len(queued) != 1 || queued[0].ID != "crashed" {
+		t.Errorf("Queued after recovery = %+v, want only the crashed download", queued)
+	}
+	running, _ := loadByStatus("downloading")
+	if len(running) != 1 || running[0].ID != "running" {
+		t.Errorf("Download running elsewhere not left alone: %+v", running)
+	}
+
+	// Once the other process is gone, it is recovered like any other
+	stop(false)
+	if _, err := RecoverInterrupted(); err != nil {
+		t.Fatalf("RecoverInterrupted failed: %v", err)
+	}
+	if queued, _ := LoadQueuedDownloads(); len(queued) != 2 {
+		t.Errorf("Queued after the other process stopped = %+v, want both", queued)
+	}
+}
+
+func TestDownloadOwner_HeldWithoutDetails(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dest := filepath.Join(t.TempDir(), "file.zip")
+	path := lockFilePath(dest)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A holder that has locked the file but not yet written who it is
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := lockFile(f, true, false); err != nil {
+		t.Fatalf("lockFile failed: %v", err)
+	}
+	defer unlockFile(f)
+
+	if owner := DownloadOwner(dest); owner != nil {
+		t.Errorf("Lock without owner details reported as owned by %+v", owner)
+	}
+
+	// Once it has, it is reported
+	f.WriteAt([]byte(`{"pid":12345,"host":"elsewhere"}`), 0)
+	if owner := DownloadOwner(dest); owner == nil || owner.PID != 12345 {
+		t.Errorf("DownloadOwner = %+v, want pid 12345", owner)
+	}
+}
+
+func TestLockDownload_TakesOverFromCrashedProcess(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dest := filepath.Join(t.TempDir(), "file.zip")
+
+	// A killed process leaves its details in the lock file but no lock
+	stop := startLockHolder(t, dest)
+	stop(true)
+	if data, _ := os.ReadFile(lockFilePath(dest)); len(data) == 0 {
+		t.Fatal("Killed helper left no owner details")
+	}
+
+	if owner := DownloadOwner(dest); owner != nil {
+		t.Errorf("Crashed process still reported as owner: %+v", owner)
+	}
+	lock, err := LockDownload(dest)
+	if err != nil {
+		t.Fatalf("LockDownload failed to take over: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestStore_LockedAcrossProcesses(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+
+	// Hold the store lock while another handle tries to take it
+	f, err := os.OpenFile(filepath.Join(dir, storeLockName), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	err = s.View(func(tx Tx) error {
+		if err := lockFile(f, true, false); !errors.Is(err, errLockHeld) {
+			t.Errorf("Exclusive lock during View = %v, want %v", err, errLockHeld)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View failed: %v", err)
+	}
+	if err := lockFile(f, true, false); err != nil {
+		t.Fatalf("Store lock not released after View: %v", err)
+	}
+	unlockFile(f)
+}
@@ -1,22 +1,19 @@
 package state
 
 import (
+	"cmp"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 
 	"github.com/pulse-downloader/pulse/internal/config"
 	"github.com/pulse-downloader/pulse/internal/download/types"
 )
 
-// masterListMu protects concurrent access to the master list file
-var masterListMu sync.Mutex
-
 // URLHash returns a short hash of the URL for master list keying
 // This is used for tracking completed downloads by URL
 func URLHash(url string) string {
@@ -45,6 +42,60 @@ func getSurgeDir() string {
 // SaveState saves download state to global pulse state directory
 // Uses URL+destPath for unique state file naming
 func SaveState(url string, destPath string, state *types.DownloadState) error {
+	if err := SaveCheckpoint(url, destPath, state); err != nil {
+		return err
+	}
+
+	// Also update master list (uses StateHash for unique identification)
+	entry := types.DownloadEntry{
+		ID:        state.ID,
+		URLHash:   state.URLHash,
+		URL:       state.URL,
+		DestPath:  state.DestPath,
+		Filename:  state.Filename,
+		Status:    "paused",
+		TotalSize: state.TotalSize,
+		Headers:   state.Headers,
+		Checksum:  state.Checksum,
+		Mirrors:   state.Mirrors,
+	}
+	_ = markPaused(entry)
+
+	return nil
+}
+
+// markPaused lists entry as paused. What only the master list knows about the download, such
+// as its category, output directory, priority and quality, is kept from the entry it replaces.
+func markPaused(entry types.DownloadEntry) error {
+	return update(func(tx Tx) error {
+		if entry.ID == "" {
+			// Legacy fallback, as in AddToMasterList
+			if same := tx.ByURLHash(entry.URLHash); len(same) > 0 {
+				entry.ID = same[0].ID
+			}
+		}
+		if old, ok := tx.Get(entry.ID); ok {
+			entry.Category = old.Category
+			entry.OutputPath = old.OutputPath
+			entry.Priority = old.Priority
+			entry.Quality = old.Quality
+			entry.TotalSize = cmp.Or(entry.TotalSize, old.TotalSize)
+			if entry.Headers == nil {
+				entry.Headers = old.Headers
+			}
+			entry.Checksum = cmp.Or(entry.Checksum, old.Checksum)
+			if entry.Mirrors == nil {
+				entry.Mirrors = old.Mirrors
+			}
+		}
+		tx.Put(entry)
+		return nil
+	})
+}
+
+// SaveCheckpoint writes the state file of a running download without listing it in the
+// master list. The file is replaced atomically, so a crash leaves either the old or the new state.
+func SaveCheckpoint(url string, destPath string, state *types.DownloadState) error {
 	statePath := getStatePath(url, destPath)
 
 	// Create state directory if it doesn't exist
@@ -64,21 +115,45 @@ func SaveState(url string, destPath string, state *types.DownloadState) error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	if err := os.WriteFile(statePath, data, 0644); err != nil {
+	if err := writeFileAtomic(statePath, data); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
-	// Also update master list (uses StateHash for unique identification)
-	entry := types.DownloadEntry{
-		ID:       state.ID,
-		URLHash:  state.URLHash,
-		URL:      state.URL,
-		DestPath: state.DestPath,
-		Filename: state.Filename,
-		Status:   "paused",
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file and a rename
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
 	}
-	_ = AddToMasterList(entry)
 
+	// Persist the rename itself; directories cannot be synced on every platform
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
 	return nil
 }
 
@@ -115,6 +190,29 @@ func DeleteState(id string, url string, destPath string) error {
 	return nil
 }
 
+// MigrateState moves a saved state from oldURL to newURL for the same destination.
+// The state file is re-keyed (StateHash depends on the URL) and the master list
+// entry is updated so the download resumes from the existing .pulse file.
+func MigrateState(oldURL, newURL, destPath string) error {
+	s, err := LoadState(oldURL, destPath)
+	if err != nil {
+		return err
+	}
+
+	s.URL = newURL
+	if err := SaveState(newURL, destPath, s); err != nil {
+		return err
+	}
+
+	if oldURL != newURL {
+		if err := os.Remove(getStatePath(oldURL, destPath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old state file: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // DeleteStateByURL removes state file by URL and destPath (for TUI delete)
 // This replaces DeleteStateByDir since we now use a global directory
 func DeleteStateByURL(id string, url string, destPath string) error {
@@ -122,138 +220,264 @@ func DeleteStateByURL(id string, url string, destPath string) error {
 }
 
 // ================== Master List Functions ==================
+//
+// The master list lives in a Store (see store.go); each function below is one transaction.
+
+// update runs fn in a transaction on the default store
+func update(fn func(tx Tx) error) error {
+	s, err := getStore()
+	if err != nil {
+		return err
+	}
+	return s.Update(fn)
+}
 
-func getMasterListPath() string {
-	return filepath.Join(getSurgeDir(), "downloads.json")
+// view runs fn in a read-only transaction on the default store
+func view(fn func(tx Tx) error) error {
+	s, err := getStore()
+	if err != nil {
+		return err
+	}
+	return s.View(fn)
 }
 
-// LoadMasterList loads the master downloads list from global state directory
+// LoadMasterList returns every entry in the master list
 func LoadMasterList() (*types.MasterList, error) {
-	path := getMasterListPath()
-
-	data, err := os.ReadFile(path)
+	list := &types.MasterList{}
+	err := view(func(tx Tx) error {
+		list.Downloads = tx.List()
+		return nil
+	})
 	if err != nil {
-		if os.IsNotExist(err) {
-			return &types.MasterList{Downloads: []types.DownloadEntry{}}, nil
-		}
 		return nil, fmt.Errorf("failed to read master list: %w", err)
 	}
-
-	var list types.MasterList
-	if err := json.Unmarshal(data, &list); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal master list: %w", err)
-	}
-
-	return &list, nil
+	return list, nil
 }
 
-// SaveMasterList saves the master downloads list to global state directory
+// SaveMasterList replaces the whole master list with list
 func SaveMasterList(list *types.MasterList) error {
-	pulseDir := getSurgeDir()
-	path := getMasterListPath()
-
-	if err := os.MkdirAll(pulseDir, 0755); err != nil {
-		return fmt.Errorf("failed to create pulse directory: %w", err)
-	}
-
-	data, err := json.MarshalIndent(list, "", "  ")
+	err := update(func(tx Tx) error {
+		for _, e := range tx.List() {
+			tx.Delete(e.ID)
+		}
+		for _, e := range list.Downloads {
+			tx.Put(e)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal master list: %w", err)
-	}
-
-	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write master list: %w", err)
 	}
-
 	return nil
 }
 
 // AddToMasterList adds or updates a download entry in the master list
 func AddToMasterList(entry types.DownloadEntry) error {
-	masterListMu.Lock()
-	defer masterListMu.Unlock()
-
-	list, err := LoadMasterList()
-	if err != nil {
-		list = &types.MasterList{Downloads: []types.DownloadEntry{}}
-	}
-
-	// Update existing or append new
-	found := false
-	for i, e := range list.Downloads {
-		// Match by ID if available
-		if entry.ID != "" && e.ID == entry.ID {
-			list.Downloads[i] = entry
-			found = true
-			break
-		} else if entry.ID == "" && e.URLHash == entry.URLHash {
+	return update(func(tx Tx) error {
+		if entry.ID == "" {
 			// Legacy fallback (should ideally not happen for new entries)
-			list.Downloads[i] = entry
-			found = true
-			break
+			if same := tx.ByURLHash(entry.URLHash); len(same) > 0 {
+				entry.ID = same[0].ID
+			}
 		}
-	}
-	if !found {
-		list.Downloads = append(list.Downloads, entry)
-	}
-
-	return SaveMasterList(list)
+		tx.Put(entry)
+		return nil
+	})
 }
 
 // RemoveFromMasterList removes a download entry from the master list
-// Uses stateHash for unique identification (falls back to URLHash for legacy entries)
 func RemoveFromMasterList(id string) error {
-	masterListMu.Lock()
-	defer masterListMu.Unlock()
+	return update(func(tx Tx) error {
+		tx.Delete(id)
+		return nil
+	})
+}
 
-	list, err := LoadMasterList()
-	if err != nil {
-		return nil // Nothing to remove
-	}
+// GetDownloadEntry returns the master list entry with the given ID, or nil if not found
+func GetDownloadEntry(id string) (*types.DownloadEntry, error) {
+	var found *types.DownloadEntry
+	err := view(func(tx Tx) error {
+		if e, ok := tx.Get(id); ok {
+			found = &e
+		}
+		return nil
+	})
+	return found, err
+}
 
-	// Filter out the entry
-	newDownloads := make([]types.DownloadEntry, 0, len(list.Downloads))
-	for _, e := range list.Downloads {
-		if e.ID == id {
-			continue // Skip this entry (remove it)
+// AppendHookOutput appends captured hook output to a master list entry.
+// Missing entries are ignored since hooks can run for downloads that were never persisted.
+func AppendHookOutput(id string, output string) error {
+	return update(func(tx Tx) error {
+		e, ok := tx.Get(id)
+		if !ok {
+			return nil
 		}
-		newDownloads = append(newDownloads, e)
-	}
-	list.Downloads = newDownloads
+		if e.HookOutput != "" {
+			e.HookOutput += "\n"
+		}
+		e.HookOutput += output
+		tx.Put(e)
+		return nil
+	})
+}
 
-	return SaveMasterList(list)
+// SaveQueue replaces the queued entries in the master list with entries, keeping their order
+func SaveQueue(entries []types.DownloadEntry) error {
+	return update(func(tx Tx) error {
+		for _, e := range tx.ByStatus("queued") {
+			tx.Delete(e.ID)
+		}
+		// Entries are added afresh so they are listed in queue order
+		for _, e := range entries {
+			tx.Delete(e.ID)
+			e.Status = "queued"
+			tx.Put(e)
+		}
+		return nil
+	})
 }
 
-// LoadPausedDownloads returns all paused downloads from the master list
-func LoadPausedDownloads() ([]types.DownloadEntry, error) {
-	list, err := LoadMasterList()
-	if err != nil {
-		return nil, err
-	}
+// MarkFailed records a failed download in the master list with status "error".
+// The destination, size and hook output saved earlier are kept if entry leaves them empty.
+func MarkFailed(entry types.DownloadEntry) error {
+	return update(func(tx Tx) error {
+		entry.Status = "error"
+		if old, ok := tx.Get(entry.ID); ok {
+			entry.DestPath = cmp.Or(entry.DestPath, old.DestPath)
+			entry.TotalSize = cmp.Or(entry.TotalSize, old.TotalSize)
+			entry.HookOutput = cmp.Or(entry.HookOutput, old.HookOutput)
+		}
+		tx.Put(entry)
+		return nil
+	})
+}
 
-	var paused []types.DownloadEntry
-	for _, e := range list.Downloads {
-		if e.Status == "paused" {
-			paused = append(paused, e)
+// ClearFailure forgets the error of a failed download that is being retried. It is listed as
+// paused again, so it is restored like one if pulse stops before the retry finishes. Downloads
+// that failed before writing anything are removed; the queue saves them again.
+func ClearFailure(id string) error {
+	return update(func(tx Tx) error {
+		e, ok := tx.Get(id)
+		if !ok || e.Status != "error" {
+			return nil
 		}
-	}
+		if e.DestPath == "" {
+			tx.Delete(id)
+			return nil
+		}
+		e.Status = "paused"
+		e.Error, e.ErrorKind, e.FailedAt = "", "", 0
+		tx.Put(e)
+		return nil
+	})
+}
 
-	return paused, nil
+// MarkDownloading lists a download that has started in the master list with status
+// "downloading", so it isn't lost if pulse stops before the download saves its state.
+// Pausing, failing or finishing the download replaces or removes the entry. The caller holds
+// LockDownloadID for the download meanwhile so other processes don't recover it.
+func MarkDownloading(entry types.DownloadEntry) error {
+	return update(func(tx Tx) error {
+		entry.Status = "downloading"
+		tx.Put(entry)
+		return nil
+	})
 }
 
-// LoadCompletedDownloads returns all completed downloads from the master list
-func LoadCompletedDownloads() ([]types.DownloadEntry, error) {
-	list, err := LoadMasterList()
+// RecoverInterrupted finds downloads that were still running when pulse last stopped without
+// saving them, for instance after a crash or power loss. Each one with a checkpoint and its
+// partial file still on disk is listed as paused in the master list and returned. Downloads
+// still marked as downloading with nothing to resume from are queued again to start over.
+// Downloads another process is still running are left alone.
+func RecoverInterrupted() ([]types.DownloadEntry, error) {
+	dir := getSurgeDir()
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
 	if err != nil {
 		return nil, err
 	}
 
-	var completed []types.DownloadEntry
-	for _, e := range list.Downloads {
-		if e.Status == "completed" {
-			completed = append(completed, e)
+	var recovered []types.DownloadEntry
+	err = update(func(tx Tx) error {
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			var s types.DownloadState
+			if err := json.Unmarshal(data, &s); err != nil || s.ID == "" || s.DestPath == "" {
+				continue
+			}
+			e, ok := tx.Get(s.ID)
+			if ok && e.Status != "queued" && e.Status != "downloading" {
+				continue
+			}
+			if _, err := os.Stat(s.DestPath + types.IncompleteSuffix); err != nil {
+				continue // Nothing left to resume into
+			}
+			if DownloadOwner(s.DestPath) != nil || DownloadIDOwner(s.ID) != nil {
+				continue // Still running in another process
+			}
+
+			// The options the download was added with are kept
+			entry := e
+			entry.ID = s.ID
+			entry.URLHash = URLHash(s.URL)
+			entry.URL = s.URL
+			entry.DestPath = s.DestPath
+			entry.Filename = s.Filename
+			entry.Status = "paused"
+			entry.TotalSize = s.TotalSize
+			entry.Downloaded = s.Downloaded
+			entry.Headers = s.Headers
+			entry.Checksum = s.Checksum
+			entry.Mirrors = s.Mirrors
+			tx.Put(entry)
+			recovered = append(recovered, entry)
+		}
+
+		for _, e := range tx.ByStatus("downloading") {
+			if DownloadIDOwner(e.ID) != nil || (e.DestPath != "" && DownloadOwner(e.DestPath) != nil) {
+				continue // Still running in another process
+			}
+			e.Status = "queued"
+			tx.Put(e)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return completed, nil
+	return recovered, nil
+}
+
+// loadByStatus returns the master list entries with the given status in list order
+func loadByStatus(status string) ([]types.DownloadEntry, error) {
+	var entries []types.DownloadEntry
+	err := view(func(tx Tx) error {
+		entries = tx.ByStatus(status)
+		return nil
+	})
+	return entries, err
+}
+
+// LoadFailedDownloads returns all failed downloads from the master list
+func LoadFailedDownloads() ([]types.DownloadEntry, error) {
+	return loadByStatus("error")
+}
+
+// LoadQueuedDownloads returns the queued downloads from the master list in start order
+func LoadQueuedDownloads() ([]types.DownloadEntry, error) {
+	return loadByStatus("queued")
+}
+
+// LoadPausedDownloads returns all paused downloads from the master list
+func LoadPausedDownloads() ([]types.DownloadEntry, error) {
+	return loadByStatus("paused")
+}
+
+// LoadCompletedDownloads returns all completed downloads from the master list
+func LoadCompletedDownloads() ([]types.DownloadEntry, error) {
+	return loadByStatus("completed")
 }
@@ -1,6 +1,8 @@
 package state
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/pulse-downloader/pulse/internal/config"
@@ -302,3 +304,321 @@ func TestDuplicateURLStateIsolation(t *testing.T) {
 	DeleteState("id2", testURL, dest2)
 	DeleteState("id3", testURL, dest3)
 }
+
+func TestMigrateState(t *testing.T) {
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	oldURL := "https://old.example.com/migrate-test.zip"
+	newURL := "https://new.example.com/migrate-test.zip"
+	testDestPath := "C:\\Downloads\\migrate-test.zip"
+	original := &types.DownloadState{
+		ID:         "migrate-test-id",
+		URL:        oldURL,
+		DestPath:   testDestPath,
+		TotalSize:  1000000,
+		Downloaded: 400000,
+		Tasks:      []types.Task{{Offset: 400000, Length: 600000}},
+		Filename:   "migrate-test.zip",
+	}
+	if err := SaveState(oldURL, testDestPath, original); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	if err := MigrateState(oldURL, newURL, testDestPath); err != nil {
+		t.Fatalf("MigrateState failed: %v", err)
+	}
+
+	migrated, err := LoadState(newURL, testDestPath)
+	if err != nil {
+		t.Fatalf("LoadState for new URL failed: %v", err)
+	}
+	if migrated.URL != newURL {
+		t.Errorf("URL = %s, want %s", migrated.URL, newURL)
+	}
+	if migrated.Downloaded != 400000 {
+		t.Errorf("Downloaded = %d, want 400000", migrated.Downloaded)
+	}
+	if _, err := LoadState(oldURL, testDestPath); err == nil {
+		t.Error("Old state should be removed after migration")
+	}
+
+	entry, err := GetDownloadEntry("migrate-test-id")
+	if err != nil {
+		t.Fatalf("GetDownloadEntry failed: %v", err)
+	}
+	if entry == nil || entry.URL != newURL {
+		t.Errorf("Master list entry not updated to new URL: %+v", entry)
+	}
+
+	// Cleanup
+	DeleteState("migrate-test-id", newURL, testDestPath)
+}
+
+func TestGetDownloadEntry_NotFound(t *testing.T) {
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	entry, err := GetDownloadEntry("does-not-exist")
+	if err != nil {
+		t.Fatalf("GetDownloadEntry failed: %v", err)
+	}
+	if entry != nil {
+		t.Errorf("Expected nil entry, got %+v", entry)
+	}
+}
+
+func TestAppendHookOutput(t *testing.T) {
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	entry := types.DownloadEntry{
+		ID:       "hook-output-test",
+		URL:      "https://example.com/hook.zip",
+		Filename: "hook.zip",
+		Status:   "completed",
+	}
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+	defer RemoveFromMasterList(entry.ID)
+
+	if err := AppendHookOutput(entry.ID, "hook a: ok"); err != nil {
+		t.Fatalf("AppendHookOutput failed: %v", err)
+	}
+	if err := AppendHookOutput(entry.ID, "hook b: exit status 1"); err != nil {
+		t.Fatalf("AppendHookOutput failed: %v", err)
+	}
+
+	got, err := GetDownloadEntry(entry.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetDownloadEntry failed: %v", err)
+	}
+	if got.HookOutput != "hook a: ok\nhook b: exit status 1" {
+		t.Errorf("HookOutput = %q", got.HookOutput)
+	}
+
+	// Unknown IDs are ignored
+	if err := AppendHookOutput("missing-id", "output"); err != nil {
+		t.Errorf("AppendHookOutput for missing entry returned error: %v", err)
+	}
+}
+
+func TestSaveQueue(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	paused := types.DownloadEntry{ID: "p", URL: "https://example.com/p.zip", Status: "paused"}
+	if err := AddToMasterList(paused); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	first := []types.DownloadEntry{
+		{ID: "a", URL: "https://example.com/a.zip", OutputPath: "/tmp", Quality: "720p", Priority: types.PriorityHigh},
+		{ID: "b", URL: "https://example.com/b.zip"},
+	}
+	if err := SaveQueue(first); err != nil {
+		t.Fatalf("SaveQueue failed: %v", err)
+	}
+	// Saving again replaces the previous queue rather than merging with it
+	if err := SaveQueue([]types.DownloadEntry{first[1], first[0]}); err != nil {
+		t.Fatalf("SaveQueue failed: %v", err)
+	}
+
+	queued, err := LoadQueuedDownloads()
+	if err != nil {
+		t.Fatalf("LoadQueuedDownloads failed: %v", err)
+	}
+	if len(queued) != 2 || queued[0].ID != "b" || queued[1].ID != "a" {
+		t.Fatalf("Unexpected queue: %+v", queued)
+	}
+	if queued[1].Status != "queued" || queued[1].OutputPath != "/tmp" || queued[1].Quality != "720p" || queued[1].Priority != types.PriorityHigh {
+		t.Errorf("Queued entry not round-tripped: %+v", queued[1])
+	}
+
+	if err := SaveQueue(nil); err != nil {
+		t.Fatalf("SaveQueue failed: %v", err)
+	}
+	if queued, _ := LoadQueuedDownloads(); len(queued) != 0 {
+		t.Errorf("Expected empty queue, got %+v", queued)
+	}
+	if entry, _ := GetDownloadEntry("p"); entry == nil || entry.Status != "paused" {
+		t.Errorf("Paused entry was not preserved: %+v", entry)
+	}
+}
+
+func TestSaveState_KeepsListedOptions(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// The running download was listed with the options it was added with
+	url, destPath := "https://example.com/p.iso", "/downloads/isos/p.iso"
+	running := types.DownloadEntry{ID: "p", URL: url, Category: "ISOs", OutputPath: "/downloads/isos", Priority: types.PriorityHigh, Quality: "1080p"}
+	if err := MarkDownloading(running); err != nil {
+		t.Fatalf("MarkDownloading failed: %v", err)
+	}
+	if err := SaveState(url, destPath, &types.DownloadState{ID: "p", URL: url, DestPath: destPath, Filename: "p.iso", TotalSize: 2048}); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	got, err := GetDownloadEntry("p")
+	if err != nil || got == nil {
+		t.Fatalf("GetDownloadEntry = %v, %v", got, err)
+	}
+	if got.Status != "paused" || got.DestPath != destPath || got.TotalSize != 2048 {
+		t.Errorf("Pause not recorded: %+v", got)
+	}
+	if got.Category != "ISOs" || got.OutputPath != "/downloads/isos" || got.Priority != types.PriorityHigh || got.Quality != "1080p" {
+		t.Errorf("Listed options lost on pause: %+v", got)
+	}
+}
+
+func TestMarkFailed(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// A download that stopped mid-way already has a paused entry from SaveState
+	paused := types.DownloadEntry{ID: "f", URL: "https://example.com/f.zip", DestPath: "/downloads/f.zip", Filename: "f.zip", TotalSize: 1000, Status: "paused"}
+	if err := AddToMasterList(paused); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	failure := types.DownloadEntry{
+		ID:         "f",
+		URL:        paused.URL,
+		Filename:   "f.zip",
+		Category:   "Archives",
+		Error:      "unexpected status: 503 Service Unavailable",
+		ErrorKind:  "network",
+		FailedAt:   1700000000,
+		Downloaded: 400,
+	}
+	if err := MarkFailed(failure); err != nil {
+		t.Fatalf("MarkFailed failed: %v", err)
+	}
+	if err := MarkFailed(types.DownloadEntry{ID: "g", URL: "https://example.com/g.zip", Error: "no such host"}); err != nil {
+		t.Fatalf("MarkFailed failed: %v", err)
+	}
+
+	failed, err := LoadFailedDownloads()
+	if err != nil {
+		t.Fatalf("LoadFailedDownloads failed: %v", err)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("Expected 2 failed downloads, got %+v", failed)
+	}
+	got := failed[0]
+	if got.Status != "error" || got.Error != failure.Error || got.ErrorKind != "network" || got.FailedAt != 1700000000 ||
+		got.Downloaded != 400 || got.Category != "Archives" {
+		t.Errorf("Failure not recorded: %+v", got)
+	}
+	if got.DestPath != "/downloads/f.zip" || got.TotalSize != 1000 {
+		t.Errorf("Saved destination and size should be kept: %+v", got)
+	}
+	if paused, _ := LoadPausedDownloads(); len(paused) != 0 {
+		t.Errorf("Failed download still listed as paused: %+v", paused)
+	}
+
+	if err := ClearFailure("f"); err != nil {
+		t.Fatalf("ClearFailure failed: %v", err)
+	}
+	if err := ClearFailure("g"); err != nil {
+		t.Fatalf("ClearFailure failed: %v", err)
+	}
+	if entry, _ := GetDownloadEntry("f"); entry == nil || entry.Status != "paused" || entry.Error != "" {
+		t.Errorf("Retried download with saved progress should be paused again: %+v", entry)
+	}
+	if entry, _ := GetDownloadEntry("g"); entry != nil {
+		t.Errorf("Retried download without progress should be removed: %+v", entry)
+	}
+}
+
+func TestRecoverInterrupted(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	checkpoint := func(id, name string, partial bool) *types.DownloadState {
+		s := &types.DownloadState{
+			ID:         id,
+			URL:        "https://example.com/" + name,
+			DestPath:   filepath.Join(dir, name),
+			Filename:   name,
+			TotalSize:  1000,
+			Downloaded: 600,
+			Tasks:      []types.Task{{Offset: 600, Length: 400}},
+		}
+		if partial {
+			if err := os.WriteFile(s.DestPath+types.IncompleteSuffix, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := SaveCheckpoint(s.URL, s.DestPath, s); err != nil {
+			t.Fatalf("SaveCheckpoint failed: %v", err)
+		}
+		return s
+	}
+
+	crashed := checkpoint("crashed", "crashed.bin", true)
+	checkpoint("cleaned-up", "gone.bin", false) // Partial file deleted since
+	paused := checkpoint("paused", "paused.bin", true)
+	if err := SaveState(paused.URL, paused.DestPath, paused); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	recovered, err := RecoverInterrupted()
+	if err != nil {
+		t.Fatalf("RecoverInterrupted failed: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].ID != "crashed" {
+		t.Fatalf("Expected only the crashed download, got %+v", recovered)
+	}
+	entry, _ := GetDownloadEntry("crashed")
+	if entry == nil || entry.Status != "paused" || entry.DestPath != crashed.DestPath || entry.Downloaded != 600 {
+		t.Errorf("Crashed download not listed as paused: %+v", entry)
+	}
+
+	// Recovery is done once
+	if again, _ := RecoverInterrupted(); len(again) != 0 {
+		t.Errorf("Second recovery found %+v", again)
+	}
+}
+
+func TestRecoverInterrupted_Downloading(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	// Both were running; only one saved a checkpoint before pulse stopped
+	for _, id := range []string{"checkpointed", "probing"} {
+		if err := MarkDownloading(types.DownloadEntry{ID: id, URL: "https://example.com/" + id, Category: "Videos", Priority: types.PriorityHigh}); err != nil {
+			t.Fatalf("MarkDownloading failed: %v", err)
+		}
+	}
+	s := &types.DownloadState{
+		ID:         "checkpointed",
+		URL:        "https://example.com/checkpointed",
+		DestPath:   filepath.Join(dir, "checkpointed.bin"),
+		Filename:   "checkpointed.bin",
+		TotalSize:  1000,
+		Downloaded: 300,
+		Tasks:      []types.Task{{Offset: 300, Length: 700}},
+	}
+	if err := os.WriteFile(s.DestPath+types.IncompleteSuffix, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCheckpoint(s.URL, s.DestPath, s); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	recovered, err := RecoverInterrupted()
+	if err != nil {
+		t.Fatalf("RecoverInterrupted failed: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].ID != "checkpointed" || recovered[0].Status != "paused" ||
+		recovered[0].Category != "Videos" || recovered[0].Downloaded != 300 {
+		t.Errorf("Checkpointed download not recovered as paused with its options: %+v", recovered)
+	}
+	queued, _ := LoadQueuedDownloads()
+	if len(queued) != 1 || queued[0].ID != "probing" || queued[0].Priority != types.PriorityHigh {
+		t.Errorf("Download without a checkpoint not queued again: %+v", queued)
+	}
+}
@@ -0,0 +1,553 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// Store keeps the master list of downloads. Changes are made in transactions that are
+// committed atomically: after a crash either all of a transaction's changes are there or none.
+type Store interface {
+	// View runs fn against the current entries. Changing them from fn is an error.
+	View(fn func(tx Tx) error) error
+	// Update runs fn and commits its changes in a single write. Nothing is changed if fn
+	// returns an error.
+	Update(fn func(tx Tx) error) error
+	// Close releases the store
+	Close() error
+}
+
+// Tx reads and changes the entries of a Store inside View or Update
+type Tx interface {
+	Get(id string) (types.DownloadEntry, bool)
+	// Put adds an entry, or replaces the one with the same ID, which keeps its position
+	Put(entry types.DownloadEntry)
+	Delete(id string)
+	// List returns all entries in the order they were first added
+	List() []types.DownloadEntry
+	// ByStatus returns the entries with the given status, in list order
+	ByStatus(status string) []types.DownloadEntry
+	// ByURLHash returns the entries for the given URL hash, in list order
+	ByURLHash(hash string) []types.DownloadEntry
+}
+
+var errReadOnly = errors.New("state: cannot change entries in a read-only transaction")
+
+const (
+	journalName    = "downloads.journal"
+	storeLockName  = "downloads.lock" // Locked while the journal is read (shared) or written (exclusive)
+	legacyListName = "downloads.json" // Master list before the journal, migrated on first open
+
+	// compactAfter is the number of journal records after which the journal is rewritten
+	// as a single snapshot of the live entries
+	compactAfter = 1000
+)
+
+// journalOp is one change within a committed transaction
+type journalOp struct {
+	Put    *types.DownloadEntry `json:"put,omitempty"`
+	Delete string               `json:"delete,omitempty"`
+}
+
+// storedEntry is an entry with its position in the list
+type storedEntry struct {
+	seq   int64
+	entry types.DownloadEntry
+}
+
+// journalStore is a Store backed by an append-only journal of transactions.
+// Each record is a line holding a CRC-32 checksum and the JSON of the transaction's changes,
+// so a record torn by a crash is detected and dropped on the next load. The journal is
+// compacted into a snapshot once it holds compactAfter records.
+//
+// Processes sharing the directory take turns through a lock file: readers share it and
+// writers hold it alone. Changes made by others are noticed by checking the journal's size
+// and identity before every transaction.
+type journalStore struct {
+	mu       sync.Mutex
+	dir      string
+	path     string
+	lockPath string
+
+	entries   map[string]storedEntry
+	byStatus  map[string]map[string]struct{}
+	byURLHash map[string]map[string]struct{}
+	nextSeq   int64
+
+	file    os.FileInfo // Journal as of the last read, nil to force a full reload
+	offset  int64       // Bytes of the journal applied so far
+	records int         // Records in the journal
+}
+
+// OpenStore opens the store in dir, creating it if needed. A master list left by an earlier
+// version (downloads.json) is imported the first time and kept as downloads.json.bak.
+func OpenStore(dir string) (Store, error) {
+	return openJournalStore(dir)
+}
+
+func openJournalStore(dir string) (*journalStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	s := &journalStore{
+		dir:      dir,
+		path:     filepath.Join(dir, journalName),
+		lockPath: filepath.Join(dir, storeLockName),
+	}
+
+	err := withFileLock(s.lockPath, true, func() error {
+		if err := s.migrate(); err != nil {
+			return err
+		}
+		return s.refresh(true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate imports the legacy JSON master list if there is no journal yet
+func (s *journalStore) migrate() error {
+	if _, err := os.Stat(s.path); err == nil || !os.IsNotExist(err) {
+		return nil
+	}
+	legacy := filepath.Join(s.dir, legacyListName)
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return nil // Nothing to migrate
+	}
+
+	var list types.MasterList
+	if err := json.Unmarshal(data, &list); err != nil {
+		utils.Debug("Not migrating unreadable master list %s: %v", legacy, err)
+		return nil
+	}
+	ops := make([]journalOp, len(list.Downloads))
+	for i := range list.Downloads {
+		ops[i] = journalOp{Put: &list.Downloads[i]}
+	}
+	record, err := encodeRecord(ops)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, record); err != nil {
+		return fmt.Errorf("failed to migrate master list: %w", err)
+	}
+	if err := os.Rename(legacy, legacy+".bak"); err != nil {
+		utils.Debug("Failed to rename migrated master list: %v", err)
+	}
+	utils.Debug("Migrated %d downloads from %s", len(list.Downloads), legacy)
+	return nil
+}
+
+func (s *journalStore) View(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return withFileLock(s.lockPath, false, func() error {
+		if err := s.refresh(false); err != nil {
+			return err
+		}
+		tx := &storeTx{s: s, readOnly: true}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.err
+	})
+}
+
+func (s *journalStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return withFileLock(s.lockPath, true, func() error {
+		// Cut off a torn record before appending, or the next full replay would drop the commit with it
+		if err := s.refresh(true); err != nil {
+			return err
+		}
+
+		// Changes apply to the entries straight away and are undone if the commit fails
+		tx := &storeTx{s: s, seq: s.nextSeq}
+		if err := fn(tx); err != nil {
+			tx.rollback()
+			return err
+		}
+		if len(tx.ops) == 0 {
+			return nil
+		}
+		if err := s.commit(tx.ops); err != nil {
+			tx.rollback()
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *journalStore) Close() error {
+	return nil // The journal is only open while it is read or written
+}
+
+// commit appends a record to the journal and makes it durable
+func (s *journalStore) commit(ops []journalOp) error {
+	record, err := encodeRecord(ops)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	if _, err := f.Write(record); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	info, statErr := f.Stat()
+	f.Close()
+	s.records++
+
+	// If another process appended in between, the offsets no longer line up: reload next time
+	if statErr != nil || s.file == nil || !os.SameFile(info, s.file) || info.Size() != s.offset+int64(len(record)) {
+		s.file = nil
+	} else {
+		s.file = info
+		s.offset = info.Size()
+	}
+
+	if s.records >= compactAfter {
+		if err := s.compact(); err != nil {
+			utils.Debug("Failed to compact journal: %v", err)
+		}
+	}
+	return nil
+}
+
+// compact replaces the journal with a single record holding the live entries
+func (s *journalStore) compact() error {
+	entries := s.all()
+	ops := make([]journalOp, len(entries))
+	for i := range entries {
+		ops[i] = journalOp{Put: &entries[i]}
+	}
+	record, err := encodeRecord(ops)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, record); err != nil {
+		return err
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		s.file = nil
+		return err
+	}
+	s.file = info
+	s.offset = info.Size()
+	s.records = 1
+	return nil
+}
+
+// refresh brings the entries up to date with the journal on disk. With repair, which needs the
+// exclusive lock, a torn record at the end is cut off even when only new records are read.
+func (s *journalStore) refresh(repair bool) error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.reset()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if s.file == nil || !os.SameFile(info, s.file) || info.Size() < s.offset {
+		// New store, compacted by another process, or truncated: read it all again
+		s.reset()
+		return s.replay(info, true)
+	}
+	if info.Size() > s.offset {
+		return s.replay(info, repair)
+	}
+	return nil
+}
+
+func (s *journalStore) reset() {
+	s.entries = make(map[string]storedEntry)
+	s.byStatus = make(map[string]map[string]struct{})
+	s.byURLHash = make(map[string]map[string]struct{})
+	s.nextSeq = 0
+	s.file = nil
+	s.offset = 0
+	s.records = 0
+}
+
+// replay applies the journal records after s.offset. With truncate it also cuts off a record
+// torn by a crash, so later appends are not hidden behind it.
+func (s *journalStore) replay(info os.FileInfo, truncate bool) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+	offset := s.offset
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		ops, decodeErr := decodeRecord(line)
+		if err != nil || decodeErr != nil {
+			utils.Debug("Ignoring damaged journal record at offset %d in %s", offset, s.path)
+			if truncate {
+				if truncErr := os.Truncate(s.path, offset); truncErr == nil {
+					info, _ = os.Stat(s.path)
+				}
+			}
+			break
+		}
+		for _, op := range ops {
+			s.apply(op)
+		}
+		offset += int64(len(line))
+		s.records++
+	}
+
+	s.file = info
+	s.offset = offset
+	return nil
+}
+
+// apply makes one change to the in-memory entries
+func (s *journalStore) apply(op journalOp) {
+	if op.Put != nil {
+		seq := s.nextSeq
+		if old, ok := s.entries[op.Put.ID]; ok {
+			seq = old.seq
+		} else {
+			s.nextSeq++
+		}
+		s.set(op.Put.ID, storedEntry{seq: seq, entry: *op.Put})
+	} else {
+		s.remove(op.Delete)
+	}
+}
+
+// set stores e under id, keeping the indexes up to date
+func (s *journalStore) set(id string, e storedEntry) {
+	s.remove(id)
+	s.entries[id] = e
+	addIndex(s.byStatus, e.entry.Status, id)
+	addIndex(s.byURLHash, e.entry.URLHash, id)
+}
+
+func (s *journalStore) remove(id string) {
+	old, ok := s.entries[id]
+	if !ok {
+		return
+	}
+	delete(s.entries, id)
+	removeIndex(s.byStatus, old.entry.Status, id)
+	removeIndex(s.byURLHash, old.entry.URLHash, id)
+}
+
+// all returns every entry in list order
+func (s *journalStore) all() []types.DownloadEntry {
+	found := make([]storedEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		found = append(found, e)
+	}
+	return sortEntries(found)
+}
+
+// lookup returns the entries with the given IDs in list order
+func (s *journalStore) lookup(ids map[string]struct{}) []types.DownloadEntry {
+	found := make([]storedEntry, 0, len(ids))
+	for id := range ids {
+		found = append(found, s.entries[id])
+	}
+	return sortEntries(found)
+}
+
+func sortEntries(found []storedEntry) []types.DownloadEntry {
+	slices.SortFunc(found, func(a, b storedEntry) int { return cmp.Compare(a.seq, b.seq) })
+
+	entries := make([]types.DownloadEntry, len(found))
+	for i, e := range found {
+		entries[i] = e.entry
+	}
+	return entries
+}
+
+func addIndex(index map[string]map[string]struct{}, key, id string) {
+	ids, ok := index[key]
+	if !ok {
+		ids = make(map[string]struct{})
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removeIndex(index map[string]map[string]struct{}, key, id string) {
+	delete(index[key], id)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+// encodeRecord returns the journal line for a transaction: "<crc32 hex> <json>\n"
+func encodeRecord(ops []journalOp) ([]byte, error) {
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	record := make([]byte, 0, len(data)+10)
+	record = fmt.Appendf(record, "%08x ", crc32.ChecksumIEEE(data))
+	record = append(record, data...)
+	return append(record, '\n'), nil
+}
+
+// decodeRecord parses a journal line, rejecting incomplete or damaged ones
+func decodeRecord(line []byte) ([]journalOp, error) {
+	line, ok := bytes.CutSuffix(line, []byte("\n"))
+	if !ok {
+		return nil, errors.New("incomplete record")
+	}
+	sum, data, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return nil, errors.New("malformed record")
+	}
+	want, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil || uint32(want) != crc32.ChecksumIEEE(data) {
+		return nil, errors.New("checksum mismatch")
+	}
+	var ops []journalOp
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// storeTx is the Tx of a journalStore. Writes are applied to the store at once, recorded
+// for the journal, and undone by rollback.
+type storeTx struct {
+	s        *journalStore
+	readOnly bool
+	err      error
+	ops      []journalOp
+	undo     []undoStep
+	seq      int64 // nextSeq when the transaction started
+}
+
+// undoStep restores an entry as it was before a change
+type undoStep struct {
+	id      string
+	prev    storedEntry
+	existed bool
+}
+
+func (tx *storeTx) Get(id string) (types.DownloadEntry, bool) {
+	e, ok := tx.s.entries[id]
+	return e.entry, ok
+}
+
+func (tx *storeTx) Put(entry types.DownloadEntry) {
+	if tx.readOnly {
+		tx.err = errReadOnly
+		return
+	}
+	prev, existed := tx.s.entries[entry.ID]
+	tx.undo = append(tx.undo, undoStep{id: entry.ID, prev: prev, existed: existed})
+	op := journalOp{Put: &entry}
+	tx.ops = append(tx.ops, op)
+	tx.s.apply(op)
+}
+
+func (tx *storeTx) Delete(id string) {
+	if tx.readOnly {
+		tx.err = errReadOnly
+		return
+	}
+	prev, existed := tx.s.entries[id]
+	if !existed {
+		return
+	}
+	tx.undo = append(tx.undo, undoStep{id: id, prev: prev, existed: true})
+	op := journalOp{Delete: id}
+	tx.ops = append(tx.ops, op)
+	tx.s.apply(op)
+}
+
+func (tx *storeTx) List() []types.DownloadEntry {
+	return tx.s.all()
+}
+
+func (tx *storeTx) ByStatus(status string) []types.DownloadEntry {
+	return tx.s.lookup(tx.s.byStatus[status])
+}
+
+func (tx *storeTx) ByURLHash(hash string) []types.DownloadEntry {
+	return tx.s.lookup(tx.s.byURLHash[hash])
+}
+
+// rollback undoes the transaction's changes, newest first
+func (tx *storeTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		u := tx.undo[i]
+		if u.existed {
+			tx.s.set(u.id, u.prev)
+		} else {
+			tx.s.remove(u.id)
+		}
+	}
+	tx.s.nextSeq = tx.seq
+}
+
+var (
+	defaultStoreMu sync.Mutex
+	defaultStore   *journalStore
+)
+
+// getStore returns the store in the current state directory, opening it on first use.
+// It is reopened if the directory changes, as it does between tests.
+func getStore() (*journalStore, error) {
+	dir := getSurgeDir()
+
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+	if defaultStore == nil || defaultStore.dir != dir {
+		s, err := openJournalStore(dir)
+		if err != nil {
+			return nil, err
+		}
+		defaultStore = s
+	}
+	return defaultStore, nil
+}
+
+// DefaultStore returns the store behind the master list functions of this package
+func DefaultStore() (Store, error) {
+	return getStore()
+}
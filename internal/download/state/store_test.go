@@ -0,0 +1,295 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
+)
+
+func storeIDs(entries []types.DownloadEntry) []string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func equalIDs(got []types.DownloadEntry, want ...string) bool {
+	ids := storeIDs(got)
+	if len(ids) != len(want) {
+		return false
+	}
+	for i := range ids {
+		if ids[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func putAll(t *testing.T, s Store, entries ...types.DownloadEntry) {
+	t.Helper()
+	err := s.Update(func(tx Tx) error {
+		for _, e := range entries {
+			tx.Put(e)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+}
+
+func listAll(t *testing.T, s Store) []types.DownloadEntry {
+	t.Helper()
+	var entries []types.DownloadEntry
+	if err := s.View(func(tx Tx) error {
+		entries = tx.List()
+		return nil
+	}); err != nil {
+		t.Fatalf("View failed: %v", err)
+	}
+	return entries
+}
+
+func TestStore_Transactions(t *testing.T) {
+	s, err := OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	defer s.Close()
+
+	putAll(t, s,
+		types.DownloadEntry{ID: "a", URLHash: "h1", Status: "paused"},
+		types.DownloadEntry{ID: "b", URLHash: "h2", Status: "queued"},
+		types.DownloadEntry{ID: "c", URLHash: "h1", Status: "queued"},
+	)
+
+	// Replacing an entry keeps its position and moves it between indexes
+	putAll(t, s, types.DownloadEntry{ID: "a", URLHash: "h1", Status: "queued"})
+	err = s.View(func(tx Tx) error {
+		if got := tx.List(); !equalIDs(got, "a", "b", "c") {
+			t.Errorf("List = %v, want [a b c]", storeIDs(got))
+		}
+		if got := tx.ByStatus("queued"); !equalIDs(got, "a", "b", "c") {
+			t.Errorf("ByStatus(queued) = %v, want [a b c]", storeIDs(got))
+		}
+		if got := tx.ByStatus("paused"); len(got) != 0 {
+			t.Errorf("ByStatus(paused) = %v, want none", storeIDs(got))
+		}
+		if got := tx.ByURLHash("h1"); !equalIDs(got, "a", "c") {
+			t.Errorf("ByURLHash(h1) = %v, want [a c]", storeIDs(got))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View failed: %v", err)
+	}
+
+	// A failed transaction leaves nothing behind
+	errBoom := errors.New("boom")
+	err = s.Update(func(tx Tx) error {
+		tx.Delete("a")
+		tx.Put(types.DownloadEntry{ID: "d", Status: "paused"})
+		tx.Put(types.DownloadEntry{ID: "b", Status: "error"})
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Update error = %v, want %v", err, errBoom)
+	}
+	err = s.View(func(tx Tx) error {
+		if got := tx.List(); !equalIDs(got, "a", "b", "c") {
+			t.Errorf("List after rollback = %v, want [a b c]", storeIDs(got))
+		}
+		if b, _ := tx.Get("b"); b.Status != "queued" {
+			t.Errorf("b status after rollback = %q, want queued", b.Status)
+		}
+		if got := tx.ByStatus("error"); len(got) != 0 {
+			t.Errorf("ByStatus(error) after rollback = %v, want none", storeIDs(got))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View failed: %v", err)
+	}
+
+	// Deleting and putting again moves an entry to the end
+	err = s.Update(func(tx Tx) error {
+		tx.Delete("a")
+		tx.Put(types.DownloadEntry{ID: "a", Status: "queued"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := listAll(t, s); !equalIDs(got, "b", "c", "a") {
+		t.Errorf("List = %v, want [b c a]", storeIDs(got))
+	}
+
+	// Views cannot write
+	err = s.View(func(tx Tx) error {
+		tx.Delete("a")
+		return nil
+	})
+	if !errors.Is(err, errReadOnly) {
+		t.Errorf("View with a write returned %v, want %v", err, errReadOnly)
+	}
+}
+
+func TestStore_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	putAll(t, s, types.DownloadEntry{ID: "a"}, types.DownloadEntry{ID: "b"}, types.DownloadEntry{ID: "c"})
+	if err := s.Update(func(tx Tx) error {
+		tx.Delete("b")
+		return nil
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	s.Close()
+
+	// A record torn by a crash is dropped, and later commits are not hidden behind it
+	path := filepath.Join(dir, journalName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`1234abcd [{"put":{"id":"torn"`)
+	f.Close()
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	if got := listAll(t, s); !equalIDs(got, "a", "c") {
+		t.Fatalf("List after reopen = %v, want [a c]", storeIDs(got))
+	}
+	putAll(t, s, types.DownloadEntry{ID: "d"})
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	if got := listAll(t, s); !equalIDs(got, "a", "c", "d") {
+		t.Errorf("List after second reopen = %v, want [a c d]", storeIDs(got))
+	}
+}
+
+func TestStore_UpdateCutsTornTail(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	putAll(t, s, types.DownloadEntry{ID: "a"})
+	listAll(t, s) // Up to date, so the next Update only reads what was appended since
+
+	// Another process dies halfway through a record after this store last read the journal
+	f, err := os.OpenFile(filepath.Join(dir, journalName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`1234abcd [{"put":{"id":"torn"`)
+	f.Close()
+
+	putAll(t, s, types.DownloadEntry{ID: "b"})
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	if got := listAll(t, s); !equalIDs(got, "a", "b") {
+		t.Errorf("List after reopen = %v, want [a b]", storeIDs(got))
+	}
+}
+
+func TestStore_Compaction(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	for i := range compactAfter + 10 {
+		putAll(t, s, types.DownloadEntry{ID: "a", Downloaded: int64(i)}, types.DownloadEntry{ID: "b"})
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, journalName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n > 20 {
+		t.Errorf("Journal has %d records after compaction, want a handful", n)
+	}
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	got := listAll(t, s)
+	if !equalIDs(got, "a", "b") {
+		t.Fatalf("List after compaction = %v, want [a b]", storeIDs(got))
+	}
+	if got[0].Downloaded != compactAfter+9 {
+		t.Errorf("a.Downloaded = %d, want %d", got[0].Downloaded, compactAfter+9)
+	}
+}
+
+func TestStore_MigratesJSON(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"downloads":[
+		{"id":"a","url":"https://example.com/a","status":"completed"},
+		{"id":"b","url":"https://example.com/b","status":"paused","dest_path":"/tmp/b"}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, legacyListName), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	got := listAll(t, s)
+	if !equalIDs(got, "a", "b") {
+		t.Fatalf("Migrated list = %v, want [a b]", storeIDs(got))
+	}
+	if got[1].Status != "paused" || got[1].DestPath != "/tmp/b" {
+		t.Errorf("Migrated entry = %+v", got[1])
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, legacyListName)); !os.IsNotExist(err) {
+		t.Errorf("Legacy master list still in place: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacyListName+".bak")); err != nil {
+		t.Errorf("Legacy master list not kept as backup: %v", err)
+	}
+}
+
+func TestStore_SharedBetweenInstances(t *testing.T) {
+	dir := t.TempDir()
+	s1, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	s2, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+
+	putAll(t, s1, types.DownloadEntry{ID: "a"})
+	putAll(t, s2, types.DownloadEntry{ID: "b"})
+	putAll(t, s1, types.DownloadEntry{ID: "c"})
+
+	if got := listAll(t, s2); !equalIDs(got, "a", "b", "c") {
+		t.Errorf("Second instance sees %v, want [a b c]", storeIDs(got))
+	}
+	if got := listAll(t, s1); !equalIDs(got, "a", "b", "c") {
+		t.Errorf("First instance sees %v, want [a b c]", storeIDs(got))
+	}
+}
@@ -1,6 +1,10 @@
 package types
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -32,7 +36,16 @@ const (
 
 // Connection limits
 const (
-	PerHostMax = 64 // Max concurrent connections per host
+	PerHostMax = 64  // Max concurrent connections per host
+	GlobalMax  = 100 // Max concurrent connections across all downloads
+)
+
+// Adaptive scaling constants
+const (
+	ScaleInterval      = 2 * time.Second // How often the connection count is reconsidered
+	ScaleMinGain       = 0.05            // Added connections must raise throughput by at least this fraction to stay
+	ScaleHoldIntervals = 5               // Intervals to wait after a plateau or throttling before adding connections again
+	ScalingTraceSize   = 8               // Scaling decisions kept per download
 )
 
 // HTTP Client Tuning
@@ -59,12 +72,70 @@ type DownloadConfig struct {
 	DestPath   string // Full destination path (for resume state lookup)
 	ID         string
 	Filename   string
-	Quality    string // Desired video quality (e.g., "720p", "1080p")
+	Quality    string            // Desired video quality (e.g., "720p", "1080p")
+	Category   string            // Explicitly selected category; empty lets the category rules decide
+	Priority   Priority          // Position in the pending queue relative to other downloads
+	Headers    map[string]string // Extra request headers, e.g. cookies or authorization
+	Checksum   string            // Expected digest as "algo=hex", checked once the download completes
+	Mirrors    []string          // Other URLs of the same file, tried in order when URL can't be reached
+	Output     io.Writer         // When set, the file is written here in order instead of saved to OutputPath
 	Verbose    bool
 	IsResume   bool // True if this is explicitly a resume, not a fresh download
+	Continue   bool // Continue a partial download of the same URL at the destination instead of starting a new file
+	Attempt    int  // Automatic retries made so far after the download failed
 	ProgressCh chan<- tea.Msg
 	State      *ProgressState
 	Runtime    *RuntimeConfig // Dynamic settings from user config
+	Live       *LiveRuntime   // Current settings, followed while the download runs; takes precedence over Runtime
+}
+
+// RuntimeConfig returns the live settings if the download follows them, otherwise the snapshot in Runtime
+func (c DownloadConfig) RuntimeConfig() *RuntimeConfig {
+	if c.Live != nil {
+		return c.Live.Get()
+	}
+	return c.Runtime
+}
+
+// SetHeaders adds extra request headers to h, replacing any with the same name
+func SetHeaders(h http.Header, headers map[string]string) {
+	for name, value := range headers {
+		h.Set(name, value)
+	}
+}
+
+// Priority orders pending downloads; higher priorities start first
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+func (p Priority) String() string {
+	switch {
+	case p > PriorityNormal:
+		return "high"
+	case p < PriorityNormal:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// ParsePriority parses "high", "normal" or "low" (case-insensitive); empty means normal
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "high":
+		return PriorityHigh, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "low":
+		return PriorityLow, nil
+	default:
+		return PriorityNormal, fmt.Errorf("invalid priority %q (want high, normal or low)", s)
+	}
 }
 
 // RuntimeConfig holds dynamic settings that can override defaults
@@ -81,6 +152,21 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+	DownloadRetries       int           // Automatic retries of a failed download; 0 disables them
+	DownloadRetryDelay    time.Duration // Wait before the first automatic retry
+	Categories            []CategoryRule
+}
+
+// CategoryRule routes matching downloads into a destination folder.
+// Mirrors config.CategoryRule so the two convert directly.
+type CategoryRule struct {
+	Name             string
+	Extensions       []string
+	MIMETypes        []string
+	Hosts            []string
+	URLPattern       string
+	Dir              string
+	FilenameTemplate string
 }
 
 // GetUserAgent returns the configured user agent or the default
@@ -99,6 +185,14 @@ func (r *RuntimeConfig) GetMaxConnectionsPerHost() int {
 	return r.MaxConnectionsPerHost
 }
 
+// GetMaxGlobalConnections returns configured value or default
+func (r *RuntimeConfig) GetMaxGlobalConnections() int {
+	if r == nil || r.MaxGlobalConnections <= 0 {
+		return GlobalMax
+	}
+	return r.MaxGlobalConnections
+}
+
 // GetMinChunkSize returns configured value or default
 func (r *RuntimeConfig) GetMinChunkSize() int64 {
 	if r == nil || r.MinChunkSize <= 0 {
@@ -135,13 +229,29 @@ const (
 	MaxTaskRetries = 3
 	RetryBaseDelay = 200 * time.Millisecond
 
+	// Failure policy
+	DownloadFailureBudget = 30               // Transient request failures before a download gives up
+	DownloadRetries       = 3                // Automatic retries of a failed download
+	DownloadRetryDelay    = 10 * time.Second // Wait before the first automatic retry; doubles for each further one
+	MaxDownloadRetryDelay = 10 * time.Minute // Longest wait between automatic retries
+
+	// Checkpointing
+	CheckpointInterval = 5 * time.Second // Longest time between checkpoints of a running download
+	CheckpointBytes    = 64 * MB         // Checkpoint sooner once this much more has been written
+
+	// Rate limiting constants
+	RateLimitBaseDelay = 1 * time.Second // First per-host back-off when the server sends no Retry-After
+	RateLimitMaxDelay  = 5 * time.Minute // Longest back-off honoured, even if Retry-After asks for more
+
 	// Health check constants
-	HealthCheckInterval = 1 * time.Second // How often to check worker health
-	SlowWorkerThreshold = 0.50            // Restart if speed < x times of mean
-	SlowWorkerGrace     = 5 * time.Second // Grace period before checking speed
-	StallTimeout        = 5 * time.Second // Restart if no data for x seconds
-	SpeedEMAAlpha       = 0.3             // EMA smoothing factor
-	MinAbsoluteSpeed    = 100 * KB        // Don't cancel workers above this speed
+	HealthCheckInterval = 1 * time.Second  // How often to check worker health
+	SlowWorkerThreshold = 0.50             // Restart if speed < x times of mean
+	SlowWorkerGrace     = 5 * time.Second  // Grace period before checking speed
+	StallTimeout        = 5 * time.Second  // Restart if no data for x seconds
+	FirstByteTimeout    = 30 * time.Second // Restart if a new connection has received nothing for x seconds
+	SpeedEMAAlpha       = 0.3              // EMA smoothing factor
+	MinAbsoluteSpeed    = 100 * KB         // Don't cancel workers above this speed
+	HostErrorThreshold  = 5                // Consecutive failed requests before a host is reported unhealthy
 )
 
 // GetMaxTaskRetries returns configured value or default
@@ -183,3 +293,20 @@ func (r *RuntimeConfig) GetSpeedEmaAlpha() float64 {
 	}
 	return r.SpeedEmaAlpha
 }
+
+// GetDownloadRetries returns the configured number of automatic retries, or the default when
+// there is no config. Unlike the other getters, zero is a valid setting that disables retries.
+func (r *RuntimeConfig) GetDownloadRetries() int {
+	if r == nil {
+		return DownloadRetries
+	}
+	return max(0, r.DownloadRetries)
+}
+
+// GetDownloadRetryDelay returns configured value or default
+func (r *RuntimeConfig) GetDownloadRetryDelay() time.Duration {
+	if r == nil || r.DownloadRetryDelay <= 0 {
+		return DownloadRetryDelay
+	}
+	return r.DownloadRetryDelay
+}
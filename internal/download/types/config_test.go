@@ -430,3 +430,46 @@ func TestRuntimeConfig_FullConfiguration(t *testing.T) {
 		t.Error("SpeedEmaAlpha mismatch")
 	}
 }
+
+func TestLiveRuntime(t *testing.T) {
+	live := NewLiveRuntime(&RuntimeConfig{MaxConnectionsPerHost: 2})
+	changes, unsubscribe := live.Subscribe()
+
+	// Several changes before the subscriber looks collapse into one signal
+	live.Set(&RuntimeConfig{MaxConnectionsPerHost: 4})
+	live.Set(&RuntimeConfig{MaxConnectionsPerHost: 8})
+	select {
+	case <-changes:
+	default:
+		t.Fatal("Expected a change signal")
+	}
+	select {
+	case <-changes:
+		t.Fatal("Expected a single pending signal")
+	default:
+	}
+	if got := live.Get().GetMaxConnectionsPerHost(); got != 8 {
+		t.Errorf("Get() = %d connections, want 8", got)
+	}
+
+	unsubscribe()
+	live.Set(&RuntimeConfig{})
+	select {
+	case <-changes:
+		t.Error("Unsubscribed channel was signalled")
+	default:
+	}
+
+	var nilLive *LiveRuntime
+	if nilLive.Get() != nil {
+		t.Error("nil LiveRuntime should return a nil config")
+	}
+	cfg := DownloadConfig{Runtime: &RuntimeConfig{UserAgent: "snapshot"}}
+	if cfg.RuntimeConfig().UserAgent != "snapshot" {
+		t.Error("Expected the snapshot without a live config")
+	}
+	cfg.Live = NewLiveRuntime(&RuntimeConfig{UserAgent: "live"})
+	if cfg.RuntimeConfig().UserAgent != "live" {
+		t.Error("Expected the live config to take precedence")
+	}
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// ErrorKind classifies why a request or download failed
+type ErrorKind string
+
+const (
+	KindNetwork   ErrorKind = "network"   // Transient: timeouts, resets, rate limits and 5xx responses
+	KindHTTP      ErrorKind = "http"      // Permanent HTTP status such as 403, 404 or 410
+	KindDisk      ErrorKind = "disk"      // Writing the file failed: disk full, permissions, read-only filesystem
+	KindIntegrity ErrorKind = "integrity" // The data no longer matches: size or ETag changed, range ignored
+	KindCancelled ErrorKind = "cancelled" // Stopped by the user or on shutdown
+	KindLocked    ErrorKind = "locked"    // Another pulse process is already downloading to the same file
+	KindConfig    ErrorKind = "config"    // The download as requested is invalid: bad URL, unknown category, bad checksum
+	KindOther     ErrorKind = "other"     // Anything not recognised above, treated as permanent
+)
+
+// Transient reports whether retrying the same request may succeed
+func (k ErrorKind) Transient() bool {
+	return k == KindNetwork
+}
+
+// DownloadError attaches an ErrorKind to an error
+type DownloadError struct {
+	Kind   ErrorKind
+	Status int // HTTP status code, if the error came from a response
+	Err    error
+}
+
+func (e *DownloadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// NewError wraps err with kind. It returns nil if err is nil.
+func NewError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &DownloadError{Kind: kind, Err: err}
+}
+
+// StatusError returns the error for an unexpected HTTP status. Client errors are permanent,
+// except 408 and 429, which like server errors are worth retrying.
+func StatusError(code int) error {
+	kind := KindNetwork
+	if code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests {
+		kind = KindHTTP
+	}
+	return &DownloadError{
+		Kind:   kind,
+		Status: code,
+		Err:    fmt.Errorf("unexpected status: %d %s", code, http.StatusText(code)),
+	}
+}
+
+// Classify returns the kind of err, looking through wrapped errors. It returns "" for nil.
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+
+	var de *DownloadError
+	if errors.As(err, &de) {
+		return de.Kind
+	}
+
+	// Requests wrap every failure in a url.Error, which counts as a net.Error even for a
+	// malformed URL; what it wraps decides
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && !urlErr.Timeout() {
+		return Classify(urlErr.Err)
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return KindCancelled
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT), errors.Is(err, syscall.EROFS),
+		errors.Is(err, fs.ErrPermission):
+		return KindDisk
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ETIMEDOUT), errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH), errors.As(err, &netErr):
+		return KindNetwork
+	}
+	return KindOther
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		code int
+		want ErrorKind
+	}{
+		{http.StatusNotFound, KindHTTP},
+		{http.StatusForbidden, KindHTTP},
+		{http.StatusGone, KindHTTP},
+		{http.StatusRequestTimeout, KindNetwork},
+		{http.StatusTooManyRequests, KindNetwork},
+		{http.StatusInternalServerError, KindNetwork},
+		{http.StatusBadGateway, KindNetwork},
+	}
+	for _, tt := range tests {
+		err := StatusError(tt.code)
+		if kind := Classify(err); kind != tt.want {
+			t.Errorf("StatusError(%d) kind = %s, want %s", tt.code, kind, tt.want)
+		}
+		var de *DownloadError
+		if !errors.As(err, &de) || de.Status != tt.code {
+			t.Errorf("StatusError(%d) does not carry its status", tt.code)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"nil", nil, ""},
+		{"wrapped kind", fmt.Errorf("task: %w", NewError(KindIntegrity, errors.New("ETag changed"))), KindIntegrity},
+		{"cancelled", fmt.Errorf("stopping: %w", context.Canceled), KindCancelled},
+		{"deadline", context.DeadlineExceeded, KindNetwork},
+		{"disk full", &os.PathError{Op: "write", Path: "f", Err: syscall.ENOSPC}, KindDisk},
+		{"permission", &os.PathError{Op: "open", Path: "f", Err: os.ErrPermission}, KindDisk},
+		{"reset", fmt.Errorf("read error: %w", syscall.ECONNRESET), KindNetwork},
+		{"short body", io.ErrUnexpectedEOF, KindNetwork},
+		{"request closed", &url.Error{Op: "Get", URL: "https://example.com", Err: io.EOF}, KindNetwork},
+		{"refused", &url.Error{Op: "Get", URL: "https://example.com", Err: syscall.ECONNREFUSED}, KindNetwork},
+		{"malformed URL", &url.Error{Op: "Get", URL: "ftp://example.com", Err: errors.New(`unsupported protocol scheme "ftp"`)}, KindOther},
+		{"config", NewError(KindConfig, errors.New("unknown category")), KindConfig},
+		{"unknown", errors.New("something odd"), KindOther},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("%s: Classify = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if NewError(KindDisk, nil) != nil {
+		t.Error("NewError(nil) should be nil")
+	}
+	if !KindNetwork.Transient() || KindHTTP.Transient() || KindDisk.Transient() || KindConfig.Transient() || KindOther.Transient() {
+		t.Error("Transient() misclassifies kinds")
+	}
+}
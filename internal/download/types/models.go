@@ -16,8 +16,14 @@ type DownloadState struct {
 	Downloaded int64  `json:"downloaded"`
 	Tasks      []Task `json:"tasks"` // Remaining tasks
 	Filename   string `json:"filename"`
-	CreatedAt  int64  `json:"created_at"` // Unix timestamp
-	PausedAt   int64  `json:"paused_at"`  // Unix timestamp
+	ETag       string `json:"etag,omitempty"` // Server ETag at download time (for URL replacement checks)
+	CreatedAt  int64  `json:"created_at"`     // Unix timestamp
+	PausedAt   int64  `json:"paused_at"`      // Unix timestamp
+
+	// Request options, kept so a resumed download is fetched and checked the same way
+	Headers  map[string]string `json:"headers,omitempty"`
+	Checksum string            `json:"checksum,omitempty"`
+	Mirrors  []string          `json:"mirrors,omitempty"` // The other URLs of the file; URL is the one in use
 }
 
 // DownloadEntry represents a download in the master list
@@ -27,10 +33,28 @@ type DownloadEntry struct {
 	URL         string `json:"url"`
 	DestPath    string `json:"dest_path"`
 	Filename    string `json:"filename"`
-	Status      string `json:"status"`       // "paused", "completed", "error"
-	TotalSize   int64  `json:"total_size"`   // File size in bytes
-	CompletedAt int64  `json:"completed_at"` // Unix timestamp when completed
-	TimeTaken   int64  `json:"time_taken"`   // Duration in milliseconds (for completed)
+	Category    string `json:"category,omitempty"`
+	Status      string `json:"status"`                // "queued", "downloading", "paused", "completed", "error"
+	TotalSize   int64  `json:"total_size"`            // File size in bytes
+	CompletedAt int64  `json:"completed_at"`          // Unix timestamp when completed
+	TimeTaken   int64  `json:"time_taken"`            // Duration in milliseconds (for completed)
+	HookOutput  string `json:"hook_output,omitempty"` // Captured output of post-download hooks
+
+	// Failed downloads keep why and when they stopped, and how far they got
+	Error      string `json:"error,omitempty"`
+	ErrorKind  string `json:"error_kind,omitempty"`
+	FailedAt   int64  `json:"failed_at,omitempty"` // Unix timestamp
+	Downloaded int64  `json:"downloaded,omitempty"`
+
+	// Queued downloads have not been probed yet, so everything needed to start them is kept
+	OutputPath string   `json:"output_path,omitempty"`
+	Quality    string   `json:"quality,omitempty"`
+	Priority   Priority `json:"priority,omitempty"`
+
+	// Extra request headers, expected digest and mirror URLs given when the download was added
+	Headers  map[string]string `json:"headers,omitempty"`
+	Checksum string            `json:"checksum,omitempty"`
+	Mirrors  []string          `json:"mirrors,omitempty"`
 }
 
 // MasterList holds all tracked downloads
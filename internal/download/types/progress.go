@@ -18,8 +18,20 @@ type ProgressState struct {
 	Paused        atomic.Bool
 	CancelFunc    context.CancelFunc
 
-	SessionStartBytes int64      // SessionStartBytes tracks how many bytes were already downloaded when the current session started
-	mu                sync.Mutex // Protects TotalSize, StartTime, SessionStartBytes
+	SessionStartBytes int64             // SessionStartBytes tracks how many bytes were already downloaded when the current session started
+	scaling           []ScalingDecision // Most recent connection count changes, oldest first
+	destPath          string            // Final file path, known once the download has started
+	category          string            // Category the download was filed under, if any
+	mu                sync.Mutex        // Protects TotalSize, StartTime, SessionStartBytes, scaling, destPath, category
+}
+
+// ScalingDecision records one change to the number of connections of a download
+type ScalingDecision struct {
+	Time   time.Time
+	From   int
+	To     int
+	Speed  float64 // Aggregate throughput when the decision was made, in bytes/s (0 if unknown)
+	Reason string
 }
 
 func NewProgressState(id string, totalSize int64) *ProgressState {
@@ -42,6 +54,11 @@ func (ps *ProgressState) SetError(err error) {
 	ps.Error.Store(&err)
 }
 
+// ClearError forgets a previous error before the download is retried
+func (ps *ProgressState) ClearError() {
+	ps.Error.Store(nil)
+}
+
 func (ps *ProgressState) GetError() error {
 	if e := ps.Error.Load(); e != nil {
 		return *e
@@ -61,6 +78,51 @@ func (ps *ProgressState) GetProgress() (downloaded int64, total int64, elapsed t
 	return
 }
 
+// SetDestPath records where the download is being written
+func (ps *ProgressState) SetDestPath(path string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.destPath = path
+}
+
+// DestPath returns the final file path, or "" if the download has not started yet
+func (ps *ProgressState) DestPath() string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.destPath
+}
+
+// SetCategory records the category chosen for the download
+func (ps *ProgressState) SetCategory(category string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.category = category
+}
+
+// Category returns the category chosen for the download, or "" if it has none
+func (ps *ProgressState) Category() string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.category
+}
+
+// AddScalingDecision appends to the scaling trace, keeping the last ScalingTraceSize decisions
+func (ps *ProgressState) AddScalingDecision(d ScalingDecision) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.scaling = append(ps.scaling, d)
+	if len(ps.scaling) > ScalingTraceSize {
+		ps.scaling = ps.scaling[len(ps.scaling)-ScalingTraceSize:]
+	}
+}
+
+// ScalingTrace returns a copy of the recent scaling decisions, oldest first
+func (ps *ProgressState) ScalingTrace() []ScalingDecision {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return append([]ScalingDecision(nil), ps.scaling...)
+}
+
 func (ps *ProgressState) Pause() {
 	ps.Paused.Store(true)
 	if ps.CancelFunc != nil {
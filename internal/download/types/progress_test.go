@@ -166,3 +166,29 @@ func TestProgressState_Done(t *testing.T) {
 		t.Error("Should be done after setting")
 	}
 }
+
+func TestProgressState_ScalingTrace(t *testing.T) {
+	ps := NewProgressState("test-id-1", 1000)
+
+	if len(ps.ScalingTrace()) != 0 {
+		t.Fatal("New ProgressState should have an empty scaling trace")
+	}
+
+	for i := 0; i < ScalingTraceSize+3; i++ {
+		ps.AddScalingDecision(ScalingDecision{From: i, To: i + 1})
+	}
+
+	trace := ps.ScalingTrace()
+	if len(trace) != ScalingTraceSize {
+		t.Fatalf("Trace length = %d, want %d", len(trace), ScalingTraceSize)
+	}
+	if trace[0].From != 3 || trace[len(trace)-1].From != ScalingTraceSize+2 {
+		t.Errorf("Trace should keep the newest decisions, got %d..%d", trace[0].From, trace[len(trace)-1].From)
+	}
+
+	// The returned slice is a copy
+	trace[0].Reason = "modified"
+	if ps.ScalingTrace()[0].Reason != "" {
+		t.Error("Modifying the returned trace changed the state")
+	}
+}
@@ -0,0 +1,55 @@
+package types
+
+import "sync"
+
+// LiveRuntime holds the current RuntimeConfig and tells running downloads when it changes.
+// Set replaces the whole config, so a value returned by Get is never modified afterwards.
+type LiveRuntime struct {
+	mu   sync.RWMutex
+	cfg  *RuntimeConfig
+	subs map[chan struct{}]struct{}
+}
+
+// NewLiveRuntime creates a LiveRuntime starting from cfg
+func NewLiveRuntime(cfg *RuntimeConfig) *LiveRuntime {
+	return &LiveRuntime{cfg: cfg, subs: make(map[chan struct{}]struct{})}
+}
+
+// Get returns the current config. It is safe to call on a nil LiveRuntime, which returns nil
+// (the RuntimeConfig getters then fall back to the defaults).
+func (l *LiveRuntime) Get() *RuntimeConfig {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.cfg
+}
+
+// Set replaces the current config and notifies every subscriber
+func (l *LiveRuntime) Set(cfg *RuntimeConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	for ch := range l.subs {
+		// Subscribers only need to know that something changed, so a pending signal is enough
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel that receives a signal after each Set, and a function that
+// ends the subscription. Several changes in quick succession may arrive as one signal.
+func (l *LiveRuntime) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subs, ch)
+		l.mu.Unlock()
+	}
+}
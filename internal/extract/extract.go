@@ -0,0 +1,408 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ulikunitz/xz"
+
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// Format identifies a supported archive type
+type Format string
+
+const (
+	FormatZip   Format = "zip"
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+	FormatTarBz Format = "tar.bz2"
+	FormatTarXz Format = "tar.xz"
+)
+
+// suffixes maps file name suffixes to formats; longer suffixes are checked first
+var suffixes = []struct {
+	suffix string
+	format Format
+}{
+	{".tar.gz", FormatTarGz},
+	{".tar.bz2", FormatTarBz},
+	{".tar.xz", FormatTarXz},
+	{".tgz", FormatTarGz},
+	{".tbz2", FormatTarBz},
+	{".tbz", FormatTarBz},
+	{".txz", FormatTarXz},
+	{".tar", FormatTar},
+	{".zip", FormatZip},
+}
+
+// ErrUnsafePath is returned when an archive entry would be written outside the destination
+var ErrUnsafePath = errors.New("archive entry escapes destination")
+
+// Detect returns the archive format of path based on its name, or "" if unsupported
+func Detect(path string) Format {
+	name := strings.ToLower(filepath.Base(path))
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s.suffix) && len(name) > len(s.suffix) {
+			return s.format
+		}
+	}
+	return ""
+}
+
+// IsArchive reports whether path names a supported archive
+func IsArchive(path string) bool {
+	return Detect(path) != ""
+}
+
+// TargetDir returns a directory next to the archive, named after it without the
+// archive suffix. A numbered suffix is added if that name is already taken.
+func TargetDir(archivePath string) string {
+	dir := filepath.Dir(archivePath)
+	name := filepath.Base(archivePath)
+	lower := strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(lower, s.suffix) && len(name) > len(s.suffix) {
+			name = name[:len(name)-len(s.suffix)]
+			break
+		}
+	}
+
+	target := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			return target
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s(%d)", name, i))
+	}
+}
+
+// Progress tracks extraction progress and is safe to read while Extract runs
+type Progress struct {
+	done  atomic.Int64
+	total atomic.Int64
+	files atomic.Int64
+}
+
+// Fraction returns the completed fraction in the range [0, 1]
+func (p *Progress) Fraction() float64 {
+	total := p.total.Load()
+	if total <= 0 {
+		return 0
+	}
+	f := float64(p.done.Load()) / float64(total)
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+// Files returns the number of entries extracted so far
+func (p *Progress) Files() int64 {
+	return p.files.Load()
+}
+
+// Extract unpacks the archive at archivePath into destDir, which must not exist yet.
+// On failure the partially extracted directory is removed. p may be nil.
+func Extract(ctx context.Context, archivePath, destDir string, p *Progress) error {
+	if p == nil {
+		p = &Progress{}
+	}
+	format := Detect(archivePath)
+	if format == "" {
+		return fmt.Errorf("unsupported archive: %s", filepath.Base(archivePath))
+	}
+
+	destDir, err := filepath.Abs(destDir)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", destDir, err)
+	}
+
+	if format == FormatZip {
+		err = extractZip(ctx, archivePath, destDir, p)
+	} else {
+		err = extractTar(ctx, archivePath, format, destDir, p)
+	}
+	if err == nil {
+		err = verifyLinks(destDir)
+	}
+	if err != nil {
+		if rmErr := os.RemoveAll(destDir); rmErr != nil {
+			utils.Debug("Failed to clean up %s: %v", destDir, rmErr)
+		}
+		return err
+	}
+
+	utils.Debug("Extracted %d entries from %s to %s", p.Files(), archivePath, destDir)
+	return nil
+}
+
+func extractZip(ctx context.Context, archivePath, destDir string, p *Progress) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer zr.Close()
+
+	var total int64
+	for _, f := range zr.File {
+		total += int64(f.UncompressedSize64)
+	}
+	p.total.Store(total)
+
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		target, err := safeJoin(destDir, f.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(target, 0755)
+		case mode&os.ModeSymlink != 0:
+			err = extractZipSymlink(f, destDir, target)
+		default:
+			err = extractZipFile(ctx, f, target, p)
+		}
+		if err != nil {
+			return err
+		}
+		p.files.Add(1)
+	}
+	return nil
+}
+
+func extractZipFile(ctx context.Context, f *zip.File, target string, p *Progress) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	return writeFile(target, f.Mode().Perm(), &progressReader{ctx: ctx, r: rc, n: &p.done})
+}
+
+func extractZipSymlink(f *zip.File, destDir, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	linkname, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return writeSymlink(destDir, target, string(linkname))
+}
+
+func extractTar(ctx context.Context, archivePath string, format Format, destDir string, p *Progress) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Progress follows the compressed bytes consumed, so the total is known up front
+	if info, err := file.Stat(); err == nil {
+		p.total.Store(info.Size())
+	}
+	var r io.Reader = &progressReader{ctx: ctx, r: file, n: &p.done}
+
+	switch format {
+	case FormatTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case FormatTarBz:
+		r = bzip2.NewReader(r)
+	case FormatTarXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to open xz stream: %w", err)
+		}
+		r = xr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			// Drain trailing padding so compressed streams are checksummed and progress completes
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				return fmt.Errorf("failed to read archive trailer: %w", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		target, err := safeJoin(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeFile(target, hdr.FileInfo().Mode().Perm(), tr)
+		case tar.TypeSymlink:
+			err = writeSymlink(destDir, target, hdr.Linkname)
+		case tar.TypeLink:
+			err = writeHardlink(destDir, target, hdr.Linkname)
+		default:
+			// Devices, FIFOs and other special files are never created
+			utils.Debug("Skipping tar entry %s (type %c)", hdr.Name, hdr.Typeflag)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		p.files.Add(1)
+	}
+}
+
+// safeJoin resolves an archive entry name inside destDir, rejecting absolute
+// paths, names that climb out of it (zip slip) and paths that pass through a
+// symlink created by an earlier entry.
+func safeJoin(destDir, name string) (string, error) {
+	clean := strings.ReplaceAll(name, `\`, "/")
+	if clean == "" || path.IsAbs(clean) || filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	target := filepath.Join(destDir, filepath.FromSlash(clean))
+	if !within(destDir, target) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	rel, _ := filepath.Rel(destDir, target)
+	current := destDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			break // Nothing below a missing component exists yet
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s passes through a symlink", ErrUnsafePath, name)
+		}
+	}
+	return target, nil
+}
+
+// writeSymlink creates a symlink if its target lexically stays inside destDir.
+// Links are re-checked against the real filesystem once extraction finishes.
+func writeSymlink(destDir, target, linkname string) error {
+	if linkname == "" || filepath.IsAbs(linkname) || path.IsAbs(linkname) {
+		return fmt.Errorf("%w: link %s -> %s", ErrUnsafePath, target, linkname)
+	}
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+	if !within(destDir, resolved) {
+		return fmt.Errorf("%w: link %s -> %s", ErrUnsafePath, target, linkname)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(linkname, target)
+}
+
+// writeHardlink links target to an earlier entry of the same archive
+func writeHardlink(destDir, target, linkname string) error {
+	src, err := safeJoin(destDir, linkname)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Link(src, target)
+}
+
+// verifyLinks checks that every symlink under destDir resolves inside it.
+// Dangling links point nowhere and are left alone.
+func verifyLinks(destDir string) error {
+	realDest, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(destDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		resolved, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return nil
+		}
+		if !within(realDest, resolved) {
+			return fmt.Errorf("%w: link %s resolves to %s", ErrUnsafePath, p, resolved)
+		}
+		return nil
+	})
+}
+
+func writeFile(target string, perm os.FileMode, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return out.Close()
+}
+
+func within(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// progressReader counts bytes read and stops early once ctx is cancelled
+type progressReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   *atomic.Int64
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	if err := pr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := pr.r.Read(b)
+	pr.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,266 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// tarBz2Fixture is a tar.bz2 holding dir/hello.txt ("hello"); the stdlib cannot write bzip2
+const tarBz2Fixture = "QlpoOTFBWSZTWRy48j8AAJP7hMmAAEhAAf+ACARmZJ5AAACACCAAkoSqeoBkyAZA9TQCSFNJ5Q0GTaJo0PU3LNx7c4LkgPd6EkZSx4UCDlDl4dBhIHEyc3HKORlIRQYAzrMCpmxIgrdBNnJuVsdpjaINZZO0QMyHyiR6FJBAIh2SHCnpUWYsfrGMxIP4u5IpwoSA5ceR+A=="
+
+type entry struct {
+	name     string
+	body     string
+	linkname string // Symlink target; body is ignored when set
+}
+
+func buildTar(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.linkname != "" {
+			hdr = &tar.Header{Name: e.name, Mode: 0777, Linkname: e.linkname, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.linkname == "" {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildZip(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func compress(t *testing.T, data []byte, format Format) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch format {
+	case FormatTarGz:
+		w = gzip.NewWriter(&buf)
+	case FormatTarXz:
+		xw, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = xw
+	default:
+		return data
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeArchive(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		want Format
+	}{
+		{"file.zip", FormatZip},
+		{"FILE.ZIP", FormatZip},
+		{"src.tar", FormatTar},
+		{"src.tar.gz", FormatTarGz},
+		{"src.tgz", FormatTarGz},
+		{"src.tar.bz2", FormatTarBz},
+		{"src.tar.xz", FormatTarXz},
+		{"/some/dir/src.txz", FormatTarXz},
+		{"movie.mp4", ""},
+		{"notes.gz", ""},
+		{".zip", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.name); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTargetDir(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "release-1.0.tar.gz")
+
+	if got, want := TargetDir(archive), filepath.Join(dir, "release-1.0"); got != want {
+		t.Errorf("TargetDir() = %s, want %s", got, want)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "release-1.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := TargetDir(archive), filepath.Join(dir, "release-1.0(1)"); got != want {
+		t.Errorf("TargetDir() with existing dir = %s, want %s", got, want)
+	}
+}
+
+func TestExtract_Formats(t *testing.T) {
+	entries := []entry{
+		{name: "dir/hello.txt", body: "hello"},
+		{name: "top.txt", body: "top level"},
+	}
+	tarData := buildTar(t, entries)
+	bz2, err := base64.StdEncoding.DecodeString(tarBz2Fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archives := map[string][]byte{
+		"a.zip":     buildZip(t, entries),
+		"a.tar":     tarData,
+		"a.tar.gz":  compress(t, tarData, FormatTarGz),
+		"a.tar.xz":  compress(t, tarData, FormatTarXz),
+		"a.tar.bz2": bz2,
+	}
+
+	for name, data := range archives {
+		t.Run(name, func(t *testing.T) {
+			archive := writeArchive(t, name, data)
+			dest := TargetDir(archive)
+			p := &Progress{}
+
+			if err := Extract(context.Background(), archive, dest, p); err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dest, "dir", "hello.txt"))
+			if err != nil || string(got) != "hello" {
+				t.Errorf("dir/hello.txt = %q, %v", got, err)
+			}
+			if p.Fraction() != 1 {
+				t.Errorf("Fraction() = %f, want 1", p.Fraction())
+			}
+			if p.Files() == 0 {
+				t.Error("expected extracted file count")
+			}
+		})
+	}
+}
+
+func TestExtract_RejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		data    func(t *testing.T) []byte
+	}{
+		{"zip parent traversal", "evil.zip", func(t *testing.T) []byte {
+			return buildZip(t, []entry{{name: "ok.txt", body: "ok"}, {name: "../evil.txt", body: "pwned"}})
+		}},
+		{"zip backslash traversal", "evil.zip", func(t *testing.T) []byte {
+			return buildZip(t, []entry{{name: `..\..\evil.txt`, body: "pwned"}})
+		}},
+		{"tar absolute path", "evil.tar", func(t *testing.T) []byte {
+			return buildTar(t, []entry{{name: "/tmp/evil.txt", body: "pwned"}})
+		}},
+		{"tar symlink outside", "evil.tar", func(t *testing.T) []byte {
+			return buildTar(t, []entry{{name: "link", linkname: "../../etc"}})
+		}},
+		{"tar write through symlink", "evil.tar", func(t *testing.T) []byte {
+			return buildTar(t, []entry{{name: "link", linkname: "."}, {name: "link/evil.txt", body: "pwned"}})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := writeArchive(t, tt.archive, tt.data(t))
+			dest := filepath.Join(filepath.Dir(archive), "out")
+
+			err := Extract(context.Background(), archive, dest, nil)
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Fatalf("expected ErrUnsafePath, got %v", err)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Error("partially extracted directory should be removed")
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(archive), "evil.txt")); !os.IsNotExist(err) {
+				t.Error("entry was written outside the destination")
+			}
+		})
+	}
+}
+
+func TestExtract_SymlinkInside(t *testing.T) {
+	archive := writeArchive(t, "links.tar", buildTar(t, []entry{
+		{name: "lib/libfoo.so.1", body: "elf"},
+		{name: "lib/libfoo.so", linkname: "libfoo.so.1"},
+	}))
+	dest := TargetDir(archive)
+
+	if err := Extract(context.Background(), archive, dest, nil); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "lib", "libfoo.so"))
+	if err != nil || string(got) != "elf" {
+		t.Errorf("symlink content = %q, %v", got, err)
+	}
+}
+
+func TestExtract_Cancelled(t *testing.T) {
+	archive := writeArchive(t, "a.tar.gz", compress(t, buildTar(t, []entry{{name: "a.txt", body: "a"}}), FormatTarGz))
+	dest := TargetDir(archive)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Extract(ctx, archive, dest, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("cancelled extraction should remove the destination")
+	}
+}
+
+func TestExtract_Unsupported(t *testing.T) {
+	archive := writeArchive(t, "movie.mp4", []byte("not an archive"))
+	if err := Extract(context.Background(), archive, TargetDir(archive), nil); err == nil {
+		t.Fatal("expected error for unsupported file")
+	}
+}
@@ -0,0 +1,191 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// Event identifies the download lifecycle event a hook is attached to
+type Event string
+
+const (
+	EventComplete Event = "complete"
+	EventError    Event = "error"
+	EventPause    Event = "pause"
+)
+
+const (
+	DefaultTimeout = 30 * time.Second
+	MaxOutputSize  = 4 * 1024 // Captured output is truncated to keep logs and history small
+)
+
+// Info describes the download that triggered an event
+type Info struct {
+	Event      Event
+	ID         string
+	URL        string
+	File       string // Full path to the downloaded file
+	Size       int64
+	Category   string
+	Error      string // Error message (error events only)
+	ExtractDir string // Where the archive was extracted (complete events only)
+}
+
+// Result is the outcome of a single hook run
+type Result struct {
+	Hook     string
+	Output   string
+	Err      error
+	Duration time.Duration
+}
+
+// Summary returns a one-line description of the result for logs
+func (r Result) Summary() string {
+	status := "ok"
+	if r.Err != nil {
+		status = r.Err.Error()
+	}
+	line := fmt.Sprintf("hook %s: %s (%s)", r.Hook, status, r.Duration.Round(time.Millisecond))
+	if out := strings.TrimSpace(r.Output); out != "" {
+		line += ": " + strings.ReplaceAll(out, "\n", " | ")
+	}
+	return line
+}
+
+// Matching returns the hooks that should run for the given event and category.
+// Hooks without categories are global and match every download.
+func Matching(hooks []config.HookConfig, event Event, category string) []config.HookConfig {
+	var matched []config.HookConfig
+	for _, h := range hooks {
+		if strings.TrimSpace(h.Command) == "" || !containsFold(h.Events, string(event)) {
+			continue
+		}
+		if len(h.Categories) > 0 && !containsFold(h.Categories, category) {
+			continue
+		}
+		matched = append(matched, h)
+	}
+	return matched
+}
+
+// Run executes every hook matching info and returns their results in order.
+// The file checksum is only computed when at least one hook runs on completion.
+func Run(ctx context.Context, hooks []config.HookConfig, info Info) []Result {
+	matched := Matching(hooks, info.Event, info.Category)
+	if len(matched) == 0 {
+		return nil
+	}
+
+	checksum := ""
+	if info.Event == EventComplete && info.File != "" {
+		sum, err := fileSHA256(info.File)
+		if err != nil {
+			utils.Debug("Hook checksum failed for %s: %v", info.File, err)
+		}
+		checksum = sum
+	}
+	env := append(os.Environ(),
+		"PULSE_EVENT="+string(info.Event),
+		"PULSE_ID="+info.ID,
+		"PULSE_URL="+info.URL,
+		"PULSE_FILE="+info.File,
+		fmt.Sprintf("PULSE_SIZE=%d", info.Size),
+		"PULSE_SHA256="+checksum,
+		"PULSE_CATEGORY="+info.Category,
+		"PULSE_ERROR="+info.Error,
+		"PULSE_EXTRACT_DIR="+info.ExtractDir,
+	)
+
+	results := make([]Result, 0, len(matched))
+	for _, h := range matched {
+		results = append(results, runHook(ctx, h, env))
+	}
+	return results
+}
+
+// runHook runs a single hook command through the system shell with a timeout
+func runHook(ctx context.Context, h config.HookConfig, env []string) Result {
+	name := h.Name
+	if name == "" {
+		name = h.Command
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", h.Command)
+	}
+	cmd.Env = env
+	cmd.WaitDelay = time.Second // Don't wait forever on pipes held open by orphaned children
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	start := time.Now()
+	err := cmd.Run()
+	result := Result{
+		Hook:     name,
+		Output:   truncate(out.String(), MaxOutputSize),
+		Duration: time.Since(start),
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+	} else if err != nil {
+		result.Err = err
+	}
+
+	utils.Debug("Hook %s finished in %s: %v", name, result.Duration, result.Err)
+	return result
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "... (truncated)"
+}
@@ -0,0 +1,125 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+)
+
+func skipOnWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in these tests use POSIX shell syntax")
+	}
+}
+
+func TestMatching(t *testing.T) {
+	hooks := []config.HookConfig{
+		{Name: "global", Command: "true", Events: []string{"complete", "error"}},
+		{Name: "videos", Command: "true", Events: []string{"complete"}, Categories: []string{"Video"}},
+		{Name: "empty", Command: "  ", Events: []string{"complete"}},
+	}
+
+	tests := []struct {
+		name     string
+		event    Event
+		category string
+		want     []string
+	}{
+		{"complete without category", EventComplete, "", []string{"global"}},
+		{"complete in category", EventComplete, "video", []string{"global", "videos"}},
+		{"error in category", EventError, "Video", []string{"global"}},
+		{"pause matches nothing", EventPause, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Matching(hooks, tt.event, tt.category)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Matching() returned %d hooks, want %d", len(got), len(tt.want))
+			}
+			for i, h := range got {
+				if h.Name != tt.want[i] {
+					t.Errorf("hook[%d] = %s, want %s", i, h.Name, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRun_Environment(t *testing.T) {
+	skipOnWindows(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "payload.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := []config.HookConfig{{
+		Name:    "env",
+		Command: `echo "$PULSE_EVENT $PULSE_FILE $PULSE_URL $PULSE_SIZE $PULSE_SHA256"`,
+		Events:  []string{"complete"},
+	}}
+	info := Info{Event: EventComplete, ID: "id-1", URL: "https://example.com/payload.txt", File: file, Size: 5}
+
+	results := Run(context.Background(), hooks, info)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].Err != nil {
+		t.Fatalf("hook failed: %v", results[0].Err)
+	}
+
+	// sha256("hello")
+	want := "complete " + file + " https://example.com/payload.txt 5 2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if got := strings.TrimSpace(results[0].Output); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRun_FailureAndTimeout(t *testing.T) {
+	skipOnWindows(t)
+
+	hooks := []config.HookConfig{
+		{Name: "fails", Command: "echo boom; exit 3", Events: []string{"error"}},
+		{Name: "slow", Command: "sleep 5", Events: []string{"error"}, Timeout: 100 * time.Millisecond},
+	}
+
+	results := Run(context.Background(), hooks, Info{Event: EventError, Error: "connection reset"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Err == nil || !strings.Contains(results[0].Output, "boom") {
+		t.Errorf("expected failing hook with captured output, got err=%v output=%q", results[0].Err, results[0].Output)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", results[1].Err)
+	}
+	if results[1].Duration > 3*time.Second {
+		t.Errorf("timeout not enforced, hook ran for %s", results[1].Duration)
+	}
+}
+
+func TestRun_NoMatchingHooks(t *testing.T) {
+	results := Run(context.Background(), nil, Info{Event: EventComplete, File: "/does/not/exist"})
+	if results != nil {
+		t.Errorf("expected no results, got %v", results)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("x", MaxOutputSize+10)
+	got := truncate(long, MaxOutputSize)
+	if !strings.HasSuffix(got, "(truncated)") || len(got) > MaxOutputSize+20 {
+		t.Errorf("truncate did not cap output, len=%d", len(got))
+	}
+	if truncate("short", MaxOutputSize) != "short" {
+		t.Error("short output should be unchanged")
+	}
+}
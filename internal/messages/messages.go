@@ -2,6 +2,8 @@ package messages
 
 import (
 	"time"
+
+	"github.com/pulse-downloader/pulse/internal/download/types"
 )
 
 // ProgressMsg represents a progress update from the downloader
@@ -24,7 +26,19 @@ type DownloadCompleteMsg struct {
 // DownloadErrorMsg signals that an error occurred
 type DownloadErrorMsg struct {
 	DownloadID string
+	URL        string // URL the download was added with, for consumers that never saw it start
 	Err        error
+	Kind       types.ErrorKind // Why the download failed (network, http, disk, ...)
+}
+
+// DownloadRetryingMsg is sent when a failed download will be retried automatically after Delay
+type DownloadRetryingMsg struct {
+	DownloadID  string
+	Attempt     int // Retry about to be made, starting at 1
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error
+	Kind        types.ErrorKind
 }
 
 // DownloadStartedMsg is sent when a download actually starts (after metadata fetch)
@@ -34,13 +48,37 @@ type DownloadStartedMsg struct {
 	Filename   string
 	Total      int64
 	DestPath   string // Full path to the destination file
+	Category   string // Category chosen by the rules or the user (empty if none)
+	Resumed    int64  // Bytes downloaded before, when the download continues an earlier one
 }
 
 type DownloadPausedMsg struct {
 	DownloadID string
+	URL        string // URL the download was added with, for consumers that never saw it start
 	Downloaded int64
 }
 
 type DownloadResumedMsg struct {
 	DownloadID string
 }
+
+// QueueChangedMsg is sent when queued downloads are reordered, reprioritised or started early
+type QueueChangedMsg struct{}
+
+// HealthKind identifies the problem reported by a HealthEventMsg
+type HealthKind string
+
+const (
+	HealthStalled    HealthKind = "stalled"     // A connection received no data for the stall timeout
+	HealthSlow       HealthKind = "slow"        // A connection was far slower than the others
+	HealthHostErrors HealthKind = "host-errors" // Requests to a host keep failing
+	HealthThrottled  HealthKind = "throttled"   // A host rate-limited us and is being backed off
+)
+
+// HealthEventMsg is sent when the health monitor restarts a connection or a host keeps failing
+type HealthEventMsg struct {
+	DownloadID string
+	Host       string
+	Kind       HealthKind
+	Detail     string
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/utils"
+)
+
+// Event identifies the download event a notification describes
+type Event string
+
+const (
+	EventComplete Event = "complete"
+	EventError    Event = "error"
+)
+
+const (
+	DefaultRetries = 3
+	RequestTimeout = 10 * time.Second
+)
+
+// retryBaseDelay is the wait before the first retry; it doubles on each attempt
+var retryBaseDelay = time.Second
+
+// Notification describes a finished or failed download
+type Notification struct {
+	Event    Event
+	ID       string
+	URL      string
+	Filename string
+	Path     string // Full path to the downloaded file
+	Size     int64
+	Category string
+	Error    string // Error message (error events only)
+	Elapsed  time.Duration
+	Time     time.Time
+}
+
+// Title returns a short headline for push and desktop notifications
+func (n Notification) Title() string {
+	if n.Event == EventError {
+		return "Download failed"
+	}
+	return "Download complete"
+}
+
+// Message returns the notification body
+func (n Notification) Message() string {
+	name := n.Filename
+	if name == "" {
+		name = n.URL
+	}
+	if n.Event == EventError {
+		return fmt.Sprintf("%s: %s", name, n.Error)
+	}
+	msg := fmt.Sprintf("%s (%s)", name, utils.ConvertBytesToHumanReadable(n.Size))
+	if n.Elapsed > 0 {
+		msg += " in " + n.Elapsed.Round(time.Second).String()
+	}
+	return msg
+}
+
+// Sink delivers notifications to one destination
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Result is the delivery outcome for one notifier
+type Result struct {
+	Notifier string
+	Err      error
+}
+
+// notifier pairs a sink with its configuration
+type notifier struct {
+	cfg  config.NotifierConfig
+	sink Sink
+}
+
+// Notifier fans notifications out to the configured sinks
+type Notifier struct {
+	notifiers []notifier
+}
+
+// New builds a Notifier from the settings. Terminal (OSC) sinks write to term and
+// are skipped when term is nil, e.g. when running headless.
+func New(cfgs []config.NotifierConfig, term io.Writer) *Notifier {
+	n := &Notifier{}
+	for _, cfg := range cfgs {
+		sink, err := newSink(cfg, term)
+		if err != nil {
+			utils.Debug("Skipping notifier %s: %v", name(cfg), err)
+			continue
+		}
+		if sink == nil {
+			continue
+		}
+		n.notifiers = append(n.notifiers, notifier{cfg: cfg, sink: sink})
+	}
+	return n
+}
+
+// Len returns the number of usable notifiers
+func (n *Notifier) Len() int {
+	return len(n.notifiers)
+}
+
+// Wants reports whether any notifier is interested in the event
+func (n *Notifier) Wants(event Event) bool {
+	for _, nt := range n.notifiers {
+		if wants(nt.cfg, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send delivers the notification to every notifier that wants its event, in parallel.
+// It blocks until all deliveries (including retries) are done.
+func (n *Notifier) Send(ctx context.Context, note Notification) []Result {
+	if note.Time.IsZero() {
+		note.Time = time.Now()
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []Result
+	)
+	for _, nt := range n.notifiers {
+		if !wants(nt.cfg, note.Event) {
+			continue
+		}
+		wg.Add(1)
+		go func(nt notifier) {
+			defer wg.Done()
+			err := nt.sink.Send(ctx, note)
+			if err != nil {
+				utils.Debug("Notifier %s failed: %v", name(nt.cfg), err)
+			}
+			mu.Lock()
+			results = append(results, Result{Notifier: name(nt.cfg), Err: err})
+			mu.Unlock()
+		}(nt)
+	}
+	wg.Wait()
+	return results
+}
+
+func newSink(cfg config.NotifierConfig, term io.Writer) (Sink, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook needs a url")
+		}
+		return &webhookSink{url: cfg.URL, secret: cfg.Secret, retries: retries(cfg)}, nil
+	case "ntfy":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("ntfy needs a topic url")
+		}
+		return &ntfySink{url: cfg.URL, token: cfg.Token, retries: retries(cfg)}, nil
+	case "gotify":
+		if cfg.URL == "" || cfg.Token == "" {
+			return nil, fmt.Errorf("gotify needs a url and token")
+		}
+		return &gotifySink{url: cfg.URL, token: cfg.Token, retries: retries(cfg)}, nil
+	case "osc9":
+		if term == nil {
+			return nil, nil
+		}
+		return &oscSink{w: term, protocol: 9}, nil
+	case "osc777":
+		if term == nil {
+			return nil, nil
+		}
+		return &oscSink{w: term, protocol: 777}, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", cfg.Type)
+	}
+}
+
+func wants(cfg config.NotifierConfig, event Event) bool {
+	if len(cfg.Events) == 0 {
+		return true
+	}
+	for _, e := range cfg.Events {
+		if strings.EqualFold(strings.TrimSpace(e), string(event)) {
+			return true
+		}
+	}
+	return false
+}
+
+func retries(cfg config.NotifierConfig) int {
+	switch {
+	case cfg.Retries < 0:
+		return 0
+	case cfg.Retries == 0:
+		return DefaultRetries
+	default:
+		return cfg.Retries
+	}
+}
+
+func name(cfg config.NotifierConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pulse-downloader/pulse/internal/config"
+)
+
+func init() {
+	retryBaseDelay = time.Millisecond
+}
+
+var completed = Notification{
+	Event:    EventComplete,
+	ID:       "id-1",
+	URL:      "https://example.com/file.iso",
+	Filename: "file.iso",
+	Size:     2048,
+	Elapsed:  3 * time.Second,
+}
+
+func TestWebhook_SignedPayload(t *testing.T) {
+	var gotBody []byte
+	var gotSig, gotEvent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get(SignatureHeader)
+		gotEvent = r.Header.Get(EventHeader)
+	}))
+	defer srv.Close()
+
+	n := New([]config.NotifierConfig{{Type: "webhook", URL: srv.URL, Secret: "s3cret"}}, nil)
+	results := n.Send(context.Background(), completed)
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	if want := "sha256=" + Sign("s3cret", gotBody); gotSig != want {
+		t.Errorf("signature = %q, want %q", gotSig, want)
+	}
+	if gotEvent != "complete" {
+		t.Errorf("event header = %q", gotEvent)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Filename != "file.iso" || payload.Size != 2048 || payload.ElapsedMs != 3000 || payload.Timestamp == 0 {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestWebhook_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		retries   int
+		wantHits  int32
+		wantError bool
+	}{
+		{"recovers after 5xx", []int{503, 502, 200}, 0, 3, false},
+		{"retries 429", []int{429, 200}, 0, 2, false},
+		{"gives up after retries", []int{500, 500, 500}, 2, 3, true},
+		{"no retry on 4xx", []int{400, 200}, 0, 1, true},
+		{"retries disabled", []int{503, 200}, -1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := hits.Add(1) - 1
+				w.WriteHeader(tt.statuses[min(int(i), len(tt.statuses)-1)])
+			}))
+			defer srv.Close()
+
+			n := New([]config.NotifierConfig{{Type: "webhook", URL: srv.URL, Retries: tt.retries}}, nil)
+			results := n.Send(context.Background(), completed)
+			if (results[0].Err != nil) != tt.wantError {
+				t.Errorf("err = %v, wantError %v", results[0].Err, tt.wantError)
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("hits = %d, want %d", hits.Load(), tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestPushSinks(t *testing.T) {
+	var ntfyReq, gotifyReq *http.Request
+	var ntfyBody, gotifyBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/message" {
+			gotifyReq, gotifyBody = r, body
+		} else {
+			ntfyReq, ntfyBody = r, body
+		}
+	}))
+	defer srv.Close()
+
+	failed := Notification{Event: EventError, Filename: "file.iso", Error: "connection reset"}
+	n := New([]config.NotifierConfig{
+		{Type: "ntfy", URL: srv.URL + "/downloads", Token: "tk"},
+		{Type: "gotify", URL: srv.URL + "/", Token: "app-token"},
+	}, nil)
+	for _, r := range n.Send(context.Background(), failed) {
+		if r.Err != nil {
+			t.Fatalf("%s failed: %v", r.Notifier, r.Err)
+		}
+	}
+
+	if ntfyReq == nil || ntfyReq.Header.Get("Title") != "Download failed" || ntfyReq.Header.Get("Authorization") != "Bearer tk" {
+		t.Errorf("unexpected ntfy request: %+v", ntfyReq)
+	}
+	if string(ntfyBody) != "file.iso: connection reset" {
+		t.Errorf("ntfy body = %q", ntfyBody)
+	}
+	if gotifyReq == nil || gotifyReq.Header.Get("X-Gotify-Key") != "app-token" {
+		t.Fatalf("unexpected gotify request: %+v", gotifyReq)
+	}
+	var msg struct {
+		Title    string `json:"title"`
+		Priority int    `json:"priority"`
+	}
+	if err := json.Unmarshal(gotifyBody, &msg); err != nil || msg.Title != "Download failed" || msg.Priority != 8 {
+		t.Errorf("gotify body = %s (%v)", gotifyBody, err)
+	}
+}
+
+func TestOSC(t *testing.T) {
+	var buf bytes.Buffer
+	n := New([]config.NotifierConfig{{Type: "osc9"}, {Type: "osc777"}}, &buf)
+	n.Send(context.Background(), Notification{Event: EventError, Filename: "a;b.zip", Error: "bad\x07"})
+
+	out := buf.String()
+	if !strings.Contains(out, "\x1b]9;Download failed: a b.zip: bad \x07") {
+		t.Errorf("missing OSC 9 sequence in %q", out)
+	}
+	if !strings.Contains(out, "\x1b]777;notify;Download failed;a b.zip: bad \x07") {
+		t.Errorf("missing OSC 777 sequence in %q", out)
+	}
+}
+
+func TestNew_SkipsUnusableNotifiers(t *testing.T) {
+	n := New([]config.NotifierConfig{
+		{Type: "webhook"},                         // No URL
+		{Type: "gotify", URL: "http://localhost"}, // No token
+		{Type: "carrier-pigeon", URL: "http://x"},
+		{Type: "osc9"}, // No terminal
+		{Type: "ntfy", URL: "http://localhost/topic", Events: []string{"error"}},
+	}, nil)
+
+	if n.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", n.Len())
+	}
+	if n.Wants(EventComplete) || !n.Wants(EventError) {
+		t.Error("event filter not applied")
+	}
+	if results := n.Send(context.Background(), completed); len(results) != 0 {
+		t.Errorf("filtered notifier should not be sent to, got %+v", results)
+	}
+}
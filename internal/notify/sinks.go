@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Pulse-Signature" // "sha256=" + hex HMAC of the request body
+	EventHeader     = "X-Pulse-Event"
+)
+
+var httpClient = &http.Client{Timeout: RequestTimeout}
+
+// termMu serialises terminal writes so sequences are never interleaved
+var termMu sync.Mutex
+
+// webhookPayload is the JSON body posted to generic webhooks
+type webhookPayload struct {
+	Event     Event  `json:"event"`
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Filename  string `json:"filename"`
+	Path      string `json:"path,omitempty"`
+	Size      int64  `json:"size"`
+	Category  string `json:"category,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+	Timestamp int64  `json:"timestamp"` // Unix seconds
+}
+
+// webhookSink posts the notification as JSON, signed with HMAC-SHA256 when a secret is set
+type webhookSink struct {
+	url     string
+	secret  string
+	retries int
+}
+
+func (s *webhookSink) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:     n.Event,
+		ID:        n.ID,
+		URL:       n.URL,
+		Filename:  n.Filename,
+		Path:      n.Path,
+		Size:      n.Size,
+		Category:  n.Category,
+		Error:     n.Error,
+		ElapsedMs: n.Elapsed.Milliseconds(),
+		Timestamp: n.Time.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		EventHeader:    string(n.Event),
+	}
+	if s.secret != "" {
+		headers[SignatureHeader] = "sha256=" + Sign(s.secret, body)
+	}
+	return post(ctx, s.url, body, headers, s.retries)
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body, as sent in SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ntfySink publishes to an ntfy topic URL (https://ntfy.sh/<topic>)
+type ntfySink struct {
+	url     string
+	token   string
+	retries int
+}
+
+func (s *ntfySink) Send(ctx context.Context, n Notification) error {
+	headers := map[string]string{
+		"Title": n.Title(),
+		"Tags":  "arrow_down",
+	}
+	if n.Event == EventError {
+		headers["Tags"] = "warning"
+		headers["Priority"] = "high"
+	}
+	if s.token != "" {
+		headers["Authorization"] = "Bearer " + s.token
+	}
+	return post(ctx, s.url, []byte(n.Message()), headers, s.retries)
+}
+
+// gotifySink pushes to a Gotify server's message endpoint
+type gotifySink struct {
+	url     string
+	token   string
+	retries int
+}
+
+func (s *gotifySink) Send(ctx context.Context, n Notification) error {
+	priority := 5
+	if n.Event == EventError {
+		priority = 8
+	}
+	body, err := json.Marshal(map[string]any{
+		"title":    n.Title(),
+		"message":  n.Message(),
+		"priority": priority,
+	})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"X-Gotify-Key": s.token,
+	}
+	return post(ctx, strings.TrimSuffix(s.url, "/")+"/message", body, headers, s.retries)
+}
+
+// oscSink emits a desktop notification through the terminal emulator.
+// OSC 9 is understood by iTerm2, Windows Terminal and others; OSC 777 by rxvt, foot and kitty.
+type oscSink struct {
+	w        io.Writer
+	protocol int
+}
+
+func (s *oscSink) Send(_ context.Context, n Notification) error {
+	title, msg := oscEscape(n.Title()), oscEscape(n.Message())
+	var seq string
+	if s.protocol == 777 {
+		seq = fmt.Sprintf("\x1b]777;notify;%s;%s\x07", title, msg)
+	} else {
+		seq = fmt.Sprintf("\x1b]9;%s: %s\x07", title, msg)
+	}
+
+	termMu.Lock()
+	defer termMu.Unlock()
+	_, err := io.WriteString(s.w, seq)
+	return err
+}
+
+// oscEscape strips characters that would terminate or corrupt an OSC sequence
+func oscEscape(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == ';' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// post sends body to url, retrying network errors, 429 and 5xx responses with exponential backoff
+func post(ctx context.Context, url string, body []byte, headers map[string]string, retries int) error {
+	delay := retryBaseDelay
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		retry, err := postOnce(ctx, url, body, headers)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// postOnce makes a single delivery attempt and reports whether a failure is worth retrying
+func postOnce(ctx context.Context, url string, body []byte, headers map[string]string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("User-Agent", "pulse")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("server returned %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
@@ -33,6 +33,7 @@ var (
 	StatePaused      = lipgloss.Color("#FFAA00") // 🟡 Amber - Paused/Queued
 	StateDownloading = lipgloss.Color("#FFD700") // 🟡 Gold - Downloading (Active)
 	StateDone        = lipgloss.Color("#00FF88") // 🟢 Green - Completed (Success)
+	StateExtracting  = lipgloss.Color("#5FD7FF") // 🔵 Blue - Extracting (Post-processing)
 )
 
 // === Progress Bar Colors ===
@@ -15,6 +15,8 @@ const (
 	StatusPaused
 	StatusComplete
 	StatusError
+	StatusExtracting
+	StatusElsewhere
 )
 
 // statusInfo holds the display properties for each status
@@ -30,6 +32,8 @@ var statusMap = map[DownloadStatus]statusInfo{
 	StatusPaused:      {"⏸", "Paused", colors.StatePaused},
 	StatusComplete:    {"✔", "Completed", colors.StateDone},
 	StatusError:       {"✖", "Error", colors.StateError},
+	StatusExtracting:  {"⇲", "Extracting", colors.StateExtracting},
+	StatusElsewhere:   {"⇄", "Running elsewhere", colors.StateDownloading},
 }
 
 // Icon returns the status icon
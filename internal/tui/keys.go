@@ -14,24 +14,37 @@ type KeyMap struct {
 	SettingsEditor SettingsEditorKeyMap
 	BatchConfirm   BatchConfirmKeyMap
 	Update         UpdateKeyMap
+	ChangeURL      ChangeURLKeyMap
 }
 
 // DashboardKeyMap defines keybindings for the main dashboard
 type DashboardKeyMap struct {
-	TabQueued   key.Binding
-	TabActive   key.Binding
-	TabDone     key.Binding
-	NextTab     key.Binding
-	Add         key.Binding
-	BatchImport key.Binding
-	Search      key.Binding
-	Pause       key.Binding
-	Delete      key.Binding
-	Settings    key.Binding
-	Log         key.Binding
-	History     key.Binding
-	Quit        key.Binding
-	ForceQuit   key.Binding
+	TabQueued      key.Binding
+	TabActive      key.Binding
+	TabDone        key.Binding
+	TabFailed      key.Binding
+	NextTab        key.Binding
+	Add            key.Binding
+	BatchImport    key.Binding
+	Search         key.Binding
+	CategoryFilter key.Binding
+	Pause          key.Binding
+	Delete         key.Binding
+	ChangeURL      key.Binding
+	Retry          key.Binding
+	Settings       key.Binding
+	Log            key.Binding
+	History        key.Binding
+	Quit           key.Binding
+	ForceQuit      key.Binding
+	// Queue ordering (Queued tab)
+	MoveUp       key.Binding
+	MoveDown     key.Binding
+	MoveTop      key.Binding
+	MoveBottom   key.Binding
+	PriorityUp   key.Binding
+	PriorityDown key.Binding
+	StartNow     key.Binding
 	// Navigation
 	Up   key.Binding
 	Down key.Binding
@@ -120,6 +133,12 @@ type UpdateKeyMap struct {
 	NeverRemind key.Binding
 }
 
+// ChangeURLKeyMap defines keybindings for the change URL dialog
+type ChangeURLKeyMap struct {
+	Confirm key.Binding
+	Cancel  key.Binding
+}
+
 // Keys contains all the keybindings for the application
 var Keys = KeyMap{
 	Dashboard: DashboardKeyMap{
@@ -135,6 +154,10 @@ var Keys = KeyMap{
 			key.WithKeys("e"),
 			key.WithHelp("e", "done tab"),
 		),
+		TabFailed: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "failed tab"),
+		),
 		NextTab: key.NewBinding(
 			key.WithKeys("tab"),
 			key.WithHelp("tab", "next tab"),
@@ -159,6 +182,18 @@ var Keys = KeyMap{
 			key.WithKeys("x"),
 			key.WithHelp("x", "delete"),
 		),
+		CategoryFilter: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "category filter"),
+		),
+		ChangeURL: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "change url"),
+		),
+		Retry: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "retry"),
+		),
 		Settings: key.NewBinding(
 			key.WithKeys("s"),
 			key.WithHelp("s", "settings"),
@@ -179,6 +214,34 @@ var Keys = KeyMap{
 			key.WithKeys("ctrl+c"),
 			key.WithHelp("ctrl+c", "force quit"),
 		),
+		MoveUp: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "move up"),
+		),
+		MoveDown: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "move down"),
+		),
+		MoveTop: key.NewBinding(
+			key.WithKeys("{"),
+			key.WithHelp("{", "move to top"),
+		),
+		MoveBottom: key.NewBinding(
+			key.WithKeys("}"),
+			key.WithHelp("}", "move to bottom"),
+		),
+		PriorityUp: key.NewBinding(
+			key.WithKeys("+", "="),
+			key.WithHelp("+", "raise priority"),
+		),
+		PriorityDown: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", "lower priority"),
+		),
+		StartNow: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "start now"),
+		),
 		Up: key.NewBinding(
 			key.WithKeys("up", "k"),
 			key.WithHelp("↑/k", "up"),
@@ -390,6 +453,16 @@ var Keys = KeyMap{
 			key.WithHelp("n", "never remind"),
 		),
 	},
+	ChangeURL: ChangeURLKeyMap{
+		Confirm: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "replace & resume"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	},
 }
 
 // ShortHelp returns keybindings to show in the mini help view
@@ -400,8 +473,9 @@ func (k DashboardKeyMap) ShortHelp() []key.Binding {
 // FullHelp returns keybindings for the expanded help view
 func (k DashboardKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.TabQueued, k.TabActive, k.TabDone, k.NextTab},
-		{k.Add, k.Search, k.Pause, k.Delete, k.Settings},
+		{k.TabQueued, k.TabActive, k.TabDone, k.TabFailed, k.NextTab},
+		{k.Add, k.Search, k.CategoryFilter, k.Pause, k.Delete, k.ChangeURL, k.Retry, k.Settings},
+		{k.MoveUp, k.MoveDown, k.MoveTop, k.MoveBottom, k.PriorityUp, k.PriorityDown, k.StartNow},
 		{k.Log, k.History, k.Quit},
 	}
 }
@@ -480,3 +554,11 @@ func (k UpdateKeyMap) ShortHelp() []key.Binding {
 func (k UpdateKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{{k.OpenGitHub, k.IgnoreNow, k.NeverRemind}}
 }
+
+func (k ChangeURLKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Confirm, k.Cancel}
+}
+
+func (k ChangeURLKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Confirm, k.Cancel}}
+}
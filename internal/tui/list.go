@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/pulse-downloader/pulse/internal/tui/components"
+	"github.com/pulse-downloader/pulse/internal/download/types"
 	"github.com/pulse-downloader/pulse/internal/utils"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -26,7 +26,7 @@ func (i DownloadItem) Description() string {
 	d := i.download
 
 	// Get styled status using the shared component
-	status := components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded)
+	status := downloadStatus(d)
 	styledStatus := status.Render()
 
 	// Build progress info
@@ -45,11 +45,24 @@ func (i DownloadItem) Description() string {
 		speedInfo = fmt.Sprintf(" • %.2f MB/s", d.Speed/Megabyte)
 	}
 
-	return fmt.Sprintf("%s • %.0f%%%s • %s", styledStatus, pct, speedInfo, sizeInfo)
+	queueInfo := ""
+	if d.queuePos > 0 {
+		queueInfo = fmt.Sprintf(" • #%d", d.queuePos)
+		if d.Priority != types.PriorityNormal {
+			queueInfo += " " + d.Priority.String()
+		}
+	}
+
+	categoryInfo := ""
+	if d.Category != "" {
+		categoryInfo = " • " + d.Category
+	}
+
+	return fmt.Sprintf("%s%s%s • %.0f%%%s • %s", styledStatus, queueInfo, categoryInfo, pct, speedInfo, sizeInfo)
 }
 
 func (i DownloadItem) FilterValue() string {
-	return i.download.Filename
+	return i.download.Filename + " " + i.download.Category
 }
 
 // Custom delegate for rendering download items
@@ -186,6 +199,8 @@ func NewDownloadList(width, height int) list.Model {
 
 // UpdateListItems updates the list with filtered downloads based on active tab
 func (m *RootModel) UpdateListItems() {
+	m.syncQueueOrder()
+
 	// If the user manually switched tabs, don't try to preserve/follow selection
 	if m.ManualTabSwitch {
 		m.ManualTabSwitch = false
@@ -234,7 +249,9 @@ func (m *RootModel) UpdateListItems() {
 			for _, d := range m.downloads {
 				if d.ID == targetID {
 					newTab := -1
-					if d.done {
+					if d.err != nil {
+						newTab = TabFailed
+					} else if d.done {
 						newTab = TabDone
 					} else if d.Speed > 0 {
 						newTab = TabActive
@@ -273,3 +290,21 @@ func (m *RootModel) GetSelectedDownload() *DownloadModel {
 	}
 	return nil
 }
+
+// syncQueueOrder copies queue positions and priorities from the pool for display and sorting
+func (m *RootModel) syncQueueOrder() {
+	if m.Pool == nil {
+		return
+	}
+	queued := m.Pool.Queued()
+	positions := make(map[string]int, len(queued))
+	for i, cfg := range queued {
+		positions[cfg.ID] = i + 1
+	}
+	for _, d := range m.downloads {
+		d.queuePos = positions[d.ID]
+		if d.queuePos > 0 {
+			d.Priority = queued[d.queuePos-1].Priority
+		}
+	}
+}
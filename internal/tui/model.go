@@ -1,8 +1,13 @@
 package tui
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,6 +24,8 @@ import (
 	"github.com/pulse-downloader/pulse/internal/download"
 	"github.com/pulse-downloader/pulse/internal/download/state"
 	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/extract"
+	"github.com/pulse-downloader/pulse/internal/utils"
 	"github.com/pulse-downloader/pulse/internal/version"
 )
 
@@ -39,12 +46,14 @@ const (
 	UpdateAvailableState                      //UpdateAvailableState is 11
 	QualitySelectionState                     //QualitySelectionState is 12
 	FetchingFormatsState                      //FetchingFormatsState is 13
+	ChangeURLState                            //ChangeURLState is 14
 )
 
 const (
 	TabQueued = 0
 	TabActive = 1
 	TabDone   = 2
+	TabFailed = 3
 )
 
 // StartDownloadMsg is sent from the HTTP server to start a new download
@@ -52,6 +61,34 @@ type StartDownloadMsg struct {
 	URL      string
 	Path     string
 	Filename string
+	Quality  string            // Skips quality selection if set
+	Category string            // Category to file the download under, if any
+	Headers  map[string]string // Extra request headers
+	Checksum string            // Expected digest as "algo=hex"
+	Mirrors  []string          // Other URLs of the same file
+}
+
+// URLChangedMsg is sent from the HTTP server once the URL of a paused or failed download has
+// been replaced and the download resumed
+type URLChangedMsg struct {
+	DownloadID string
+	URL        string
+}
+
+// DownloadRetriedMsg is sent from the HTTP server once the pool has retried a failed download
+type DownloadRetriedMsg struct {
+	DownloadID string
+}
+
+// DownloadRemovedMsg is sent from the HTTP server once the pool has removed a download and
+// its partial file
+type DownloadRemovedMsg struct {
+	DownloadID string
+}
+
+// SetMaxDownloadsMsg is sent from the HTTP server to change the concurrency limit
+type SetMaxDownloadsMsg struct {
+	Max int
 }
 
 type DownloadModel struct {
@@ -59,6 +96,8 @@ type DownloadModel struct {
 	URL         string
 	Filename    string
 	Destination string // Full path to the destination file
+	Category    string // Category assigned by the rules or chosen in the add dialog
+	Priority    types.Priority
 	Total       int64
 	Downloaded  int64
 	Speed       float64
@@ -73,9 +112,18 @@ type DownloadModel struct {
 	state    *types.ProgressState
 	reporter *ProgressReporter
 
-	done   bool
-	err    error
-	paused bool
+	done      bool
+	err       error
+	paused    bool
+	retrying  bool // Failed and waiting for an automatic retry
+	elsewhere bool // Paused here because another pulse process is downloading it
+
+	queuePos int // 1-based position in the pool's queue, 0 once started (or paused)
+
+	// Archive extraction after completion
+	extracting      bool
+	extractProgress *extract.Progress
+	ExtractedDir    string
 }
 
 type RootModel struct {
@@ -97,19 +145,26 @@ type RootModel struct {
 	// Bubbles list component for download listing
 	list list.Model
 
-	Pool *download.WorkerPool //Works as the download queue
-	PWD  string
+	Pool     *download.WorkerPool //Works as the download queue
+	Terminal *Terminal            // Output the program renders to
+	PWD      string
+
+	restoredQueue []types.DownloadConfig // Queued downloads from the last session, added to the pool by Init
 
 	// History view
 	historyEntries []types.DownloadEntry
 	historyCursor  int
 
 	// Duplicate detection
-	pendingURL      string // URL pending confirmation
-	pendingPath     string // Path pending confirmation
-	pendingFilename string // Filename pending confirmation
-	pendingQuality  string // Quality pending confirmation
-	duplicateInfo   string // Info about the duplicate
+	pendingURL      string            // URL pending confirmation
+	pendingPath     string            // Path pending confirmation
+	pendingFilename string            // Filename pending confirmation
+	pendingQuality  string            // Quality pending confirmation
+	pendingCategory string            // Category pending confirmation
+	pendingHeaders  map[string]string // Request headers pending confirmation
+	pendingChecksum string            // Checksum pending confirmation
+	pendingMirrors  []string          // Mirror URLs pending confirmation
+	duplicateInfo   string            // Info about the duplicate
 
 	// Quality Selection
 	availableQualities []string // List of available qualities
@@ -132,6 +187,8 @@ type RootModel struct {
 	SettingsIsEditing    bool             // Whether currently editing a value
 	SettingsInput        textinput.Model  // Input for editing string/int values
 	SettingsFileBrowsing bool             // Whether browsing for a directory
+	settingsVersion      config.SettingsVersion
+	liveRuntime          *types.LiveRuntime // Settings followed by running downloads
 
 	// Selection persistence
 	SelectedDownloadID string // ID of the currently selected download
@@ -142,9 +199,15 @@ type RootModel struct {
 	searchActive bool            // Whether search mode is active
 	searchQuery  string          // Current search query
 
+	categoryFilter string // Only show downloads in this category (empty shows all)
+
+	// Change URL
+	changeURLInput    textinput.Model // Input for the replacement URL
+	changeURLTargetID string          // ID of the download whose URL is being changed
+
 	// Batch import
-	pendingBatchURLs []string // URLs pending batch import
-	batchFilePath    string   // Path to the batch file
+	pendingBatch  []download.InputEntry // Downloads pending batch import
+	batchFilePath string                // Path to the batch file
 
 	// Keybindings
 	keys KeyMap
@@ -191,6 +254,12 @@ func InitialRootModel(serverPort int, currentVersion string) RootModel {
 	filenameInput.Width = InputWidth
 	filenameInput.Prompt = ""
 
+	categoryInput := textinput.New()
+	categoryInput.Placeholder = "(auto)"
+	categoryInput.Width = InputWidth
+	categoryInput.Prompt = ""
+	categoryInput.ShowSuggestions = true
+
 	// Create channel first so we can pass it to WorkerPool
 	progressChan := make(chan tea.Msg, ProgressChannelBuffer)
 
@@ -208,8 +277,15 @@ func InitialRootModel(serverPort int, currentVersion string) RootModel {
 	fp.ShowPermissions = true
 	fp.SetHeight(FilePickerHeight)
 
+	// Downloads cut short by a crash resume from their last checkpoint; list them as paused
+	recovered, err := state.RecoverInterrupted()
+	if err != nil {
+		utils.Debug("Failed to recover interrupted downloads: %v", err)
+	}
+
 	// Load paused downloads from master list (now uses global config directory)
 	var downloads []*DownloadModel
+	var pausedConfigs []types.DownloadConfig
 	if pausedEntries, err := state.LoadPausedDownloads(); err == nil {
 		for _, entry := range pausedEntries {
 			var id string
@@ -219,6 +295,7 @@ func InitialRootModel(serverPort int, currentVersion string) RootModel {
 			dm := NewDownloadModel(id, entry.URL, entry.Filename, 0)
 			dm.paused = true
 			dm.Destination = entry.DestPath // Store destination for state lookup on resume
+			dm.elsewhere = state.DownloadOwner(entry.DestPath) != nil
 			// Load actual progress from state file (using URL+DestPath for unique lookup)
 			if state, err := state.LoadState(entry.URL, entry.DestPath); err == nil {
 				dm.Downloaded = state.Downloaded
@@ -231,6 +308,10 @@ func InitialRootModel(serverPort int, currentVersion string) RootModel {
 				}
 			}
 			downloads = append(downloads, dm)
+
+			cfg := download.ResumedConfig(entry)
+			cfg.State = dm.state
+			pausedConfigs = append(pausedConfigs, cfg)
 		}
 	}
 
@@ -262,41 +343,131 @@ func InitialRootModel(serverPort int, currentVersion string) RootModel {
 	// Load settings from disk (or defaults)
 	settings, _ := config.LoadSettings()
 
+	pool := download.NewWorkerPool(progressChan, settings.General.MaxConcurrentDownloads)
+	liveRuntime := types.NewLiveRuntime(convertRuntimeConfig(settings.ToRuntimeConfig()))
+	// Paused downloads are tracked with their listed progress so the API can resume them
+	for _, cfg := range pausedConfigs {
+		cfg.ProgressCh = progressChan
+		cfg.Live = liveRuntime
+		pool.RestorePaused(cfg)
+	}
+
+	// Downloads that were still waiting when Pulse last exited are re-enqueued in their saved
+	// order by Init, once the program is running to receive their events
+	var restoredQueue []types.DownloadConfig
+	if queuedEntries, err := state.LoadQueuedDownloads(); err == nil {
+		for _, entry := range queuedEntries {
+			dm := NewDownloadModel(entry.ID, entry.URL, "Queued", 0)
+			dm.Category = entry.Category
+			dm.Priority = entry.Priority
+			downloads = append(downloads, dm)
+
+			cfg := download.RestoredConfig(entry)
+			cfg.ProgressCh = progressChan
+			cfg.State = dm.state
+			cfg.Live = liveRuntime
+			restoredQueue = append(restoredQueue, cfg)
+		}
+	}
+
+	// Failed downloads stay listed, with their partial data, until they are retried or deleted
+	if failedEntries, err := state.LoadFailedDownloads(); err == nil {
+		for _, entry := range failedEntries {
+			dm := NewDownloadModel(entry.ID, entry.URL, cmp.Or(entry.Filename, entry.URL), entry.TotalSize)
+			dm.done = true
+			dm.err = types.NewError(types.ErrorKind(entry.ErrorKind), errors.New(cmp.Or(entry.Error, "download failed")))
+			dm.Destination = entry.DestPath
+			dm.Category = entry.Category
+			dm.Priority = entry.Priority
+			dm.Downloaded = entry.Downloaded
+			dm.state.Downloaded.Store(entry.Downloaded)
+			if entry.TotalSize > 0 {
+				dm.progress.SetPercent(float64(entry.Downloaded) / float64(entry.TotalSize))
+			}
+			downloads = append(downloads, dm)
+
+			cfg := download.RestoredConfig(entry)
+			cfg.ProgressCh = progressChan
+			cfg.State = dm.state
+			cfg.Live = liveRuntime
+			pool.RestoreFailed(entry, cfg)
+		}
+	}
+
 	// Initialize settings input for editing
 	settingsInput := textinput.New()
 	settingsInput.Width = 40
 	settingsInput.Prompt = ""
 
+	// Initialize change URL input
+	changeURLInput := textinput.New()
+	changeURLInput.Placeholder = "https://example.com/new-link.zip"
+	changeURLInput.Width = InputWidth
+	changeURLInput.Prompt = ""
+
 	// Initialize search input
 	searchInput := textinput.New()
 	searchInput.Placeholder = "Type to search..."
 	searchInput.Width = 30
 	searchInput.Prompt = ""
 
-	return RootModel{
-		downloads:      downloads,
-		inputs:         []textinput.Model{urlInput, pathInput, filenameInput},
-		state:          DashboardState,
-		progressChan:   progressChan,
-		filepicker:     fp,
-		help:           helpModel,
-		list:           downloadList,
-		Pool:           download.NewWorkerPool(progressChan, settings.General.MaxConcurrentDownloads),
-		PWD:            pwd,
-		SpeedHistory:   make([]float64, GraphHistoryPoints), // 60 points of history (30s at 0.5s interval)
-		logViewport:    viewport.New(40, 5),                 // Default size, will be resized
-		logEntries:     make([]string, 0),
-		Settings:       settings,
-		SettingsInput:  settingsInput,
-		searchInput:    searchInput,
-		keys:           Keys,
-		ServerPort:     serverPort,
-		CurrentVersion: currentVersion,
+	m := RootModel{
+		downloads:       downloads,
+		inputs:          []textinput.Model{urlInput, pathInput, filenameInput, categoryInput},
+		state:           DashboardState,
+		progressChan:    progressChan,
+		filepicker:      fp,
+		help:            helpModel,
+		list:            downloadList,
+		Pool:            pool,
+		restoredQueue:   restoredQueue,
+		Terminal:        &Terminal{File: os.Stdout},
+		PWD:             pwd,
+		SpeedHistory:    make([]float64, GraphHistoryPoints), // 60 points of history (30s at 0.5s interval)
+		logViewport:     viewport.New(40, 5),                 // Default size, will be resized
+		logEntries:      make([]string, 0),
+		Settings:        settings,
+		settingsVersion: config.CurrentSettingsVersion(),
+		liveRuntime:     liveRuntime,
+		SettingsInput:   settingsInput,
+		searchInput:     searchInput,
+		changeURLInput:  changeURLInput,
+		keys:            Keys,
+		ServerPort:      serverPort,
+		CurrentVersion:  currentVersion,
+	}
+	if len(recovered) > 0 {
+		m.addLogEntry(LogStylePaused.Render(fmt.Sprintf("↺ Recovered %d interrupted download(s), press p to resume", len(recovered))))
+	}
+	m.logCategoryErrors()
+	return m
+}
+
+// CategoryRules returns the category rules currently in effect
+func (m RootModel) CategoryRules() []types.CategoryRule {
+	if rc := m.liveRuntime.Get(); rc != nil {
+		return rc.Categories
+	}
+	return nil
+}
+
+// logCategoryErrors logs the category rules of the current settings that can't be applied
+func (m *RootModel) logCategoryErrors() {
+	if m.Settings == nil {
+		return
+	}
+	if err := m.Settings.ValidateCategories(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			m.addLogEntry(LogStyleError.Render("✖ Settings: " + line))
+		}
 	}
 }
 
 func (m RootModel) Init() tea.Cmd {
-	cmds := []tea.Cmd{listenForActivity(m.progressChan)}
+	cmds := []tea.Cmd{listenForActivity(m.progressChan), settingsWatchCmd()}
+	if m.Pool != nil {
+		cmds = append(cmds, restoreQueueCmd(m.Pool, m.restoredQueue))
+	}
 	// Trigger update check if not disabled in settings
 	if !m.Settings.General.SkipUpdateCheck {
 		cmds = append(cmds, checkForUpdateCmd(m.CurrentVersion))
@@ -304,6 +475,18 @@ func (m RootModel) Init() tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// restoreQueueCmd adds the downloads queued in the last session to the pool, then mirrors the
+// queue into the master list from there on
+func restoreQueueCmd(pool *download.WorkerPool, cfgs []types.DownloadConfig) tea.Cmd {
+	return func() tea.Msg {
+		for _, cfg := range cfgs {
+			pool.Add(cfg)
+		}
+		pool.EnableQueuePersistence()
+		return nil
+	}
+}
+
 func listenForActivity(sub chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
 		return <-sub
@@ -327,23 +510,47 @@ func (m RootModel) getFilteredDownloads() []*DownloadModel {
 				continue
 			}
 		case TabDone:
-			if !d.done {
+			if !d.done || d.err != nil {
+				continue
+			}
+		case TabFailed:
+			if d.err == nil {
 				continue
 			}
 		}
 
-		// Apply search filter if query is set
+		// Apply category filter
+		if m.categoryFilter != "" && !strings.EqualFold(d.Category, m.categoryFilter) {
+			continue
+		}
+
+		// Apply search filter if query is set (matches filename or category)
 		if m.searchQuery != "" {
-			if !strings.Contains(strings.ToLower(d.Filename), searchLower) {
+			if !strings.Contains(strings.ToLower(d.Filename), searchLower) &&
+				!strings.Contains(strings.ToLower(d.Category), searchLower) {
 				continue
 			}
 		}
 
 		filtered = append(filtered, d)
 	}
+
+	// Queued downloads are listed in the order they will start, paused ones after them
+	if m.activeTab == TabQueued {
+		slices.SortStableFunc(filtered, func(a, b *DownloadModel) int {
+			return cmp.Compare(queueSortKey(a), queueSortKey(b))
+		})
+	}
 	return filtered
 }
 
+func queueSortKey(d *DownloadModel) int {
+	if d.queuePos == 0 {
+		return math.MaxInt
+	}
+	return d.queuePos
+}
+
 // resetFilepicker resets the filepicker to default directory-only mode
 func (m *RootModel) resetFilepicker() {
 	m.filepicker.FileAllowed = false
@@ -50,6 +50,7 @@ func (r *ProgressReporter) PollCmd() tea.Cmd {
 			return messages.DownloadErrorMsg{
 				DownloadID: r.state.ID,
 				Err:        err,
+				Kind:       types.Classify(err),
 			}
 		}
 
@@ -64,20 +64,9 @@ func (m RootModel) viewSettings() string {
 		if i == m.SettingsSelectedRow {
 			style := lipgloss.NewStyle().Foreground(ColorNeonPurple).Bold(true)
 			cursor := "▸ "
-
-			if meta.Key == "max_global_connections" {
-				style = lipgloss.NewStyle().Foreground(ColorGray)
-				cursor = "# "
-			}
-
 			line = style.Render(cursor + line)
 		} else {
 			style := lipgloss.NewStyle().Foreground(ColorLightGray)
-
-			if meta.Key == "max_global_connections" {
-				style = lipgloss.NewStyle().Foreground(lipgloss.Color("238")) // Darker gray
-			}
-
 			line = style.Render("  " + line)
 		}
 
@@ -112,10 +101,6 @@ func (m RootModel) viewSettings() string {
 		} else {
 			// Show formatted value with unit
 			valueStr = formatSettingValueForEdit(value, meta.Type, meta.Key) + unitStyle.Render(unit)
-
-			if meta.Key == "max_global_connections" {
-				valueStr += " (Ignored)"
-			}
 		}
 
 		// Show Tab hint for directory settings
@@ -218,6 +203,9 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 		values["skip_update_check"] = m.Settings.General.SkipUpdateCheck
 		values["max_concurrent_downloads"] = m.Settings.General.MaxConcurrentDownloads
 		values["clipboard_monitor"] = m.Settings.General.ClipboardMonitor
+		values["extract_archives"] = m.Settings.General.ExtractArchives
+		values["delete_after_extract"] = m.Settings.General.DeleteAfterExtract
+		values["pause_on_shrink"] = m.Settings.General.PauseOnShrink
 
 	case "Connections":
 		values["max_connections_per_host"] = m.Settings.Connections.MaxConnectionsPerHost
@@ -234,6 +222,8 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 		values["slow_worker_grace_period"] = m.Settings.Performance.SlowWorkerGracePeriod
 		values["stall_timeout"] = m.Settings.Performance.StallTimeout
 		values["speed_ema_alpha"] = m.Settings.Performance.SpeedEmaAlpha
+		values["download_retries"] = m.Settings.Performance.DownloadRetries
+		values["download_retry_delay"] = m.Settings.Performance.DownloadRetryDelay
 	}
 
 	return values
@@ -280,6 +270,12 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 		m.Settings.General.SkipUpdateCheck = !m.Settings.General.SkipUpdateCheck
 	case "clipboard_monitor":
 		m.Settings.General.ClipboardMonitor = !m.Settings.General.ClipboardMonitor
+	case "extract_archives":
+		m.Settings.General.ExtractArchives = !m.Settings.General.ExtractArchives
+	case "delete_after_extract":
+		m.Settings.General.DeleteAfterExtract = !m.Settings.General.DeleteAfterExtract
+	case "pause_on_shrink":
+		m.Settings.General.PauseOnShrink = !m.Settings.General.PauseOnShrink
 	case "max_concurrent_downloads":
 		if v, err := strconv.Atoi(value); err == nil {
 			if v < 1 {
@@ -288,11 +284,30 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 				v = 10
 			}
 			m.Settings.General.MaxConcurrentDownloads = v
+			m.applyMaxDownloads()
 		}
 	}
 	return nil
 }
 
+// applyMaxDownloads resizes the worker pool to the configured concurrency limit
+func (m *RootModel) applyMaxDownloads() {
+	if m.Pool == nil {
+		return
+	}
+	n := m.Settings.General.MaxConcurrentDownloads
+	paused, err := m.Pool.SetMaxDownloads(n, m.Settings.General.PauseOnShrink)
+	if err != nil {
+		m.addLogEntry(LogStyleError.Render("✖ " + err.Error()))
+		return
+	}
+	msg := fmt.Sprintf("⚙ Max concurrent downloads set to %d", n)
+	if len(paused) > 0 {
+		msg += fmt.Sprintf(", paused %d", len(paused))
+	}
+	m.addLogEntry(LogStyleStarted.Render(msg))
+}
+
 func (m *RootModel) setConnectionsSetting(key, value, typ string) error {
 	switch key {
 	case "max_connections_per_host":
@@ -375,6 +390,18 @@ func (m *RootModel) setPerformanceSetting(key, value, typ string) error {
 			}
 			m.Settings.Performance.SpeedEmaAlpha = v
 		}
+	case "download_retries":
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			m.Settings.Performance.DownloadRetries = v
+		}
+	case "download_retry_delay":
+		// Check if it's just a number, if so add "s"
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			value += "s"
+		}
+		if v, err := time.ParseDuration(value); err == nil {
+			m.Settings.Performance.DownloadRetryDelay = v
+		}
 	}
 	return nil
 }
@@ -421,9 +448,9 @@ func (m RootModel) getSettingUnit() string {
 		return " MB"
 	case "worker_buffer_size":
 		return " KB"
-	case "max_task_retries":
+	case "max_task_retries", "download_retries":
 		return " retries"
-	case "slow_worker_grace_period", "stall_timeout":
+	case "slow_worker_grace_period", "stall_timeout", "download_retry_delay":
 		return " seconds"
 	case "slow_worker_threshold", "speed_ema_alpha":
 		return " (0.0-1.0)"
@@ -446,7 +473,7 @@ func formatSettingValueForEdit(value interface{}, typ, key string) string {
 			kb := float64(v.Int()) / 1024
 			return fmt.Sprintf("%.0f", kb)
 		}
-	case "slow_worker_grace_period", "stall_timeout":
+	case "slow_worker_grace_period", "stall_timeout", "download_retry_delay":
 		// Show duration as plain seconds number (e.g., "5" instead of "5s")
 		if d, ok := value.(time.Duration); ok {
 			return fmt.Sprintf("%.0f", d.Seconds())
@@ -529,8 +556,15 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.General.SkipUpdateCheck = defaults.General.SkipUpdateCheck
 		case "max_concurrent_downloads":
 			m.Settings.General.MaxConcurrentDownloads = defaults.General.MaxConcurrentDownloads
+			m.applyMaxDownloads()
 		case "clipboard_monitor":
 			m.Settings.General.ClipboardMonitor = defaults.General.ClipboardMonitor
+		case "extract_archives":
+			m.Settings.General.ExtractArchives = defaults.General.ExtractArchives
+		case "delete_after_extract":
+			m.Settings.General.DeleteAfterExtract = defaults.General.DeleteAfterExtract
+		case "pause_on_shrink":
+			m.Settings.General.PauseOnShrink = defaults.General.PauseOnShrink
 		}
 
 	case "Connections":
@@ -565,6 +599,10 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.Performance.StallTimeout = defaults.Performance.StallTimeout
 		case "speed_ema_alpha":
 			m.Settings.Performance.SpeedEmaAlpha = defaults.Performance.SpeedEmaAlpha
+		case "download_retries":
+			m.Settings.Performance.DownloadRetries = defaults.Performance.DownloadRetries
+		case "download_retry_delay":
+			m.Settings.Performance.DownloadRetryDelay = defaults.Performance.DownloadRetryDelay
 		}
 	}
 }
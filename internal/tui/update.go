@@ -1,13 +1,17 @@
 package tui
 
 import (
-	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pulse-downloader/pulse/internal/clipboard"
@@ -15,13 +19,17 @@ import (
 	"github.com/pulse-downloader/pulse/internal/download"
 	"github.com/pulse-downloader/pulse/internal/download/state"
 	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/extract"
+	"github.com/pulse-downloader/pulse/internal/hooks"
 	"github.com/pulse-downloader/pulse/internal/messages"
+	"github.com/pulse-downloader/pulse/internal/notify"
 	"github.com/pulse-downloader/pulse/internal/utils"
 	"github.com/pulse-downloader/pulse/internal/version"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/google/uuid"
 )
@@ -49,6 +57,174 @@ func fetchFormatsCmd(url string) tea.Cmd {
 	}
 }
 
+// ChangeURLResultMsg is sent when a URL replacement has been validated (or rejected)
+type ChangeURLResultMsg struct {
+	DownloadID string
+	URL        string
+	Err        error
+}
+
+// changeURLCmd probes the new URL and migrates the saved state off the UI thread
+func changeURLCmd(id, oldURL, newURL, destPath string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := download.ReplaceURL(context.Background(), oldURL, newURL, destPath)
+		return ChangeURLResultMsg{DownloadID: id, URL: newURL, Err: err}
+	}
+}
+
+// HookResultMsg is sent when the hooks for a download event have finished
+type HookResultMsg struct {
+	DownloadID string
+	Event      hooks.Event
+	Results    []hooks.Result
+}
+
+// runHooksCmd runs the configured hooks for a download event off the UI thread
+func (m RootModel) runHooksCmd(event hooks.Event, d *DownloadModel) tea.Cmd {
+	if len(hooks.Matching(m.Settings.Hooks, event, d.Category)) == 0 {
+		return nil
+	}
+	info := hooks.Info{
+		Event:      event,
+		ID:         d.ID,
+		URL:        d.URL,
+		File:       d.Destination,
+		Size:       d.Total,
+		Category:   d.Category,
+		ExtractDir: d.ExtractedDir,
+	}
+	if event != hooks.EventComplete && d.Destination != "" {
+		// Unfinished downloads only exist as the working file
+		info.File = d.Destination + types.IncompleteSuffix
+	}
+	if d.err != nil {
+		info.Error = d.err.Error()
+	}
+	settingsHooks := m.Settings.Hooks
+	return func() tea.Msg {
+		return HookResultMsg{
+			DownloadID: info.ID,
+			Event:      event,
+			Results:    hooks.Run(context.Background(), settingsHooks, info),
+		}
+	}
+}
+
+// NotifyResultMsg is sent when notifications for a download event have been delivered
+type NotifyResultMsg struct {
+	Results []notify.Result
+}
+
+// Terminal is the TUI's output, passed to the program with tea.WithOutput. The renderer writes
+// each frame in one call, so serialising writes lets terminal (OSC) notifications reach the
+// terminal between frames instead of in the middle of one.
+type Terminal struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.File.Write(p)
+}
+
+// notifyCmd pushes a download event to the configured notification sinks off the UI thread.
+// Terminal (OSC) notifications go through the TUI's Terminal, and are skipped without one.
+func (m RootModel) notifyCmd(event notify.Event, d *DownloadModel) tea.Cmd {
+	var term io.Writer
+	if m.Terminal != nil {
+		term = m.Terminal
+	}
+	n := notify.New(m.Settings.Notifications, term)
+	if !n.Wants(event) {
+		return nil
+	}
+	note := notify.Notification{
+		Event:    event,
+		ID:       d.ID,
+		URL:      d.URL,
+		Filename: d.Filename,
+		Path:     d.Destination,
+		Size:     d.Total,
+		Category: d.Category,
+		Elapsed:  d.Elapsed,
+	}
+	if d.err != nil {
+		note.Error = d.err.Error()
+	}
+	return func() tea.Msg {
+		return NotifyResultMsg{Results: n.Send(context.Background(), note)}
+	}
+}
+
+// ExtractResultMsg is sent when the archive of a completed download has been extracted
+type ExtractResultMsg struct {
+	DownloadID string
+	Dir        string
+	Err        error
+}
+
+// extractTickMsg polls the progress of a running extraction
+type extractTickMsg struct {
+	DownloadID string
+}
+
+func extractTickCmd(id string) tea.Cmd {
+	return tea.Tick(150*time.Millisecond, func(time.Time) tea.Msg {
+		return extractTickMsg{DownloadID: id}
+	})
+}
+
+// startExtractCmd extracts a completed archive next to it when enabled in settings.
+// It returns nil if the download is not a supported archive.
+func (m RootModel) startExtractCmd(d *DownloadModel) tea.Cmd {
+	if !m.Settings.General.ExtractArchives || d.Destination == "" || !extract.IsArchive(d.Destination) {
+		return nil
+	}
+	d.extracting = true
+	d.extractProgress = &extract.Progress{}
+
+	id, archive, p := d.ID, d.Destination, d.extractProgress
+	return tea.Batch(
+		func() tea.Msg {
+			dir := extract.TargetDir(archive)
+			err := extract.Extract(context.Background(), archive, dir, p)
+			return ExtractResultMsg{DownloadID: id, Dir: dir, Err: err}
+		},
+		extractTickCmd(id),
+	)
+}
+
+// settingsWatchTickMsg checks whether settings.json was edited outside the TUI
+type settingsWatchTickMsg struct{}
+
+func settingsWatchCmd() tea.Cmd {
+	return tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+		return settingsWatchTickMsg{}
+	})
+}
+
+// saveSettings writes the settings to disk and applies them to running downloads
+func (m *RootModel) saveSettings() {
+	if err := config.SaveSettings(m.Settings); err != nil {
+		m.addLogEntry(LogStyleError.Render("✖ Failed to save settings: " + err.Error()))
+	}
+	// Our own write is not an outside edit
+	m.settingsVersion = config.CurrentSettingsVersion()
+	m.applySettings()
+}
+
+// applySettings pushes the current settings to running downloads and the worker pool
+func (m *RootModel) applySettings() {
+	if m.liveRuntime != nil {
+		m.liveRuntime.Set(convertRuntimeConfig(m.Settings.ToRuntimeConfig()))
+	}
+	if m.Pool != nil && m.Pool.MaxDownloads() != m.Settings.General.MaxConcurrentDownloads {
+		m.applyMaxDownloads()
+	}
+}
+
 // notificationTickCmd waits briefly then sends a tick to check notification expiry
 func notificationTickCmd() tea.Cmd {
 	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
@@ -93,42 +269,39 @@ func convertRuntimeConfig(rc *config.RuntimeConfig) *types.RuntimeConfig {
 		SlowWorkerGracePeriod: rc.SlowWorkerGracePeriod,
 		StallTimeout:          rc.StallTimeout,
 		SpeedEmaAlpha:         rc.SpeedEmaAlpha,
+		DownloadRetries:       rc.DownloadRetries,
+		DownloadRetryDelay:    rc.DownloadRetryDelay,
+		Categories:            convertCategoryRules(rc.Categories),
 	}
 }
 
-// readURLsFromFile reads URLs from a file, one per line (skips empty lines, comments, and duplicates)
-func readURLsFromFile(filepath string) ([]string, error) {
-	file, err := os.Open(filepath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	var urls []string
-	seen := make(map[string]bool)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line != "" && !strings.HasPrefix(line, "#") {
-			// Normalize URL for duplicate detection
-			normalized := strings.TrimRight(line, "/")
-			if !seen[normalized] {
-				seen[normalized] = true
-				urls = append(urls, line)
-			}
-		}
+// convertCategoryRules converts config.CategoryRule values to types.CategoryRule
+func convertCategoryRules(rules []config.CategoryRule) []types.CategoryRule {
+	converted := make([]types.CategoryRule, len(rules))
+	for i, r := range rules {
+		converted[i] = types.CategoryRule(r)
 	}
+	return converted
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+// resolveCategoryName maps the add dialog's category input to a configured category name.
+// Unknown names are kept as typed so they still label the download.
+func (m RootModel) resolveCategoryName(input string) string {
+	input = strings.TrimSpace(input)
+	for _, c := range m.Settings.Categories {
+		if strings.EqualFold(c.Name, input) {
+			return c.Name
+		}
 	}
+	return input
+}
 
-	if len(urls) == 0 {
-		return nil, fmt.Errorf("no URLs found in file")
+// lastInputIndex returns the index of the last add dialog input (category is only shown when configured)
+func (m RootModel) lastInputIndex() int {
+	if len(m.Settings.Categories) > 0 {
+		return 3
 	}
-
-	return urls, nil
+	return 2
 }
 
 // addLogEntry adds a log entry to the log viewport
@@ -168,13 +341,14 @@ func (m RootModel) checkForDuplicate(url string) *DownloadModel {
 }
 
 // startDownload initiates a new download
-func (m RootModel) startDownload(url, path, filename, quality string) (RootModel, tea.Cmd) {
+func (m RootModel) startDownload(url, path, filename, quality, category string) (RootModel, tea.Cmd) {
 	// Generate unique filename to avoid overwriting
 	// Note: We do this check here because it applies to ALL new downloads
 	finalFilename := m.generateUniqueFilename(path, filename)
 
 	nextID := uuid.New().String()
 	newDownload := NewDownloadModel(nextID, url, "Queued", 0)
+	newDownload.Category = category
 	m.downloads = append(m.downloads, newDownload)
 
 	cfg := types.DownloadConfig{
@@ -183,11 +357,16 @@ func (m RootModel) startDownload(url, path, filename, quality string) (RootModel
 		ID:         nextID,
 		Filename:   finalFilename,
 		Quality:    quality,
+		Category:   category,
+		Headers:    m.pendingHeaders,
+		Checksum:   m.pendingChecksum,
+		Mirrors:    m.pendingMirrors,
 		Verbose:    false,
 		ProgressCh: m.progressChan,
 		State:      newDownload.state,
-		Runtime:    convertRuntimeConfig(m.Settings.ToRuntimeConfig()),
+		Live:       m.liveRuntime,
 	}
+	m.pendingHeaders, m.pendingChecksum, m.pendingMirrors = nil, "", nil
 
 	utils.Debug("Adding to Queue: %s -> %s", url, finalFilename)
 	m.Pool.Add(cfg)
@@ -199,6 +378,119 @@ func (m RootModel) startDownload(url, path, filename, quality string) (RootModel
 	return m, nil
 }
 
+// resumeDownload re-queues a paused download from its saved state and returns the polling command
+func (m RootModel) resumeDownload(d *DownloadModel) tea.Cmd {
+	d.paused = false
+	d.state.Resume()
+	// Use the download's actual destination directory
+	outputPath := filepath.Dir(d.Destination)
+	if outputPath == "" || outputPath == "." {
+		outputPath = m.Settings.General.DefaultDownloadDir
+		if outputPath == "" {
+			outputPath = m.PWD
+		}
+	}
+	cfg := types.DownloadConfig{
+		URL:        d.URL,
+		OutputPath: outputPath,
+		DestPath:   d.Destination, // Full path for state lookup
+		ID:         d.ID,
+		Filename:   d.Filename,
+		IsResume:   true, // Explicit resume - use saved state
+	}
+	// The master list keeps the options it was added with: priority, category, quality, headers,
+	// checksum and mirrors
+	if entry, err := state.GetDownloadEntry(d.ID); err == nil && entry != nil {
+		cfg = download.ResumedConfig(*entry)
+		cfg.URL = d.URL
+		if d.Destination != "" {
+			cfg.DestPath = d.Destination
+		}
+		if cfg.OutputPath == "" {
+			cfg.OutputPath = outputPath
+		}
+	}
+	cfg.ProgressCh = m.progressChan
+	cfg.State = d.state // Keeps the listed progress
+	cfg.Live = m.liveRuntime
+	m.Pool.Add(cfg)
+	// Restart polling
+	return d.reporter.PollCmd()
+}
+
+// retryDownload resumes a failed download, or one waiting for an automatic retry, straight away
+func (m *RootModel) retryDownload(d *DownloadModel) tea.Cmd {
+	if err := m.Pool.Retry(d.ID); err != nil {
+		m.addLogEntry(LogStyleError.Render("✖ Retry: " + err.Error()))
+		return nil
+	}
+	return m.retried(d)
+}
+
+// retried lists a download the pool has started again and returns the polling command
+func (m *RootModel) retried(d *DownloadModel) tea.Cmd {
+	failed := d.err != nil
+	d.err = nil
+	d.done = false
+	d.paused = false
+	d.retrying = false
+	m.addLogEntry(LogStyleStarted.Render("↻ Retrying: " + d.Filename))
+	if !failed {
+		return nil // Still polling while the automatic retry was pending
+	}
+	// Polling stopped when the error was reported
+	return d.reporter.PollCmd()
+}
+
+// refreshOwners notes which paused downloads another pulse process has started or let go of
+func (m *RootModel) refreshOwners() {
+	for _, d := range m.downloads {
+		if !d.paused || d.Destination == "" {
+			continue
+		}
+		elsewhere := state.DownloadOwner(d.Destination) != nil
+		if elsewhere == d.elsewhere {
+			continue
+		}
+		d.elsewhere = elsewhere
+		if elsewhere {
+			m.addLogEntry(LogStylePaused.Render("⇄ Running elsewhere: " + d.Filename))
+		} else {
+			m.addLogEntry(LogStylePaused.Render("⇄ Released by another process: " + d.Filename))
+		}
+	}
+	m.UpdateListItems()
+}
+
+// removeDownload cancels a download and deletes it from the list along with its saved state
+// and partial file. A download running in another process is left alone.
+func (m *RootModel) removeDownload(id string) error {
+	idx := slices.IndexFunc(m.downloads, func(d *DownloadModel) bool { return d.ID == id })
+	if idx < 0 {
+		return fmt.Errorf("no download with ID %s", id)
+	}
+	d := m.downloads[idx]
+	if d.elsewhere {
+		return fmt.Errorf("%s is running in another pulse process", d.Filename)
+	}
+	if err := m.Pool.Remove(id); err != nil {
+		// Only the list entry is left to remove
+		utils.Debug("Remove %s: %v", id, err)
+	}
+	m.dropDownload(id)
+	return nil
+}
+
+// dropDownload deletes a download from the list
+func (m *RootModel) dropDownload(id string) {
+	m.downloads = slices.DeleteFunc(m.downloads, func(d *DownloadModel) bool { return d.ID == id })
+}
+
+// canChangeURL reports whether the download is stopped and can be pointed at a new URL
+func canChangeURL(d *DownloadModel) bool {
+	return d != nil && (d.paused || d.err != nil)
+}
+
 // Update handles messages and updates the model
 func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -214,11 +506,17 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		m.pendingURL = msg.URL
+		m.pendingPath = path
+		m.pendingFilename = msg.Filename
+		m.pendingQuality = msg.Quality
+		m.pendingCategory = m.resolveCategoryName(msg.Category)
+		m.pendingHeaders = msg.Headers
+		m.pendingChecksum = msg.Checksum
+		m.pendingMirrors = msg.Mirrors
+
 		// Check if extension prompt is enabled
 		if m.Settings.General.ExtensionPrompt {
-			m.pendingURL = msg.URL
-			m.pendingPath = path
-			m.pendingFilename = msg.Filename
 			m.state = ExtensionConfirmationState
 			return m, nil
 		}
@@ -226,24 +524,159 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Check for duplicate URL
 		if d := m.checkForDuplicate(msg.URL); d != nil {
 			utils.Debug("Duplicate download detected from extension: %s", msg.URL)
-			m.pendingURL = msg.URL
-			m.pendingPath = path
-			m.pendingFilename = msg.Filename
 			m.duplicateInfo = d.Filename
 			m.state = DuplicateWarningState
 			return m, nil
 		}
 
 		// Check if it's a YouTube URL to trigger quality selection
-		if download.IsYoutubeURL(msg.URL) {
-			m.pendingURL = msg.URL
-			m.pendingPath = path
-			m.pendingFilename = msg.Filename
+		if download.IsYoutubeURL(msg.URL) && msg.Quality == "" {
 			m.state = FetchingFormatsState
 			return m, fetchFormatsCmd(msg.URL)
 		}
 
-		return m.startDownload(msg.URL, path, msg.Filename, "")
+		return m.startDownload(msg.URL, path, msg.Filename, msg.Quality, m.pendingCategory)
+
+	case settingsWatchTickMsg:
+		// Reload settings edited on disk, unless the settings view is open and would overwrite them
+		if m.state != SettingsState {
+			if v := config.CurrentSettingsVersion(); v != m.settingsVersion {
+				m.settingsVersion = v
+				if s, err := config.LoadSettings(); err != nil {
+					m.addLogEntry(LogStyleError.Render("✖ Settings not reloaded: " + err.Error()))
+				} else if !reflect.DeepEqual(s, m.Settings) {
+					m.Settings = s
+					m.applySettings()
+					m.addLogEntry(LogStyleStarted.Render("⚙ Settings reloaded"))
+					m.logCategoryErrors()
+				}
+			}
+		}
+		m.refreshOwners()
+		return m, settingsWatchCmd()
+
+	case SetMaxDownloadsMsg:
+		// Apply and persist a concurrency limit change from the HTTP server
+		m.Settings.General.MaxConcurrentDownloads = msg.Max
+		m.applyMaxDownloads()
+		m.saveSettings()
+		return m, nil
+
+	case URLChangedMsg:
+		// The pool resumes the download and reports it; only the listing is left to update
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				d.URL = msg.URL
+				m.addLogEntry(LogStyleStarted.Render("↻ URL changed: " + d.Filename))
+				break
+			}
+		}
+		m.UpdateListItems()
+		return m, nil
+
+	case DownloadRemovedMsg:
+		// Only the list entry is left to remove
+		m.dropDownload(msg.DownloadID)
+		m.UpdateListItems()
+		return m, nil
+
+	case DownloadRetriedMsg:
+		// The pool has started the download again; only the listing is left to update
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				cmd := m.retried(d)
+				m.UpdateListItems()
+				return m, cmd
+			}
+		}
+		return m, nil
+
+	case ChangeURLResultMsg:
+		for _, d := range m.downloads {
+			if d.ID != msg.DownloadID {
+				continue
+			}
+			if msg.Err != nil {
+				m.addLogEntry(LogStyleError.Render("✖ Change URL failed: " + msg.Err.Error()))
+				break
+			}
+			if !canChangeURL(d) {
+				break
+			}
+			d.URL = msg.URL
+			if d.err != nil {
+				// Failed downloads get a fresh progress state so polling does not re-report the old error
+				d.err = nil
+				d.done = false
+				d.state = types.NewProgressState(d.ID, d.Total)
+				d.reporter = NewProgressReporter(d.state)
+			}
+			m.addLogEntry(LogStyleStarted.Render("↻ URL changed: " + d.Filename))
+			cmds = append(cmds, m.resumeDownload(d))
+			break
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
+	case HookResultMsg:
+		var lines []string
+		for _, r := range msg.Results {
+			style := LogStyleComplete
+			if r.Err != nil {
+				style = LogStyleError
+			}
+			m.addLogEntry(style.Render("⚙ " + r.Summary()))
+			lines = append(lines, r.Summary())
+		}
+		if len(lines) > 0 {
+			// Keep hook output with the history entry (only persisted downloads have one)
+			_ = state.AppendHookOutput(msg.DownloadID, strings.Join(lines, "\n"))
+		}
+		return m, nil
+
+	case NotifyResultMsg:
+		for _, r := range msg.Results {
+			if r.Err != nil {
+				m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Notify %s: %v", r.Notifier, r.Err)))
+			}
+		}
+		return m, nil
+
+	case extractTickMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID && d.extracting {
+				cmds = append(cmds, d.progress.SetPercent(d.extractProgress.Fraction()), extractTickCmd(d.ID))
+				m.UpdateListItems()
+				break
+			}
+		}
+		return m, tea.Batch(cmds...)
+
+	case ExtractResultMsg:
+		for _, d := range m.downloads {
+			if d.ID != msg.DownloadID {
+				continue
+			}
+			d.extracting = false
+			cmds = append(cmds, d.progress.SetPercent(1.0))
+			if msg.Err != nil {
+				m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Extract failed: %s: %v", d.Filename, msg.Err)))
+			} else {
+				d.ExtractedDir = msg.Dir
+				m.addLogEntry(LogStyleComplete.Render("⇲ Extracted: " + d.Filename + " → " + filepath.Base(msg.Dir)))
+				if m.Settings.General.DeleteAfterExtract {
+					if err := os.Remove(d.Destination); err != nil {
+						m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Failed to delete archive: %v", err)))
+					} else {
+						m.addLogEntry(LogStyleComplete.Render("✔ Deleted archive: " + d.Filename))
+					}
+				}
+			}
+			cmds = append(cmds, m.runHooksCmd(hooks.EventComplete, d))
+			break
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
 
 	case messages.DownloadStartedMsg:
 
@@ -254,6 +687,10 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				d.Total = msg.Total
 				d.URL = msg.URL
 				d.Destination = msg.DestPath
+				d.retrying = false
+				if msg.Category != "" {
+					d.Category = msg.Category
+				}
 				// Reset start time to exclude probing
 				d.StartTime = time.Now()
 				// Update the progress state with real total size
@@ -347,12 +784,22 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					URL:         d.URL,
 					DestPath:    d.Destination,
 					Filename:    d.Filename,
+					Category:    d.Category,
 					Status:      "completed",
 					TotalSize:   d.Total,
 					CompletedAt: time.Now().Unix(),
 					TimeTaken:   d.Elapsed.Milliseconds(),
 				})
 
+				cmds = append(cmds, m.notifyCmd(notify.EventComplete, d))
+
+				// Completion hooks wait for the extraction so they can use its output
+				if cmd := m.startExtractCmd(d); cmd != nil {
+					m.addLogEntry(LogStyleStarted.Render("⇲ Extracting: " + d.Filename))
+					cmds = append(cmds, cmd)
+				} else {
+					cmds = append(cmds, m.runHooksCmd(hooks.EventComplete, d))
+				}
 				break
 			}
 		}
@@ -363,10 +810,32 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case messages.DownloadErrorMsg:
 		for _, d := range m.downloads {
 			if d.ID == msg.DownloadID {
+				if msg.Kind == types.KindLocked {
+					// Nothing failed: another process got there first, so leave it paused here
+					d.paused = true
+					d.elsewhere = true
+					d.Speed = 0
+					m.addLogEntry(LogStylePaused.Render("⇄ Running elsewhere: " + d.Filename))
+					break
+				}
 				d.err = msg.Err
 				d.done = true
 				// Add log entry
-				m.addLogEntry(LogStyleError.Render("✖ Error: " + d.Filename))
+				m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Error (%s): %s", msg.Kind, d.Filename)))
+				cmds = append(cmds, m.runHooksCmd(hooks.EventError, d), m.notifyCmd(notify.EventError, d))
+				break
+			}
+		}
+		m.UpdateListItems()
+		cmds = append(cmds, listenForActivity(m.progressChan))
+
+	case messages.DownloadRetryingMsg:
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				d.Speed = 0
+				d.retrying = true
+				m.addLogEntry(LogStylePaused.Render(fmt.Sprintf("↻ Retrying %s in %s (%d/%d): %v",
+					d.Filename, msg.Delay.Round(time.Second), msg.Attempt, msg.MaxAttempts, msg.Err)))
 				break
 			}
 		}
@@ -381,16 +850,35 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				d.Speed = 0 // Clear speed when paused
 				// Add log entry
 				m.addLogEntry(LogStylePaused.Render("⏸ Paused: " + d.Filename))
+				cmds = append(cmds, m.runHooksCmd(hooks.EventPause, d))
 				break
 			}
 		}
 		m.UpdateListItems()
 		cmds = append(cmds, listenForActivity(m.progressChan))
 
+	case messages.QueueChangedMsg:
+		m.UpdateListItems()
+		cmds = append(cmds, listenForActivity(m.progressChan))
+
+	case messages.HealthEventMsg:
+		name := msg.Host
+		for _, d := range m.downloads {
+			if d.ID == msg.DownloadID {
+				name = d.Filename
+				break
+			}
+		}
+		m.addLogEntry(LogStylePaused.Render("⚠ " + name + ": " + msg.Detail))
+		cmds = append(cmds, listenForActivity(m.progressChan))
+
 	case messages.DownloadResumedMsg:
 		for _, d := range m.downloads {
 			if d.ID == msg.DownloadID {
 				d.paused = false
+				// A failed download whose URL was changed over the API starts over too
+				d.err = nil
+				d.done = false
 				// Add log entry
 				m.addLogEntry(LogStyleStarted.Render("▶ Resumed: " + d.Filename))
 				// Restart polling
@@ -443,7 +931,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			utils.Debug("Failed to fetch formats: %v", msg.Err)
 			m.state = DashboardState
 			// Just proceed with default quality
-			return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, "")
+			return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, "", m.pendingCategory)
 		}
 
 		m.availableQualities = msg.Qualities
@@ -452,7 +940,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// If no specific qualities found (e.g. non-video or parsing error), skip selection
 		if len(m.availableQualities) == 0 {
 			m.state = DashboardState
-			return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, "")
+			return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, "", m.pendingCategory)
 		}
 
 		// Update pending filename with title if available and not already set
@@ -492,8 +980,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Check if a file was selected
 			if didSelect, path := m.filepicker.DidSelectFile(msg); didSelect {
-				// Read URLs from file
-				urls, err := readURLsFromFile(path)
+				// Read downloads and their options from file
+				entries, _, err := download.ReadInputFile(path)
 				if err != nil {
 					m.addLogEntry(LogStyleError.Render("✖ Failed to read batch file: " + err.Error()))
 					// Reset filepicker and return
@@ -504,7 +992,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 				// Store pending URLs and show confirmation
-				m.pendingBatchURLs = urls
+				m.pendingBatch = entries
 				m.batchFilePath = path
 
 				// Reset filepicker to directory mode
@@ -562,6 +1050,25 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 
+			// Cycle category filter: all -> each configured category -> all
+			if key.Matches(msg, m.keys.Dashboard.CategoryFilter) {
+				names := m.Settings.CategoryNames()
+				next := ""
+				if m.categoryFilter == "" && len(names) > 0 {
+					next = names[0]
+				} else {
+					for i, n := range names {
+						if strings.EqualFold(n, m.categoryFilter) && i+1 < len(names) {
+							next = names[i+1]
+							break
+						}
+					}
+				}
+				m.categoryFilter = next
+				m.UpdateListItems()
+				return m, nil
+			}
+
 			// Tab switching
 			if key.Matches(msg, m.keys.Dashboard.TabQueued) {
 				m.activeTab = TabQueued
@@ -584,6 +1091,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.UpdateListItems()
 				return m, nil
 			}
+			if key.Matches(msg, m.keys.Dashboard.TabFailed) {
+				m.activeTab = TabFailed
+				m.ManualTabSwitch = true
+				m.updateListTitle()
+				m.UpdateListItems()
+				return m, nil
+			}
 			// Quit
 			if key.Matches(msg, m.keys.Dashboard.Quit) {
 				// Graceful shutdown: pause all active downloads to save state
@@ -609,9 +1123,9 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.inputs[1].Blur()
 				m.inputs[2].SetValue("")
 				m.inputs[2].Blur()
-				m.inputs[2].SetValue("")
-				m.inputs[2].Blur()
-				// m.inputs[3] removed
+				m.inputs[3].SetValue("")
+				m.inputs[3].SetSuggestions(m.Settings.CategoryNames())
+				m.inputs[3].Blur()
 
 				// Check clipboard for URL if setting is enabled
 				if m.Settings.General.ClipboardMonitor {
@@ -628,7 +1142,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Next Tab
 			if key.Matches(msg, m.keys.Dashboard.NextTab) {
-				m.activeTab = (m.activeTab + 1) % 3
+				m.activeTab = (m.activeTab + 1) % 4
 				m.ManualTabSwitch = true
 				m.updateListTitle()
 				m.UpdateListItems()
@@ -641,48 +1155,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.list.FilterState() == list.Filtering {
 					// Fall through to let list handle it
 				} else if d := m.GetSelectedDownload(); d != nil {
-					targetID := d.ID
-
-					// Find index in real list
-					realIdx := -1
-					for i, dl := range m.downloads {
-						if dl.ID == targetID {
-							realIdx = i
-							break
-						}
-					}
-
-					if realIdx != -1 {
-						dl := m.downloads[realIdx]
-
-						// Cancel if active
-						m.Pool.Cancel(dl.ID)
-
-						// Delete state files
-						if dl.URL != "" && dl.Destination != "" {
-							_ = state.DeleteStateByURL(dl.ID, dl.URL, dl.Destination)
-						}
-
-						// Delete partial/incomplete files (only for non-completed downloads)
-						if !dl.done && dl.Destination != "" {
-							// Delete the .pulse partial file with retries
-							// (worker may still hold file briefly after Cancel on Windows)
-							pulseFile := dl.Destination + types.IncompleteSuffix
-							for i := 0; i < 5; i++ {
-								if err := os.Remove(pulseFile); err == nil {
-									break
-								}
-								time.Sleep(50 * time.Millisecond)
-							}
-						}
-
-						// Remove completed downloads from master list (for Done tab persistence)
-						if dl.done && dl.URL != "" {
-							_ = state.RemoveFromMasterList(dl.ID)
-						}
-
-						// Remove from list
-						m.downloads = append(m.downloads[:realIdx], m.downloads[realIdx+1:]...)
+					if err := m.removeDownload(d.ID); err != nil {
+						m.addLogEntry(LogStyleError.Render("✖ Delete: " + err.Error()))
 					}
 					m.UpdateListItems()
 					return m, nil
@@ -704,33 +1178,10 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if key.Matches(msg, m.keys.Dashboard.Pause) {
 				if d := m.GetSelectedDownload(); d != nil {
 					if !d.done {
-						if d.paused {
-							// Resume: create config and add to pool
-							d.paused = false
-							d.state.Resume()
-							// Use the download's actual destination directory
-							outputPath := filepath.Dir(d.Destination)
-							if outputPath == "" || outputPath == "." {
-								outputPath = m.Settings.General.DefaultDownloadDir
-								if outputPath == "" {
-									outputPath = m.PWD
-								}
-							}
-							cfg := types.DownloadConfig{
-								URL:        d.URL,
-								OutputPath: outputPath,
-								DestPath:   d.Destination, // Full path for state lookup
-								ID:         d.ID,
-								Filename:   d.Filename,
-								Verbose:    false,
-								IsResume:   true, // Explicit resume - use saved state
-								ProgressCh: m.progressChan,
-								State:      d.state,
-								Runtime:    convertRuntimeConfig(m.Settings.ToRuntimeConfig()),
-							}
-							m.Pool.Add(cfg)
-							// Restart polling
-							cmds = append(cmds, d.reporter.PollCmd())
+						if d.elsewhere {
+							m.addLogEntry(LogStylePaused.Render("⇄ Running elsewhere: " + d.Filename))
+						} else if d.paused {
+							cmds = append(cmds, m.resumeDownload(d))
 						} else {
 							m.Pool.Pause(d.ID)
 						}
@@ -740,6 +1191,63 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Batch(cmds...)
 			}
 
+			// Retry a failed download from where it stopped
+			if key.Matches(msg, m.keys.Dashboard.Retry) {
+				if d := m.GetSelectedDownload(); d != nil && (d.err != nil || d.retrying) {
+					cmds = append(cmds, m.retryDownload(d))
+				}
+				m.UpdateListItems()
+				return m, tea.Batch(cmds...)
+			}
+
+			// Change URL of a paused or failed download
+			if key.Matches(msg, m.keys.Dashboard.ChangeURL) {
+				if d := m.GetSelectedDownload(); canChangeURL(d) {
+					m.changeURLTargetID = d.ID
+					m.changeURLInput.SetValue(d.URL)
+					m.changeURLInput.CursorEnd()
+					m.changeURLInput.Focus()
+					m.state = ChangeURLState
+					return m, textinput.Blink
+				}
+				return m, nil
+			}
+
+			// Reorder, reprioritise or start the selected queued download
+			if m.activeTab == TabQueued {
+				if d := m.GetSelectedDownload(); d != nil && d.queuePos > 0 {
+					var err error
+					handled := true
+					switch {
+					case key.Matches(msg, m.keys.Dashboard.MoveUp):
+						err = m.Pool.Move(d.ID, download.MoveUp)
+					case key.Matches(msg, m.keys.Dashboard.MoveDown):
+						err = m.Pool.Move(d.ID, download.MoveDown)
+					case key.Matches(msg, m.keys.Dashboard.MoveTop):
+						err = m.Pool.Move(d.ID, download.MoveTop)
+					case key.Matches(msg, m.keys.Dashboard.MoveBottom):
+						err = m.Pool.Move(d.ID, download.MoveBottom)
+					case key.Matches(msg, m.keys.Dashboard.PriorityUp):
+						err = m.Pool.SetPriority(d.ID, min(d.Priority+1, types.PriorityHigh))
+					case key.Matches(msg, m.keys.Dashboard.PriorityDown):
+						err = m.Pool.SetPriority(d.ID, max(d.Priority-1, types.PriorityLow))
+					case key.Matches(msg, m.keys.Dashboard.StartNow):
+						if err = m.Pool.StartNow(d.ID); err == nil {
+							m.addLogEntry(LogStyleStarted.Render("▶ Starting now: " + d.Filename))
+						}
+					default:
+						handled = false
+					}
+					if handled {
+						if err != nil {
+							m.addLogEntry(LogStyleError.Render("✖ Queue: " + err.Error()))
+						}
+						m.UpdateListItems()
+						return m, nil
+					}
+				}
+			}
+
 			// Toggle log focus
 			if key.Matches(msg, m.keys.Dashboard.Log) {
 				m.logFocused = !m.logFocused
@@ -818,8 +1326,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, m.filepicker.Init()
 			}
 			if key.Matches(msg, m.keys.Input.Enter) {
-				// Navigate through inputs: URL -> Path -> Filename -> Start
-				if m.focusedInput < 2 {
+				// Navigate through inputs: URL -> Path -> Filename -> (Category) -> Start
+				if m.focusedInput < m.lastInputIndex() {
 					m.inputs[m.focusedInput].Blur()
 					m.focusedInput++
 					m.inputs[m.focusedInput].Focus()
@@ -833,6 +1341,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.inputs[0].Focus()
 					m.inputs[1].Blur()
 					m.inputs[2].Blur()
+					m.inputs[3].Blur()
 					return m, nil
 				}
 				path := m.inputs[1].Value()
@@ -849,12 +1358,18 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 				filename := m.inputs[2].Value()
+				category := m.resolveCategoryName(m.inputs[3].Value())
+				m.pendingQuality = ""
+				m.pendingHeaders = nil
+				m.pendingChecksum = ""
+				m.pendingMirrors = nil
 
 				// Check for duplicate URL
 				if d := m.checkForDuplicate(url); d != nil {
 					m.pendingURL = url
 					m.pendingPath = path
 					m.pendingFilename = filename
+					m.pendingCategory = category
 					m.duplicateInfo = d.Filename
 					m.state = DuplicateWarningState
 					return m, nil
@@ -865,12 +1380,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.pendingURL = url
 					m.pendingPath = path
 					m.pendingFilename = filename
+					m.pendingCategory = category
 					m.state = FetchingFormatsState
 					return m, fetchFormatsCmd(url)
 				}
 
 				m.state = DashboardState
-				return m.startDownload(url, path, filename, "")
+				return m.startDownload(url, path, filename, "", category)
 			}
 
 			// Up/Down navigation between inputs
@@ -880,7 +1396,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.inputs[m.focusedInput].Focus()
 				return m, nil
 			}
-			if key.Matches(msg, m.keys.Input.Down) && m.focusedInput < 2 {
+			if key.Matches(msg, m.keys.Input.Down) && m.focusedInput < m.lastInputIndex() {
 				m.inputs[m.focusedInput].Blur()
 				m.focusedInput++
 				m.inputs[m.focusedInput].Focus()
@@ -980,13 +1496,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case DuplicateWarningState:
 			if key.Matches(msg, m.keys.Duplicate.Continue) {
 				// Continue -> Check for YouTube quality selection
-				if download.IsYoutubeURL(m.pendingURL) {
+				if download.IsYoutubeURL(m.pendingURL) && m.pendingQuality == "" {
 					m.state = FetchingFormatsState
 					return m, fetchFormatsCmd(m.pendingURL)
 				}
 
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, "")
+				return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, m.pendingQuality, m.pendingCategory)
 			}
 			if key.Matches(msg, m.keys.Duplicate.Cancel) {
 				// Cancel - don't add
@@ -1017,13 +1533,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 				// No duplicate (or warning disabled) - add to queue
-				if download.IsYoutubeURL(m.pendingURL) {
+				if download.IsYoutubeURL(m.pendingURL) && m.pendingQuality == "" {
 					m.state = FetchingFormatsState
 					return m, fetchFormatsCmd(m.pendingURL)
 				}
 
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, "")
+				return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, m.pendingQuality, m.pendingCategory)
 			}
 			if key.Matches(msg, m.keys.Extension.No) {
 				// Cancelled
@@ -1061,8 +1577,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Check if a file was selected
 			if didSelect, path := m.filepicker.DidSelectFile(msg); didSelect {
-				// Read URLs from file
-				urls, err := readURLsFromFile(path)
+				// Read downloads and their options from file
+				entries, _, err := download.ReadInputFile(path)
 				if err != nil {
 					m.addLogEntry(LogStyleError.Render("✖ Failed to read batch file: " + err.Error()))
 					// Reset filepicker and return
@@ -1074,7 +1590,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 				// Store pending URLs and show confirmation
-				m.pendingBatchURLs = urls
+				m.pendingBatch = entries
 				m.batchFilePath = path
 
 				// Reset filepicker to directory mode
@@ -1098,13 +1614,14 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				added := 0
 				skipped := 0
-				for _, url := range m.pendingBatchURLs {
+				for _, e := range m.pendingBatch {
 					// Skip duplicate URLs
-					if m.checkForDuplicate(url) != nil {
+					if m.checkForDuplicate(e.URL) != nil {
 						skipped++
 						continue
 					}
-					m, _ = m.startDownload(url, path, "", "")
+					m.pendingHeaders, m.pendingChecksum, m.pendingMirrors = e.Headers, e.Checksum, e.Mirrors
+					m, _ = m.startDownload(e.URL, e.OutputPath(path), e.Filename, e.Quality, "")
 					added++
 				}
 
@@ -1113,13 +1630,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("⬇ Added %d downloads from batch", added)))
 				}
-				m.pendingBatchURLs = nil
+				m.pendingBatch = nil
 				m.batchFilePath = ""
 				m.state = DashboardState
 				return m, nil
 			}
 			if key.Matches(msg, m.keys.BatchConfirm.Cancel) {
-				m.pendingBatchURLs = nil
+				m.pendingBatch = nil
 				m.batchFilePath = ""
 				m.state = DashboardState
 				return m, nil
@@ -1155,7 +1672,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Not editing - handle navigation
 			if key.Matches(msg, m.keys.Settings.Close) {
 				// Save settings and exit
-				_ = config.SaveSettings(m.Settings)
+				m.saveSettings()
 				m.state = DashboardState
 				return m, nil
 			}
@@ -1285,13 +1802,40 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if key.Matches(msg, m.keys.Update.NeverRemind) {
 				// Persist the setting and dismiss
 				m.Settings.General.SkipUpdateCheck = true
-				_ = config.SaveSettings(m.Settings)
+				m.saveSettings()
 				m.state = DashboardState
 				m.UpdateInfo = nil
 				return m, nil
 			}
 			return m, nil
 
+		case ChangeURLState:
+			if key.Matches(msg, m.keys.ChangeURL.Cancel) {
+				m.changeURLInput.Blur()
+				m.changeURLTargetID = ""
+				m.state = DashboardState
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.ChangeURL.Confirm) {
+				newURL := strings.TrimSpace(m.changeURLInput.Value())
+				if newURL == "" {
+					return m, nil
+				}
+				m.changeURLInput.Blur()
+				m.state = DashboardState
+				for _, d := range m.downloads {
+					if d.ID == m.changeURLTargetID && newURL != d.URL {
+						m.changeURLTargetID = ""
+						return m, changeURLCmd(d.ID, d.URL, newURL, d.Destination)
+					}
+				}
+				m.changeURLTargetID = ""
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.changeURLInput, cmd = m.changeURLInput.Update(msg)
+			return m, cmd
+
 		case QualitySelectionState:
 			if msg.String() == "esc" {
 				m.state = InputState
@@ -1313,7 +1857,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Quality selected -> start download
 				quality := m.availableQualities[m.selectedQualityIdx]
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, quality)
+				return m.startDownload(m.pendingURL, m.pendingPath, m.pendingFilename, quality, m.pendingCategory)
 			}
 			return m, nil
 		}
@@ -1344,6 +1888,8 @@ func (m *RootModel) updateListTitle() {
 		m.list.Title = "⬇️ Active"
 	case TabDone:
 		m.list.Title = "✅ Completed"
+	case TabFailed:
+		m.list.Title = "❌ Failed"
 	}
 }
 
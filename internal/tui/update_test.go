@@ -1,11 +1,19 @@
 package tui
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pulse-downloader/pulse/internal/config"
+	"github.com/pulse-downloader/pulse/internal/download"
+	"github.com/pulse-downloader/pulse/internal/download/state"
 	"github.com/pulse-downloader/pulse/internal/download/types"
+	"github.com/pulse-downloader/pulse/internal/notify"
 )
 
 func TestGenerateUniqueFilename(t *testing.T) {
@@ -147,3 +155,101 @@ func TestGenerateUniqueFilename_IncompleteSuffixConstant(t *testing.T) {
 		t.Errorf("IncompleteSuffix = %q, want .pulse", types.IncompleteSuffix)
 	}
 }
+
+func TestNotifyCmd_Terminal(t *testing.T) {
+	settings := config.DefaultSettings()
+	settings.Notifications = []config.NotifierConfig{{Type: "osc9"}}
+	d := NewDownloadModel("id", "https://example.com/file.zip", "file.zip", 100)
+
+	// Without a terminal to share with the renderer, terminal notifications are skipped
+	m := RootModel{Settings: settings}
+	if cmd := m.notifyCmd(notify.EventComplete, d); cmd != nil {
+		t.Fatal("Terminal notification sent without a Terminal")
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "term"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m.Terminal = &Terminal{File: f}
+	cmd := m.notifyCmd(notify.EventComplete, d)
+	if cmd == nil {
+		t.Fatal("No notification for a completed download")
+	}
+	msg := cmd().(NotifyResultMsg)
+	if len(msg.Results) != 1 || msg.Results[0].Err != nil {
+		t.Fatalf("Results = %+v, want one successful delivery", msg.Results)
+	}
+	if data, _ := os.ReadFile(f.Name()); !strings.HasPrefix(string(data), "\x1b]9;") {
+		t.Errorf("Terminal got %q, want an OSC 9 sequence", data)
+	}
+}
+
+func TestInitialRootModel_DefersRestoredQueue(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := state.SaveQueue([]types.DownloadEntry{{ID: "queued", URL: "https://example.com/queued.bin"}}); err != nil {
+		t.Fatalf("SaveQueue failed: %v", err)
+	}
+
+	// Nothing may start before the program runs to receive the download's events
+	m := InitialRootModel(0, "dev")
+	if queued := m.Pool.Queued(); len(queued) != 0 {
+		t.Errorf("Restored download queued before Init: %+v", queued)
+	}
+	for _, d := range m.Pool.Downloads() {
+		if d.Status == "downloading" {
+			t.Errorf("Restored download started before Init: %+v", d)
+		}
+	}
+	if len(m.restoredQueue) != 1 || m.restoredQueue[0].ID != "queued" || m.restoredQueue[0].State == nil {
+		t.Errorf("Restored queue = %+v, want the saved download with its progress state", m.restoredQueue)
+	}
+}
+
+func TestResumeDownload_KeepsListedOptions(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// Keep the only slot busy so the resumed download waits in the queue
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	defer close(release)
+	ch := make(chan tea.Msg, 100)
+	pool := download.NewWorkerPool(ch, 1)
+	pool.Add(types.DownloadConfig{ID: "busy", URL: server.URL + "/busy.bin", OutputPath: t.TempDir(), ProgressCh: ch, State: types.NewProgressState("busy", 0)})
+
+	destPath := filepath.Join(t.TempDir(), "p.iso")
+	entry := types.DownloadEntry{
+		ID: "p", URL: "https://example.com/p.iso", DestPath: destPath, Filename: "p.iso", Status: "paused",
+		Category: "ISOs", Quality: "1080p", Priority: types.PriorityHigh,
+		Headers: map[string]string{"Authorization": "Bearer x"}, Checksum: "md5=d41d8cd98f00b204e9800998ecf8427e",
+		Mirrors: []string{"https://mirror.example.com/p.iso"},
+	}
+	if err := state.AddToMasterList(entry); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	m := RootModel{Pool: pool, Settings: config.DefaultSettings(), progressChan: ch}
+	d := NewDownloadModel("p", entry.URL, "p.iso", 0)
+	d.Destination = destPath
+	d.paused = true
+	m.resumeDownload(d)
+
+	queued := pool.Queued()
+	if len(queued) != 1 {
+		t.Fatalf("Queued = %+v, want the resumed download", queued)
+	}
+	cfg := queued[0]
+	if !cfg.IsResume || cfg.DestPath != destPath || cfg.State != d.state {
+		t.Errorf("Not resumed from its saved state: %+v", cfg)
+	}
+	if cfg.Priority != types.PriorityHigh || cfg.Category != "ISOs" || cfg.Quality != "1080p" ||
+		cfg.Headers["Authorization"] != "Bearer x" || cfg.Checksum != entry.Checksum || len(cfg.Mirrors) != 1 {
+		t.Errorf("Resumed download lost its options: %+v", cfg)
+	}
+	pool.Cancel("p") // Nothing to download once the slot frees up
+}
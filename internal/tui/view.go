@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pulse-downloader/pulse/internal/download/types"
 	"github.com/pulse-downloader/pulse/internal/tui/components"
 	"github.com/pulse-downloader/pulse/internal/utils"
 
@@ -46,8 +47,7 @@ func (m RootModel) View() string {
 			hintStyle.Render("[Tab] Browse"),
 		)
 
-		// Content layout - removing TitleStyle Render and adding spacers
-		content := lipgloss.JoinVertical(lipgloss.Left,
+		lines := []string{
 			"", // Top spacer
 			lipgloss.JoinHorizontal(lipgloss.Left, labelStyle.Render("URL:"), m.inputs[0].View()),
 			"", // Spacer
@@ -55,16 +55,58 @@ func (m RootModel) View() string {
 			"", // Spacer
 			lipgloss.JoinHorizontal(lipgloss.Left, labelStyle.Render("Filename:"), m.inputs[2].View()),
 			"", // Spacer
+		}
+		boxHeight := 11
+		// Category selection is only offered when category rules are configured
+		if len(m.Settings.Categories) > 0 {
+			lines = append(lines,
+				lipgloss.JoinHorizontal(lipgloss.Left, labelStyle.Render("Category:"), m.inputs[3].View()),
+				"", // Spacer
+			)
+			boxHeight += 2
+		}
+		lines = append(lines,
 			"", // Bottom spacer
 			"",
 			// Render dynamic help
 			m.help.View(m.keys.Input),
 		)
 
+		// Content layout - removing TitleStyle Render and adding spacers
+		content := lipgloss.JoinVertical(lipgloss.Left, lines...)
+
 		// Apply padding to the content before boxing it
 		paddedContent := lipgloss.NewStyle().Padding(0, 2).Render(content)
 
-		box := renderBtopBox(PaneTitleStyle.Render(" Add Download "), "", paddedContent, 80, 11, ColorNeonPink)
+		box := renderBtopBox(PaneTitleStyle.Render(" Add Download "), "", paddedContent, 80, boxHeight, ColorNeonPink)
+
+		return m.renderModalWithOverlay(box)
+	}
+
+	if m.state == ChangeURLState {
+		labelStyle := lipgloss.NewStyle().Width(10).Foreground(ColorLightGray)
+		filename := ""
+		for _, d := range m.downloads {
+			if d.ID == m.changeURLTargetID {
+				filename = d.Filename
+				break
+			}
+		}
+
+		content := lipgloss.JoinVertical(lipgloss.Left,
+			"", // Top spacer
+			lipgloss.JoinHorizontal(lipgloss.Left, labelStyle.Render("File:"), truncateString(filename, 60)),
+			"", // Spacer
+			lipgloss.JoinHorizontal(lipgloss.Left, labelStyle.Render("New URL:"), m.changeURLInput.View()),
+			"", // Spacer
+			lipgloss.NewStyle().Foreground(ColorLightGray).Render("Progress is kept if the new URL serves the same file"),
+			"", // Bottom spacer
+			m.help.View(m.keys.ChangeURL),
+		)
+
+		paddedContent := lipgloss.NewStyle().Padding(0, 2).Render(content)
+
+		box := renderBtopBox(PaneTitleStyle.Render(" Change URL "), "", paddedContent, 80, 10, ColorNeonPink)
 
 		return m.renderModalWithOverlay(box)
 	}
@@ -128,7 +170,7 @@ func (m RootModel) View() string {
 	}
 
 	if m.state == BatchConfirmState {
-		urlCount := len(m.pendingBatchURLs)
+		urlCount := len(m.pendingBatch)
 		modal := components.ConfirmationModal{
 			Title:       "Batch Import",
 			Message:     fmt.Sprintf("Add %d downloads?", urlCount),
@@ -235,7 +277,7 @@ func (m RootModel) View() string {
 /_/    \__,_/_/____/\___/  `
 
 	// Calculate stats for tab bar
-	active, queued, downloaded := m.CalculateStats()
+	active, queued, downloaded, failed := m.CalculateStats()
 
 	// Logo takes ~45% of header width
 	logoWidth := int(float64(leftWidth) * 0.45)
@@ -425,7 +467,7 @@ func (m RootModel) View() string {
 
 	// --- SECTION 3: DOWNLOAD LIST (Bottom Left) ---
 	// Tab Bar
-	tabBar := renderTabs(m.activeTab, active, queued, downloaded)
+	tabBar := renderTabs(m.activeTab, active, queued, downloaded, failed)
 
 	// Search bar (shown when search is active or has a query)
 	var leftTitle string
@@ -443,6 +485,15 @@ func (m RootModel) View() string {
 		// Pad the search bar to look like a title block
 		leftTitle = " " + lipgloss.JoinHorizontal(lipgloss.Left, searchIcon, searchDisplay) + " "
 	}
+	if m.categoryFilter != "" {
+		categoryDisplay := lipgloss.NewStyle().Foreground(ColorNeonCyan).Render("["+m.categoryFilter+"]") +
+			lipgloss.NewStyle().Foreground(ColorGray).Render(" [c next]")
+		if leftTitle == "" {
+			leftTitle = " " + categoryDisplay + " "
+		} else {
+			leftTitle += categoryDisplay + " "
+		}
+	}
 
 	// Render the bubbles list or centered empty message
 	var listContent string
@@ -450,7 +501,7 @@ func (m RootModel) View() string {
 		// FIX: Reduced width (leftWidth-8) to account for padding (4) and borders (2) + safety
 		// preventing the "floating bits" wrap-around artifact.
 		listContentHeight := listHeight - 6
-		if m.searchQuery != "" {
+		if m.searchQuery != "" || m.categoryFilter != "" {
 			listContent = lipgloss.Place(leftWidth-8, listContentHeight, lipgloss.Center, lipgloss.Center,
 				lipgloss.NewStyle().Foreground(ColorNeonCyan).Render("No matching downloads"))
 		} else {
@@ -535,6 +586,11 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Filename:"), StatsValueStyle.Render(truncateString(d.Filename, contentWidth-14))),
 		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Filepath:"), StatsValueStyle.Render(truncateString(d.Destination, contentWidth-14))),
 	}
+	if d.Category != "" {
+		fileInfoLines = append(fileInfoLines,
+			lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Category:"), StatsValueStyle.Render(d.Category)),
+		)
+	}
 
 	// Size display differs based on completed status
 	if d.done {
@@ -563,7 +619,7 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 	// For errored downloads, show error details
 	if d.err != nil {
 		errorStyle := lipgloss.NewStyle().Foreground(ColorStateError).Width(contentWidth - 4)
-		errorLabel := lipgloss.NewStyle().Foreground(ColorStateError).Bold(true).Render("Error Details")
+		errorLabel := lipgloss.NewStyle().Foreground(ColorStateError).Bold(true).Render(fmt.Sprintf("Error Details (%s)", types.Classify(d.err)))
 
 		// Word wrap the error message
 		errMsg := d.err.Error()
@@ -605,10 +661,27 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 			avgSpeedStr = "N/A"
 		}
 
-		statsSection := lipgloss.JoinVertical(lipgloss.Left,
+		statsLines := []string{
 			lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Time Taken:"), StatsValueStyle.Render(d.Elapsed.Round(time.Second).String())),
 			lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Avg Speed:"), StatsValueStyle.Render(avgSpeedStr)),
-		)
+		}
+		if d.extracting {
+			progressWidth := w - 12
+			if progressWidth < 20 {
+				progressWidth = 20
+			}
+			d.progress.Width = progressWidth
+			statsLines = append(statsLines,
+				lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Extracted:"), StatsValueStyle.Render(fmt.Sprintf("%d files", d.extractProgress.Files()))),
+				"",
+				lipgloss.NewStyle().MarginLeft(1).Render(d.progress.ViewAs(d.extractProgress.Fraction())),
+			)
+		} else if d.ExtractedDir != "" {
+			statsLines = append(statsLines,
+				lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Extracted:"), StatsValueStyle.Render(truncateString(d.ExtractedDir, contentWidth-14))),
+			)
+		}
+		statsSection := lipgloss.JoinVertical(lipgloss.Left, statsLines...)
 
 		content := lipgloss.JoinVertical(lipgloss.Left,
 			statusBox,
@@ -715,7 +788,7 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 	)
 
 	// Combine all sections with status box at top
-	content := lipgloss.JoinVertical(lipgloss.Left,
+	sections := []string{
 		statusBox,
 		"",
 		fileInfo,
@@ -729,9 +802,12 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 		"",
 		divider,
 		"",
-		urlSection,
-		IDSection,
-	)
+	}
+	if scaling := renderScalingTrace(d, contentWidth); scaling != "" {
+		sections = append(sections, scaling, "", divider, "")
+	}
+	sections = append(sections, urlSection, IDSection)
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
 
 	// Wrap in a container with reduced padding
 	return lipgloss.NewStyle().
@@ -739,9 +815,45 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 		Render(content)
 }
 
+// renderScalingTrace lists the most recent connection count changes, newest first
+func renderScalingTrace(d *DownloadModel, w int) string {
+	if d.state == nil {
+		return ""
+	}
+	trace := d.state.ScalingTrace()
+	if len(trace) == 0 {
+		return ""
+	}
+
+	label := lipgloss.NewStyle().
+		Foreground(ColorNeonCyan).
+		Bold(true).
+		Render("Scaling")
+	lines := []string{label, ""}
+	for i := len(trace) - 1; i >= 0 && len(lines) < 6; i-- {
+		dec := trace[i]
+		line := fmt.Sprintf("%s  %d → %d  %s", dec.Time.Format("15:04:05"), dec.From, dec.To, dec.Reason)
+		if dec.Speed > 0 {
+			line += fmt.Sprintf(" @ %.2f MB/s", dec.Speed/Megabyte)
+		}
+		lines = append(lines, lipgloss.NewStyle().Foreground(ColorLightGray).Render(truncateString(line, w-2)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
 func getDownloadStatus(d *DownloadModel) string {
-	status := components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded)
-	return status.Render()
+	return downloadStatus(d).Render()
+}
+
+// downloadStatus returns the status of a download, including post-processing
+func downloadStatus(d *DownloadModel) components.DownloadStatus {
+	if d.extracting {
+		return components.StatusExtracting
+	}
+	if d.elsewhere {
+		return components.StatusElsewhere
+	}
+	return components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded)
 }
 
 func (m RootModel) calcTotalSpeed() float64 {
@@ -756,9 +868,11 @@ func (m RootModel) calcTotalSpeed() float64 {
 	return total / Megabyte
 }
 
-func (m RootModel) CalculateStats() (active, queued, downloaded int) {
+func (m RootModel) CalculateStats() (active, queued, downloaded, failed int) {
 	for _, d := range m.downloads {
-		if d.done {
+		if d.err != nil {
+			failed++
+		} else if d.done {
 			downloaded++
 		} else if d.Speed > 0 {
 			active++
@@ -777,11 +891,12 @@ func truncateString(s string, i int) string {
 	return s
 }
 
-func renderTabs(activeTab, activeCount, queuedCount, doneCount int) string {
+func renderTabs(activeTab, activeCount, queuedCount, doneCount, failedCount int) string {
 	tabs := []components.Tab{
 		{Label: "Queued", Count: queuedCount},
 		{Label: "Active", Count: activeCount},
 		{Label: "Done", Count: doneCount},
+		{Label: "Failed", Count: failedCount},
 	}
 	return components.RenderTabBar(tabs, activeTab, ActiveTabStyle, TabStyle)
 }